@@ -2,16 +2,33 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
-	"time"
 
 	"goszakup-automation/internal/api"
+	"goszakup-automation/internal/backend"
+	_ "goszakup-automation/internal/backend/all"
+	"goszakup-automation/internal/batch"
 	"goszakup-automation/internal/config"
+	"goszakup-automation/internal/debugger"
+	"goszakup-automation/internal/executor"
 	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/ocr"
+	_ "goszakup-automation/internal/ocr/tesseract"
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/safety"
+	"goszakup-automation/internal/scenario"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+	"goszakup-automation/internal/templates"
 	"goszakup-automation/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +36,19 @@ import (
 )
 
 func main() {
+	// Подкоманда проверки сценариев: app validate [-screen 1920x1080] [-strict] файлы или каталоги
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	// Подкоманда пакетного запуска: app batch -scenario имя -data таблица.xlsx [-resume] ...
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		os.Exit(runBatch(os.Args[2:]))
+	}
+	// Подкоманда записи физического ввода: app record [-o файл] [-stop-key f12] ...
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		os.Exit(runRecord(os.Args[2:]))
+	}
+
 	// Загрузка конфигурации
 	cfg := config.Load()
 
@@ -29,8 +59,55 @@ func main() {
 	}
 	defer zapLogger.Sync()
 
-	// Инициализация Input Service для работы с мышью и клавиатурой
-	inputService := input.NewService(zapLogger)
+	// Инициализация бэкенда ввода и Input Service для работы с мышью и клавиатурой
+	inputBackend, err := backend.New(cfg.InputBackend, backend.Options{
+		Display: cfg.Display,
+	})
+	if err != nil {
+		zapLogger.Fatal("Failed to initialize input backend", zap.Error(err))
+	}
+	if closer, ok := inputBackend.(io.Closer); ok {
+		defer closer.Close()
+	}
+	zapLogger.Info("Input backend initialized", zap.String("backend", inputBackend.Name()))
+
+	// Отмененное при остановке задание еще выполняет блоки on_failure и finally,
+	// поэтому остановка не должна прерывать их раньше sequence.CleanupTimeout
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout < sequence.CleanupTimeout {
+		zapLogger.Warn("SHUTDOWN_TIMEOUT меньше времени блоков завершения, используется минимальное значение",
+			zap.Duration("configured", shutdownTimeout), zap.Duration("timeout", sequence.CleanupTimeout))
+		shutdownTimeout = sequence.CleanupTimeout
+	}
+	inputService := input.NewService(zapLogger, inputBackend)
+
+	// Единая очередь: все действия с рабочим столом выполняются строго по одному
+	actionExecutor := executor.New(zapLogger, cfg.QueueWaitTimeout)
+	// После ошибки, отмены или паники отпускаем все, что действие успело зажать
+	actionExecutor.SetCleanup(func() {
+		if err := inputService.ReleaseAll(); err != nil {
+			zapLogger.Error("Ошибка отпускания зажатых клавиш", zap.Error(err))
+		}
+	})
+
+	// Fail-safe: курсор, уведенный оператором в угол экрана, останавливает все действия
+	failSafeCorners, err := safety.ParseCorners(cfg.FailSafeCorners)
+	if err != nil {
+		zapLogger.Fatal("Invalid FAILSAFE_CORNERS", zap.Error(err))
+	}
+	failSafe := safety.NewFailSafe(zapLogger, inputBackend, safety.Options{
+		Corners:      failSafeCorners,
+		Margin:       cfg.FailSafeMargin,
+		PollInterval: cfg.FailSafePollInterval,
+	}, func(reason string) {
+		if _, err := safety.Stop(actionExecutor, inputService); err != nil {
+			zapLogger.Error("Ошибка аварийной остановки", zap.Error(err))
+		}
+	})
+	inputService.SetGuard(failSafe)
+	failSafeCtx, stopFailSafe := context.WithCancel(context.Background())
+	defer stopFailSafe()
+	go failSafe.Run(failSafeCtx)
 
 	// Настройка Gin
 	if cfg.Environment == "production" {
@@ -52,7 +129,24 @@ func main() {
 	})
 
 	// API routes
-	apiHandler := api.NewHandler(zapLogger, inputService)
+	scenarios := scenario.NewLibrary(scenario.NewDir(cfg.ScenariosDir))
+	matchDefaults := screen.MatchOptions{
+		Threshold: cfg.MatchThreshold,
+		Mode:      screen.MatchMode(cfg.MatchMode),
+		MinScale:  cfg.MatchMinScale,
+		MaxScale:  cfg.MatchMaxScale,
+		ScaleStep: cfg.MatchScaleStep,
+	}
+	if err := matchDefaults.Validate(); err != nil {
+		zapLogger.Fatal("Invalid MATCH_* settings", zap.Error(err))
+	}
+	locator := templates.NewLocator(templates.NewStore(cfg.TemplatesDir), inputService, matchDefaults)
+	ocrDefaults := ocr.Options{Languages: cfg.OCRLanguages, PSM: cfg.OCRPSM}
+	if err := ocrDefaults.Validate(); err != nil {
+		zapLogger.Fatal("Invalid OCR_* settings", zap.Error(err))
+	}
+	reader := ocr.NewReader(inputService, ocrDefaults)
+	apiHandler := api.NewHandler(zapLogger, inputService, actionExecutor, failSafe, scenarios, cfg.DebugPauseTimeout, recorder.New(cfg.Display), locator, reader)
 	apiGroup := router.Group("/api")
 	{
 		// Robotogo API endpoints
@@ -71,6 +165,72 @@ func main() {
 			
 			// Полный цикл: заполнение инпута и клик по кнопке
 			testGroup.POST("/fill-and-click", apiHandler.FillInputAndClick)
+
+			// Снимок экрана и проба цвета
+			testGroup.GET("/screen/capture", apiHandler.CaptureScreen)
+			testGroup.GET("/screen/pixel", apiHandler.GetPixel)
+
+			// Изображения-шаблоны и поиск их на экране
+			testGroup.GET("/templates", apiHandler.ListTemplates)
+			testGroup.GET("/templates/:name", apiHandler.GetTemplate)
+			testGroup.POST("/templates", apiHandler.UploadTemplate)
+			testGroup.DELETE("/templates/:name", apiHandler.DeleteTemplate)
+			testGroup.POST("/screen/find", apiHandler.FindImage)
+
+			// Распознавание текста на экране
+			testGroup.POST("/screen/ocr", apiHandler.ReadText)
+
+			// Последовательность шагов за одно место в очереди
+			testGroup.POST("/sequence", apiHandler.RunSequence)
+
+			// Сценарии из каталога SCENARIOS_DIR
+			testGroup.GET("/scenarios", apiHandler.ListScenarios)
+			testGroup.GET("/scenarios/:name", apiHandler.GetScenario)
+			testGroup.POST("/scenarios", apiHandler.CreateScenario)
+			testGroup.PUT("/scenarios/:name", apiHandler.UpdateScenario)
+			testGroup.DELETE("/scenarios/:name", apiHandler.DeleteScenario)
+			testGroup.GET("/scenarios/:name/versions", apiHandler.ListScenarioVersions)
+			testGroup.GET("/scenarios/:name/diff", apiHandler.DiffScenario)
+			testGroup.POST("/scenarios/:name/rollback", apiHandler.RollbackScenario)
+			testGroup.POST("/scenarios/validate", apiHandler.ValidateScenario)
+			testGroup.POST("/scenarios/:name/run", apiHandler.RunScenario)
+
+			// Пакетный запуск сценария по строкам таблицы CSV/XLSX
+			testGroup.POST("/scenarios/:name/batch", apiHandler.RunBatch)
+			testGroup.GET("/batches", apiHandler.ListBatches)
+			testGroup.GET("/batches/:id", apiHandler.GetBatch)
+			testGroup.GET("/batches/:id/result", apiHandler.GetBatchResult)
+
+			// Пошаговая отладка сценариев
+			testGroup.POST("/scenarios/:name/debug", apiHandler.DebugScenario)
+			testGroup.GET("/debug/:id", apiHandler.GetDebugSession)
+			testGroup.GET("/debug/:id/screenshot", apiHandler.GetDebugScreenshot)
+			testGroup.POST("/debug/:id/step", apiHandler.DebugCommand(debugger.CommandStep))
+			testGroup.POST("/debug/:id/continue", apiHandler.DebugCommand(debugger.CommandContinue))
+			testGroup.POST("/debug/:id/skip", apiHandler.DebugCommand(debugger.CommandSkip))
+			testGroup.POST("/debug/:id/abort", apiHandler.DebugCommand(debugger.CommandAbort))
+			testGroup.PUT("/debug/:id/vars", apiHandler.SetDebugVars)
+
+			// Запись действий API в сценарий
+			testGroup.POST("/recordings", apiHandler.StartRecording)
+			testGroup.GET("/recordings", apiHandler.ListRecordings)
+			testGroup.GET("/recordings/:id", apiHandler.GetRecording)
+			testGroup.POST("/recordings/:id/stop", apiHandler.StopRecording)
+			testGroup.DELETE("/recordings/:id", apiHandler.DeleteRecording)
+
+			// Очередь действий и асинхронные задания
+			testGroup.GET("/queue", apiHandler.GetQueue)
+			testGroup.GET("/jobs/:id", apiHandler.GetJob)
+			testGroup.DELETE("/jobs/:id", apiHandler.CancelJob)
+
+			// Аварийный останов
+			testGroup.POST("/stop", apiHandler.Stop)
+			testGroup.GET("/failsafe", apiHandler.GetFailSafe)
+			testGroup.POST("/failsafe/arm", apiHandler.ArmFailSafe)
+
+			// Зажатые клавиши и кнопки мыши
+			testGroup.GET("/keys/held", apiHandler.GetHeldKeys)
+			testGroup.POST("/keys/release", apiHandler.ReleaseKeys)
 		}
 	}
 
@@ -100,12 +260,273 @@ func main() {
 
 	zapLogger.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	// Сначала прерываем действия на рабочем столе, чтобы ожидающие их запросы
+	// получили ответ до остановки HTTP сервера
+	if err := actionExecutor.Shutdown(ctx); err != nil {
+		zapLogger.Warn("Действия не остановились вовремя", zap.Error(err))
+	}
+	if err := inputService.ReleaseAll(); err != nil {
+		zapLogger.Error("Ошибка отпускания зажатых клавиш", zap.Error(err))
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		zapLogger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
 	zapLogger.Info("Server exited")
 }
+
+// runValidate проверяет файлы сценариев и выводит замечания в формате файл:строка:колонка.
+// Каталоги проверяются целиком. Возвращает код выхода: 1 при ошибках, 2 при неверных аргументах.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	screenSize := flags.String("screen", "", "размер экрана для проверки координат, например 1920x1080")
+	strict := flags.Bool("strict", false, "считать предупреждения ошибками")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: app validate [-screen 1920x1080] [-strict] файл|каталог...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var opts scenario.LintOptions
+	if *screenSize != "" {
+		if _, err := fmt.Sscanf(*screenSize, "%dx%d", &opts.ScreenWidth, &opts.ScreenHeight); err != nil {
+			fmt.Fprintf(os.Stderr, "неверный размер экрана %q, ожидается ШИРИНАxВЫСОТА\n", *screenSize)
+			return 2
+		}
+	}
+
+	var files []string
+	for _, arg := range flags.Args() {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
+			return 2
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		summaries, err := scenario.NewDir(arg).List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
+			return 2
+		}
+		for _, summary := range summaries {
+			files = append(files, filepath.Join(arg, summary.File))
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed = true
+			continue
+		}
+		issues := scenario.Lint(file, data, opts)
+		for _, issue := range issues {
+			fmt.Printf("%s:%s\n", file, issue)
+		}
+		if scenario.HasErrors(issues) || (*strict && len(issues) > 0) {
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	fmt.Printf("Проверено файлов: %d, ошибок не найдено\n", len(files))
+	return 0
+}
+
+// runRecord записывает физические мышь и клавиатуру через источник ввода (по умолчанию x11)
+// до нажатия стоп-клавиши или Ctrl+C и сохраняет сценарий в файл или выводит его.
+// Возвращает код выхода: 1 при ошибке записи, 2 при неверных аргументах.
+func runRecord(args []string) int {
+	cfg := config.Load()
+
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	source := flags.String("source", "x11", "источник физического ввода")
+	display := flags.String("display", cfg.Display, "адрес X-сервера")
+	output := flags.String("o", "", "файл сценария .yaml или .json (по умолчанию вывод в stdout)")
+	name := flags.String("name", "", "имя сценария (по умолчанию имя файла)")
+	description := flags.String("description", "", "описание сценария")
+	parameterize := flags.Bool("parameterize", false, "заменить введенные тексты параметрами text1, text2, ...")
+	stopKey := flags.String("stop-key", "f12", "клавиша остановки записи")
+	minPause := flags.Int("min-pause", 0, "паузы короче (мс) не записываются (по умолчанию 200)")
+	maxPause := flags.Int("max-pause", 0, "ограничение записанной паузы в мс (0 — без ограничения)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: app record [-o файл.yaml] [-stop-key f12] [-parameterize]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	file := *output
+	if file == "" {
+		file = "recording.yaml"
+	}
+	if ext := strings.ToLower(filepath.Ext(file)); ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		fmt.Fprintf(os.Stderr, "неверное расширение файла %q, ожидается .yaml, .yml или .json\n", file)
+		return 2
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	rec := recorder.New(*display)
+	recording, err := rec.Start(recorder.Options{
+		Source:     *source,
+		StopKey:    *stopKey,
+		MinPauseMs: *minPause,
+		MaxPauseMs: *maxPause,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка начала записи: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Запись начата, для остановки нажмите %s или Ctrl+C\n", *stopKey)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-recording.Done():
+	case <-quit:
+	}
+	signal.Stop(quit)
+
+	// Ошибку остановки не проверяем: запись уже могла остановиться стоп-клавишей
+	_, _ = rec.Stop(recording.ID())
+	info := recording.Info()
+	if info.Error != "" {
+		fmt.Fprintf(os.Stderr, "запись прервана: %s\n", info.Error)
+	}
+	if len(info.Actions) == 0 {
+		fmt.Fprintln(os.Stderr, "Действий не записано")
+		if info.Error != "" {
+			return 1
+		}
+		return 0
+	}
+
+	sc := recording.Scenario(*name, recorder.ScenarioOptions{
+		Description:  *description,
+		Parameterize: *parameterize,
+	})
+	data, err := scenario.Marshal(file, sc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка построения сценария: %v\n", err)
+		return 1
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка записи файла: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Сценарий %s сохранен в %s, шагов: %d\n", sc.Name, *output, len(sc.Steps))
+	return 0
+}
+
+// runBatch запускает сценарий по строкам таблицы через API работающего сервиса (чтобы действия
+// шли через общую очередь и fail-safe), показывает ход выполнения и сохраняет файл результата.
+// Ctrl+C отменяет запуск, итоги уже выполненных строк сохраняются. Возвращает код выхода:
+// 1 при ошибках строк или запуска, 2 при неверных аргументах.
+func runBatch(args []string) int {
+	cfg := config.Load()
+	port := cfg.Port
+	if port == "" {
+		port = "3001"
+	}
+
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	server := flags.String("server", "http://localhost:"+port, "адрес сервиса")
+	name := flags.String("scenario", "", "имя сценария из каталога сценариев")
+	data := flags.String("data", "", "таблица .csv или .xlsx, по строке на запуск")
+	sheet := flags.String("sheet", "", "лист XLSX (по умолчанию первый)")
+	mapping := flags.String("map", "", "соответствие параметр=колонка через запятую (по умолчанию колонки с именами параметров)")
+	version := flags.Int("version", 0, "версия сценария (0 — текущая)")
+	resume := flags.Bool("resume", false, "продолжить с первой неуспешной строки файла результата")
+	stopOnError := flags.Bool("stop-on-error", false, "остановиться после первой строки с ошибкой")
+	output := flags.String("o", "", "файл результата (по умолчанию <таблица>-result рядом с таблицей)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: app batch -scenario имя -data таблица.xlsx [-map параметр=колонка,...] [-resume] [-o результат.xlsx]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" || *data == "" || flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(*data, filepath.Ext(*data)) + "-result" + filepath.Ext(*data)
+	}
+	if !batch.IsSpreadsheet(*data) || !batch.IsSpreadsheet(*output) {
+		fmt.Fprintln(os.Stderr, "таблица и файл результата должны быть .csv или .xlsx")
+		return 2
+	}
+	req := batch.Request{
+		Scenario:     *name,
+		File:         *data,
+		Sheet:        *sheet,
+		Version:      *version,
+		Resume:       *resume,
+		StopOnError:  *stopOnError,
+		ResultFormat: strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), "."),
+	}
+	if *mapping != "" {
+		pairs, err := batch.ParseMapping(*mapping)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		req.Mapping = pairs
+	}
+	content, err := os.ReadFile(*data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *data, err)
+		return 2
+	}
+	req.Data = content
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := &batch.Client{Server: *server, Log: os.Stderr}
+	report, err := client.Run(ctx, req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(*output, report.Result, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка сохранения результата: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Результат сохранен в %s\n", *output)
+
+	if !report.Succeeded() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goszakup-automation/internal/batch"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RunBatch выполняет сценарий для каждой строки таблицы CSV или XLSX за одно место в очереди.
+// Запрос multipart/form-data: file — таблица, mapping и params — JSON-объекты,
+// sheet, version, resume и stop_on_error — значения полей формы.
+func (h *Handler) RunBatch(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо передать таблицу в поле file (multipart/form-data)",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	opts, version, err := batchOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный формат запроса",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sc, ok := h.loadScenario(c, version)
+	if !ok {
+		return
+	}
+	if version == 0 {
+		version = h.scenarios.Current(sc.Name)
+	}
+
+	table, err := readTable(header, c.PostForm("sheet"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Ошибка чтения таблицы",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	b, err := h.batches.Create(sc, version, filepath.Base(header.Filename), table, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Таблица не подходит для сценария",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	h.runWith(c, "batch/"+sc.Name, "Ошибка пакетного запуска", gin.H{"batch_id": b.ID()}, func(ctx context.Context) (any, error) {
+		err := b.Run(ctx, func(ctx context.Context, line int, params map[string]any) error {
+			scope, err := sc.Prepare(params)
+			if err != nil {
+				return err
+			}
+			if _, err := h.sequenceRunner.RunWithCleanup(ctx, sc.Steps, sc.Cleanup(), scope, nil); err != nil {
+				h.logger.Warn("Строка таблицы выполнена с ошибкой",
+					zap.String("batch", b.ID()), zap.Int("line", line), zap.Error(err))
+				return err
+			}
+			return nil
+		})
+		info := b.Info()
+		return gin.H{
+			"message":  fmt.Sprintf("Сценарий %s: успешно строк %d из %d", sc.Name, info.Summary.OK, info.Summary.Total),
+			"batch_id": b.ID(),
+			"batch":    info,
+		}, err
+	})
+}
+
+// ListBatches возвращает пакетные запуски за последний час (без итогов по строкам)
+func (h *Handler) ListBatches(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"batches": h.batches.List(),
+	})
+}
+
+// GetBatch возвращает ход пакетного запуска и итоги по строкам
+func (h *Handler) GetBatch(c *gin.Context) {
+	b, ok := h.loadBatch(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"batch":   b.Info(),
+	})
+}
+
+// GetBatchResult отдает файл результата: исходную таблицу с колонками status и error.
+// Формат — как у исходного файла или ?format=csv|xlsx.
+func (h *Handler) GetBatchResult(c *gin.Context) {
+	b, ok := h.loadBatch(c)
+	if !ok {
+		return
+	}
+
+	info := b.Info()
+	ext := strings.ToLower(filepath.Ext(info.File))
+	if format := c.Query("format"); format != "" {
+		ext = "." + strings.ToLower(format)
+	}
+	name := strings.TrimSuffix(info.File, filepath.Ext(info.File)) + "-result" + ext
+
+	data, err := b.Result().Marshal(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Ошибка формирования файла результата",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if ext == ".xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// batchOptions читает настройки пакетного запуска и версию сценария из полей формы
+func batchOptions(c *gin.Context) (batch.Options, int, error) {
+	var opts batch.Options
+	for field, target := range map[string]any{"mapping": &opts.Mapping, "params": &opts.Params} {
+		value := c.PostForm(field)
+		if value == "" {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(target); err != nil {
+			return opts, 0, fmt.Errorf("%s: ожидается JSON-объект: %w", field, err)
+		}
+	}
+	for field, target := range map[string]*bool{"resume": &opts.Resume, "stop_on_error": &opts.StopOnError} {
+		if value := c.PostForm(field); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, 0, fmt.Errorf("%s: ожидается true или false", field)
+			}
+			*target = parsed
+		}
+	}
+
+	version := 0
+	if value := c.PostForm("version"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return opts, 0, errors.New("version: ожидается номер версии")
+		}
+		version = parsed
+	}
+	return opts, version, nil
+}
+
+// readTable читает загруженную таблицу
+func readTable(header *multipart.FileHeader, sheet string) (*batch.Table, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return batch.ReadTable(header.Filename, data, sheet)
+}
+
+// loadBatch находит пакетный запуск из параметра пути и отправляет ошибку, если его нет
+func (h *Handler) loadBatch(c *gin.Context) (*batch.Batch, bool) {
+	b, err := h.batches.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Пакетный запуск не найден",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+	return b, true
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"goszakup-automation/internal/debugger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DebugScenarioRequest struct {
+	Params map[string]any `json:"params"`
+	// Breakpoints пути шагов ("3", "2.then.1") или имена шагов, перед которыми нужно остановиться
+	Breakpoints []string `json:"breakpoints"`
+	// PauseOnStart остановиться перед первым шагом
+	PauseOnStart bool `json:"pause_on_start"`
+	// Version версия сценария из истории (0 — текущий файл)
+	Version int `json:"version"`
+}
+
+type DebugVarsRequest struct {
+	Vars map[string]any `json:"vars" binding:"required"`
+}
+
+// DebugScenario запускает сценарий в режиме отладки. Выполнение ставится в очередь как задание
+// и останавливается на точках останова; рабочий стол остается занят, пока отладка не завершится.
+func (h *Handler) DebugScenario(c *gin.Context) {
+	var req DebugScenarioRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный формат запроса",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	sc, ok := h.loadScenario(c, req.Version)
+	if !ok {
+		return
+	}
+
+	scope, err := sc.Prepare(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Некорректные параметры сценария",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.failSafe.Check(); err != nil {
+		c.JSON(http.StatusLocked, gin.H{
+			"success": false,
+			"message": "Ввод заблокирован, требуется повторное взведение fail-safe",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	session := debugger.NewSession(h.logger, h.inputService, sc.Name, req.Breakpoints, req.PauseOnStart, h.debugPauseTimeout)
+	job := h.executor.Submit("debug/"+sc.Name, func(ctx context.Context) (any, error) {
+		report, err := h.sequenceRunner.RunWithCleanup(session.Attach(ctx), sc.Steps, sc.Cleanup(), scope, session.Hook)
+		return cleanupResults(gin.H{
+			"message":     fmt.Sprintf("Сценарий %s: выполнено шагов %d из %d", sc.Name, report.Completed(), len(sc.Steps)),
+			"scenario":    sc.Name,
+			"steps":       report.Steps,
+			"vars":        report.Vars,
+			"duration_ms": report.DurationMs,
+		}, report), err
+	})
+	h.debugSessions.Add(job.ID, session)
+
+	// Сеанс завершается вместе с заданием, в том числе если оно отменено в очереди
+	go func() {
+		_, err := job.Wait(context.Background())
+		session.Finish(err)
+	}()
+
+	info := job.Info()
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":        true,
+		"message":        "Отладка поставлена в очередь",
+		"session_id":     job.ID,
+		"job_id":         job.ID,
+		"queue_position": info.QueuePosition,
+		"debug":          session.Info(),
+	})
+}
+
+// GetDebugSession возвращает состояние отладки: текущую паузу и переменные
+func (h *Handler) GetDebugSession(c *gin.Context) {
+	session, ok := h.loadDebugSession(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"debug":   session.Info(),
+	})
+}
+
+// GetDebugScreenshot возвращает PNG скриншот, снятый при остановке
+func (h *Handler) GetDebugScreenshot(c *gin.Context) {
+	session, ok := h.loadDebugSession(c)
+	if !ok {
+		return
+	}
+
+	data, err := session.Screenshot()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, debugger.ErrNotPaused) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "Скриншот недоступен",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// DebugCommand возвращает обработчик команды отладчика (step, continue, skip, abort)
+func (h *Handler) DebugCommand(cmd debugger.Command) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := h.loadDebugSession(c)
+		if !ok {
+			return
+		}
+
+		if err := session.Send(c.Request.Context(), cmd); err != nil {
+			h.debugError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": fmt.Sprintf("Команда %s выполнена", cmd),
+			"debug":   session.Info(),
+		})
+	}
+}
+
+// SetDebugVars изменяет переменные последовательности, стоящей на паузе
+func (h *Handler) SetDebugVars(c *gin.Context) {
+	var req DebugVarsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать vars",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	session, ok := h.loadDebugSession(c)
+	if !ok {
+		return
+	}
+
+	if err := session.SetVars(c.Request.Context(), req.Vars); err != nil {
+		h.debugError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Переменные изменены",
+		"debug":   session.Info(),
+	})
+}
+
+// loadDebugSession находит сеанс отладки из параметра пути и отправляет ошибку, если его нет
+func (h *Handler) loadDebugSession(c *gin.Context) (*debugger.Session, bool) {
+	session, err := h.debugSessions.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Сеанс отладки не найден",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+	return session, true
+}
+
+func (h *Handler) debugError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := "Ошибка выполнения команды"
+	if errors.Is(err, debugger.ErrNotPaused) {
+		status = http.StatusConflict
+		message = "Выполнение не на паузе"
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
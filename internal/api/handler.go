@@ -1,10 +1,25 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"goszakup-automation/internal/batch"
+	"goszakup-automation/internal/debugger"
+	"goszakup-automation/internal/dryrun"
+	"goszakup-automation/internal/executor"
 	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/safety"
+	"goszakup-automation/internal/scenario"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+	"goszakup-automation/internal/templates"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -13,27 +28,314 @@ import (
 type Handler struct {
 	logger       *zap.Logger
 	inputService *input.Service
+	executor     *executor.Executor
+	failSafe     *safety.FailSafe
+	// sequenceRunner выполняет пакетные последовательности шагов
+	sequenceRunner *sequence.Runner
+	// planner выполняет пробные прогоны (dry_run) без реального ввода
+	planner *dryrun.Planner
+	// scenarios сценарии каталога SCENARIOS_DIR с историей версий
+	scenarios *scenario.Library
+	// debugSessions сеансы пошаговой отладки сценариев
+	debugSessions     *debugger.Manager
+	debugPauseTimeout time.Duration
+	// recorder записывает выполненные действия для сохранения в сценарий
+	recorder *recorder.Recorder
+	// batches пакетные запуски сценариев по таблицам CSV/XLSX
+	batches *batch.Manager
+	// locator поиск изображений-шаблонов на экране
+	locator *templates.Locator
+	// reader распознавание текста на экране
+	reader *ocr.Reader
 }
 
 func NewHandler(
 	logger *zap.Logger,
 	inputService *input.Service,
+	executor *executor.Executor,
+	failSafe *safety.FailSafe,
+	scenarios *scenario.Library,
+	debugPauseTimeout time.Duration,
+	recorder *recorder.Recorder,
+	locator *templates.Locator,
+	reader *ocr.Reader,
 ) *Handler {
+	runner := sequence.NewRunner(logger, inputService)
+	runner.SetImageChecker(locator)
+	runner.SetImageLocator(locator)
+	runner.SetTextReader(reader)
+
 	return &Handler{
 		logger:       logger,
 		inputService: inputService,
+		executor:     executor,
+		failSafe:     failSafe,
+
+		sequenceRunner: runner,
+		planner:        dryrun.NewPlanner(logger, inputService),
+		scenarios:      scenarios,
+
+		debugSessions:     debugger.NewManager(),
+		debugPauseTimeout: debugPauseTimeout,
+
+		recorder: recorder,
+		batches:  batch.NewManager(),
+		locator:  locator,
+		reader:   reader,
+	}
+}
+
+// run выполняет действие через общую очередь и отправляет ответ.
+// Задача возвращает поля успешного ответа (gin.H), к ним добавляется позиция в очереди.
+// С параметром ?async=true действие ставится в очередь как задание и сразу возвращается его ID.
+func (h *Handler) run(c *gin.Context, action, failMessage string, task executor.Task) {
+	h.runWith(c, action, failMessage, nil, task)
+}
+
+// runWith выполняет действие как run; fields добавляются к ответу о постановке задания
+// в очередь, например ID, по которому можно следить за ходом выполнения
+func (h *Handler) runWith(c *gin.Context, action, failMessage string, fields gin.H, task executor.Task) {
+	// После аварийного останова не принимаем действия даже в очередь
+	if err := h.failSafe.Check(); err != nil {
+		c.JSON(http.StatusLocked, gin.H{
+			"success": false,
+			"message": "Ввод заблокирован, требуется повторное взведение fail-safe",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		job := h.executor.Submit(action, task)
+		info := job.Info()
+		response := gin.H{
+			"success":        true,
+			"message":        "Задание поставлено в очередь",
+			"job_id":         job.ID,
+			"state":          info.State,
+			"queue_position": info.QueuePosition,
+		}
+		for k, v := range fields {
+			response[k] = v
+		}
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	result, err := h.executor.Do(c.Request.Context(), action, task)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, executor.ErrQueueTimeout):
+			status = http.StatusServiceUnavailable
+			failMessage = "Рабочий стол занят другими действиями"
+		case errors.Is(err, safety.ErrTripped):
+			status = http.StatusLocked
+			failMessage = "Ввод заблокирован, требуется повторное взведение fail-safe"
+		case errors.Is(err, screen.ErrImageNotFound):
+			status = http.StatusNotFound
+			failMessage = "Изображение не найдено на экране"
+		case errors.Is(err, templates.ErrNotFound):
+			status = http.StatusNotFound
+			failMessage = "Шаблон не найден"
+		case errors.Is(err, ocr.ErrNotSupported):
+			status = http.StatusNotImplemented
+			failMessage = "Распознавание текста не поддерживается в этой сборке"
+		}
+		response := gin.H{
+			"job_id": result.JobID,
+		}
+		// Частичный результат (например, отчет по шагам) отдаем и при ошибке
+		if fields, ok := result.Value.(gin.H); ok {
+			for k, v := range fields {
+				response[k] = v
+			}
+		}
+		response["success"] = false
+		response["message"] = failMessage
+		response["error"] = err.Error()
+		c.JSON(status, response)
+		return
+	}
+
+	response := gin.H{
+		"success":        true,
+		"job_id":         result.JobID,
+		"queue_position": result.QueuePosition,
+	}
+	if fields, ok := result.Value.(gin.H); ok {
+		for k, v := range fields {
+			response[k] = v
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// recorded оборачивает задание: после успешного выполнения действие добавляется
+// в активную запись шагами steps (params — значения шаблонов этих шагов)
+func (h *Handler) recorded(action string, params map[string]any, steps []sequence.Step, task executor.Task) executor.Task {
+	return func(ctx context.Context) (any, error) {
+		started := time.Now()
+		result, err := task(ctx)
+		if err == nil {
+			h.recorder.Record(action, started, time.Now(), params, steps...)
+		}
+		return result, err
+	}
+}
+
+// Stop аварийно останавливает все действия: отменяет выполняемое и ожидающие
+// задания и отпускает клавиши и кнопки мыши
+func (h *Handler) Stop(c *gin.Context) {
+	h.logger.Warn("Запрошена аварийная остановка", zap.String("client", c.ClientIP()))
+
+	cancelled, err := safety.Stop(h.executor, h.inputService)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":   false,
+			"message":   "Действия отменены, но не все клавиши удалось отпустить",
+			"error":     err.Error(),
+			"cancelled": cancelled,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Все действия остановлены",
+		"cancelled": cancelled,
+	})
+}
+
+// GetFailSafe возвращает состояние fail-safe
+func (h *Handler) GetFailSafe(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"fail_safe": h.failSafe.Status(),
+	})
+}
+
+// ArmFailSafe повторно взводит fail-safe после срабатывания
+func (h *Handler) ArmFailSafe(c *gin.Context) {
+	if err := h.failSafe.Arm(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Не удалось взвести fail-safe",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Fail-safe взведен, ввод разблокирован",
+		"fail_safe": h.failSafe.Status(),
+	})
+}
+
+// GetHeldKeys возвращает клавиши и кнопки мыши, зажатые сервисом и еще не отпущенные
+func (h *Handler) GetHeldKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"held":    h.inputService.Held(),
+	})
+}
+
+// ReleaseKeys отпускает все зажатые сервисом клавиши и кнопки мыши.
+// Выполняется сразу, без очереди, чтобы помочь и при зависшем действии.
+func (h *Handler) ReleaseKeys(c *gin.Context) {
+	released := h.inputService.Held()
+	if err := h.inputService.ReleaseAll(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Не все клавиши удалось отпустить",
+			"error":   err.Error(),
+			"held":    h.inputService.Held(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Клавиши и кнопки мыши отпущены",
+		"released": released,
+	})
+}
+
+// GetQueue возвращает состояние очереди действий
+func (h *Handler) GetQueue(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"queue":   h.executor.Status(),
+	})
+}
+
+// GetJob возвращает состояние задания
+func (h *Handler) GetJob(c *gin.Context) {
+	job, err := h.executor.Job(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Задание не найдено",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"job":     job.Info(),
+	})
+}
+
+// CancelJob отменяет задание: ожидающее снимается с очереди, выполняемое прерывается
+func (h *Handler) CancelJob(c *gin.Context) {
+	job, err := h.executor.Job(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Задание не найдено",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if job.State().Finished() {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Задание уже завершено",
+			"job":     job.Info(),
+		})
+		return
+	}
+
+	job.Cancel()
+	// Ожидающее задание снимается с очереди сразу, выполняемое — при ближайшей проверке отмены
+	select {
+	case <-job.Done():
+	case <-time.After(100 * time.Millisecond):
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Задание отменено",
+		"job":     job.Info(),
+	})
 }
 
 // ========== Robotogo API для работы с мышью и клавиатурой ==========
 
 // GetMousePosition возвращает текущую позицию мыши
 func (h *Handler) GetMousePosition(c *gin.Context) {
-	x, y := h.inputService.GetMousePosition()
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"x":       x,
-		"y":       y,
+	h.run(c, "mouse/position", "Ошибка определения позиции мыши", func(ctx context.Context) (any, error) {
+		x, y, err := h.inputService.GetMousePosition(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"x": x,
+			"y": y,
+		}, nil
 	})
 }
 
@@ -55,21 +357,17 @@ func (h *Handler) MoveMouse(c *gin.Context) {
 		return
 	}
 
-	if err := h.inputService.MoveMouse(req.X, req.Y); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Ошибка перемещения мыши",
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": fmt.Sprintf("Мышь перемещена на (%d, %d)", req.X, req.Y),
-		"x":       req.X,
-		"y":       req.Y,
-	})
+	step := sequence.Step{Type: sequence.StepMove, X: sequence.IntOf(req.X), Y: sequence.IntOf(req.Y)}
+	h.run(c, "mouse/move", "Ошибка перемещения мыши", h.recorded("mouse/move", nil, []sequence.Step{step}, func(ctx context.Context) (any, error) {
+		if err := h.inputService.MoveMouse(ctx, req.X, req.Y); err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"message": fmt.Sprintf("Мышь перемещена на (%d, %d)", req.X, req.Y),
+			"x":       req.X,
+			"y":       req.Y,
+		}, nil
+	}))
 }
 
 // ClickRequest запрос на клик мышью
@@ -77,6 +375,9 @@ type ClickRequest struct {
 	X      int    `json:"x"`
 	Y      int    `json:"y"`
 	Button string `json:"button"` // left, right, center
+	// Target цель "image:имя" вместо x и y, MatchOptions — параметры поиска шаблона
+	Target string `json:"target"`
+	screen.MatchOptions
 }
 
 // Click выполняет клик мышью
@@ -91,37 +392,52 @@ func (h *Handler) Click(c *gin.Context) {
 		return
 	}
 
-	if req.Button == "" {
-		req.Button = "left"
-	}
-
-	var err error
-	if req.X > 0 && req.Y > 0 {
-		// Клик по координатам
-		err = h.inputService.ClickAt(req.X, req.Y, req.Button)
-	} else {
-		// Клик на текущей позиции
-		err = h.inputService.Click(req.Button)
-	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := checkTarget("target", req.Target, req.MatchOptions, req.X > 0 && req.Y > 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Ошибка клика",
+			"message": "Неверная цель клика",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	message := fmt.Sprintf("Клик выполнен (кнопка: %s)", req.Button)
-	if req.X > 0 && req.Y > 0 {
-		message = fmt.Sprintf("Клик выполнен на (%d, %d) (кнопка: %s)", req.X, req.Y, req.Button)
+	if req.Button == "" {
+		req.Button = "left"
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": message,
-	})
+	step := sequence.Step{Type: sequence.StepClick, Button: req.Button, Target: req.Target, MatchOptions: req.MatchOptions}
+	if req.X > 0 && req.Y > 0 {
+		step.X, step.Y = sequence.IntOf(req.X), sequence.IntOf(req.Y)
+	}
+	h.run(c, "mouse/click", "Ошибка клика", h.recorded("mouse/click", nil, []sequence.Step{step}, func(ctx context.Context) (any, error) {
+		if req.Target != "" {
+			x, y, err := h.locateTarget(ctx, req.Target, req.MatchOptions)
+			if err != nil {
+				return nil, err
+			}
+			req.X, req.Y = x, y
+		}
+
+		var err error
+		if req.X > 0 && req.Y > 0 {
+			// Клик по координатам
+			err = h.inputService.ClickAt(ctx, req.X, req.Y, req.Button)
+		} else {
+			// Клик на текущей позиции
+			err = h.inputService.Click(ctx, req.Button)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		message := fmt.Sprintf("Клик выполнен (кнопка: %s)", req.Button)
+		if req.X > 0 && req.Y > 0 {
+			message = fmt.Sprintf("Клик выполнен на (%d, %d) (кнопка: %s)", req.X, req.Y, req.Button)
+		}
+		return gin.H{
+			"message": message,
+		}, nil
+	}))
 }
 
 // TypeTextRequest запрос на ввод текста
@@ -130,6 +446,9 @@ type TypeTextRequest struct {
 	X        int    `json:"x"`
 	Y        int    `json:"y"`
 	DelayMs  int    `json:"delay_ms"` // Задержка между символами
+	// Target цель "image:имя" вместо x и y, MatchOptions — параметры поиска шаблона
+	Target string `json:"target"`
+	screen.MatchOptions
 }
 
 // TypeText вводит текст
@@ -144,44 +463,62 @@ func (h *Handler) TypeText(c *gin.Context) {
 		return
 	}
 
-	var err error
-	if req.X > 0 && req.Y > 0 {
-		// Ввод текста по координатам
-		err = h.inputService.TypeTextAt(req.X, req.Y, req.Text, req.DelayMs)
-	} else {
-		// Ввод текста на текущей позиции
-		err = h.inputService.TypeText(req.Text, req.DelayMs)
-	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := checkTarget("target", req.Target, req.MatchOptions, req.X > 0 && req.Y > 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Ошибка ввода текста",
+			"message": "Неверная цель ввода текста",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	message := fmt.Sprintf("Текст введен: %s", req.Text)
+	step := sequence.Step{Type: sequence.StepText, Text: recorder.Literal(req.Text), DelayMs: req.DelayMs, Target: req.Target, MatchOptions: req.MatchOptions}
 	if req.X > 0 && req.Y > 0 {
-		message = fmt.Sprintf("Текст введен на (%d, %d): %s", req.X, req.Y, req.Text)
+		step.X, step.Y = sequence.IntOf(req.X), sequence.IntOf(req.Y)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": message,
-		"text":    req.Text,
-	})
+	h.run(c, "keyboard/type", "Ошибка ввода текста", h.recorded("keyboard/type", nil, []sequence.Step{step}, func(ctx context.Context) (any, error) {
+		if req.Target != "" {
+			x, y, err := h.locateTarget(ctx, req.Target, req.MatchOptions)
+			if err != nil {
+				return nil, err
+			}
+			req.X, req.Y = x, y
+		}
+
+		var err error
+		if req.X > 0 && req.Y > 0 {
+			// Ввод текста по координатам
+			err = h.inputService.TypeTextAt(ctx, req.X, req.Y, req.Text, req.DelayMs)
+		} else {
+			// Ввод текста на текущей позиции
+			err = h.inputService.TypeText(ctx, req.Text, req.DelayMs)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		message := fmt.Sprintf("Текст введен: %s", req.Text)
+		if req.X > 0 && req.Y > 0 {
+			message = fmt.Sprintf("Текст введен на (%d, %d): %s", req.X, req.Y, req.Text)
+		}
+		return gin.H{
+			"message": message,
+			"text":    req.Text,
+		}, nil
+	}))
 }
 
 // InputAtCoordinatesRequest запрос на полный цикл ввода
 type InputAtCoordinatesRequest struct {
-	X               int    `json:"x" binding:"required"`
-	Y               int    `json:"y" binding:"required"`
+	X               int    `json:"x"`
+	Y               int    `json:"y"`
 	Text            string `json:"text" binding:"required"`
 	ClearBeforeInput bool  `json:"clear_before_input"`
 	ClickDelay      int    `json:"click_delay_ms"`
 	TypeDelay       int    `json:"type_delay_ms"`
+	// Target цель "image:имя" вместо x и y, MatchOptions — параметры поиска шаблона
+	Target string `json:"target"`
+	screen.MatchOptions
 }
 
 // InputAtCoordinates выполняет полный цикл: клик + ввод текста
@@ -195,6 +532,22 @@ func (h *Handler) InputAtCoordinates(c *gin.Context) {
 		})
 		return
 	}
+	if req.Target == "" && (req.X == 0 || req.Y == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать x, y и text",
+			"error":   "не указаны координаты x и y или цель target",
+		})
+		return
+	}
+	if err := checkTarget("target", req.Target, req.MatchOptions, req.X != 0 || req.Y != 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверная цель ввода",
+			"error":   err.Error(),
+		})
+		return
+	}
 
 	options := &input.InputOptions{
 		ClearBeforeInput: req.ClearBeforeInput,
@@ -210,35 +563,56 @@ func (h *Handler) InputAtCoordinates(c *gin.Context) {
 		options.TypeDelay = 30
 	}
 
-	if err := h.inputService.InputAtCoordinates(req.X, req.Y, req.Text, options); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Ошибка ввода данных",
-			"error":   err.Error(),
-		})
-		return
+	step := sequence.Step{
+		Type:         sequence.StepInput,
+		Text:         recorder.Literal(req.Text),
+		DelayMs:      req.TypeDelay,
+		ClickDelayMs: req.ClickDelay,
+		Target:       req.Target,
+		MatchOptions: req.MatchOptions,
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": fmt.Sprintf("Данные введены на (%d, %d): %s", req.X, req.Y, req.Text),
-		"x":       req.X,
-		"y":       req.Y,
-		"text":    req.Text,
-	})
+	if req.Target == "" {
+		step.X, step.Y = sequence.IntOf(req.X), sequence.IntOf(req.Y)
+	}
+	if req.ClearBeforeInput {
+		step.ClearBeforeInput = &req.ClearBeforeInput
+	}
+	h.run(c, "input", "Ошибка ввода данных", h.recorded("input", nil, []sequence.Step{step}, func(ctx context.Context) (any, error) {
+		if req.Target != "" {
+			x, y, err := h.locateTarget(ctx, req.Target, req.MatchOptions)
+			if err != nil {
+				return nil, err
+			}
+			req.X, req.Y = x, y
+		}
+		if err := h.inputService.InputAtCoordinates(ctx, req.X, req.Y, req.Text, options); err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"message": fmt.Sprintf("Данные введены на (%d, %d): %s", req.X, req.Y, req.Text),
+			"x":       req.X,
+			"y":       req.Y,
+			"text":    req.Text,
+		}, nil
+	}))
 }
 
 // FillInputAndClickRequest запрос на заполнение инпута и клик по кнопке
 type FillInputAndClickRequest struct {
-	InputX            int     `json:"input_x" binding:"required"`
-	InputY            int     `json:"input_y" binding:"required"`
+	InputX            int     `json:"input_x"`
+	InputY            int     `json:"input_y"`
 	Text              string  `json:"text" binding:"required"`
-	ButtonX           int     `json:"button_x" binding:"required"`
-	ButtonY           int     `json:"button_y" binding:"required"`
+	ButtonX           int     `json:"button_x"`
+	ButtonY           int     `json:"button_y"`
 	Button            string  `json:"button"` // left, right, center
 	ClearBeforeInput  *bool   `json:"clear_before_input"` // nil = не указано (по умолчанию true), false = явно false, true = явно true
 	ClickDelay        int     `json:"click_delay_ms"`
 	TypeDelay         int     `json:"type_delay_ms"`
+	// InputTarget и ButtonTarget цели "image:имя" вместо координат инпута и кнопки,
+	// MatchOptions — параметры поиска шаблонов
+	InputTarget  string `json:"input_target"`
+	ButtonTarget string `json:"button_target"`
+	screen.MatchOptions
 }
 
 // FillInputAndClick выполняет полный цикл: наведение на инпут, очистка, ввод текста, клик по кнопке
@@ -252,6 +626,27 @@ func (h *Handler) FillInputAndClick(c *gin.Context) {
 		})
 		return
 	}
+	if (req.InputTarget == "" && (req.InputX == 0 || req.InputY == 0)) || (req.ButtonTarget == "" && (req.ButtonX == 0 || req.ButtonY == 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать input_x, input_y, text, button_x, button_y",
+			"error":   "не указаны координаты инпута или кнопки и цели input_target или button_target",
+		})
+		return
+	}
+	for _, err := range []error{
+		checkTarget("input_target", req.InputTarget, req.MatchOptions, req.InputX != 0 || req.InputY != 0),
+		checkTarget("button_target", req.ButtonTarget, req.MatchOptions, req.ButtonX != 0 || req.ButtonY != 0),
+	} {
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверная цель инпута или кнопки",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
 
 	// Задержка на 4 секунды в начале обработки
 // 	time.Sleep(4 * time.Second)
@@ -280,34 +675,64 @@ func (h *Handler) FillInputAndClick(c *gin.Context) {
 		options.TypeDelay = 30
 	}
 
-	if err := h.inputService.FillInputAndClickButton(
-		req.InputX, req.InputY,
-		req.Text,
-		req.ButtonX, req.ButtonY,
-		req.Button,
-		options,
-	); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Ошибка выполнения операции",
-			"error":   err.Error(),
-		})
-		return
+	step := sequence.Step{
+		Type:         sequence.StepFillAndClick,
+		Text:         recorder.Literal(req.Text),
+		Button:       req.Button,
+		DelayMs:      req.TypeDelay,
+		ClickDelayMs: req.ClickDelay,
+		Target:       req.InputTarget,
+		ButtonTarget: req.ButtonTarget,
+		MatchOptions: req.MatchOptions,
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": fmt.Sprintf("Текст '%s' введен в инпут (%d, %d) и выполнен клик по кнопке (%d, %d)", 
-			req.Text, req.InputX, req.InputY, req.ButtonX, req.ButtonY),
-		"input": gin.H{
-			"x": req.InputX,
-			"y": req.InputY,
-		},
-		"text": req.Text,
-		"button": gin.H{
-			"x":      req.ButtonX,
-			"y":      req.ButtonY,
-			"button": req.Button,
-		},
-	})
+	if req.InputTarget == "" {
+		step.X, step.Y = sequence.IntOf(req.InputX), sequence.IntOf(req.InputY)
+	}
+	if req.ButtonTarget == "" {
+		step.ButtonX, step.ButtonY = sequence.IntOf(req.ButtonX), sequence.IntOf(req.ButtonY)
+	}
+	if req.ClearBeforeInput != nil {
+		step.ClearBeforeInput = &clearBeforeInput
+	}
+	h.run(c, "fill-and-click", "Ошибка выполнения операции", h.recorded("fill-and-click", nil, []sequence.Step{step}, func(ctx context.Context) (any, error) {
+		if req.InputTarget != "" {
+			x, y, err := h.locateTarget(ctx, req.InputTarget, req.MatchOptions)
+			if err != nil {
+				return nil, fmt.Errorf("input_target: %w", err)
+			}
+			req.InputX, req.InputY = x, y
+		}
+		if req.ButtonTarget != "" {
+			x, y, err := h.locateTarget(ctx, req.ButtonTarget, req.MatchOptions)
+			if err != nil {
+				return nil, fmt.Errorf("button_target: %w", err)
+			}
+			req.ButtonX, req.ButtonY = x, y
+		}
+		if err := h.inputService.FillInputAndClickButton(
+			ctx,
+			req.InputX, req.InputY,
+			req.Text,
+			req.ButtonX, req.ButtonY,
+			req.Button,
+			options,
+		); err != nil {
+			return nil, err
+		}
+
+		return gin.H{
+			"message": fmt.Sprintf("Текст '%s' введен в инпут (%d, %d) и выполнен клик по кнопке (%d, %d)",
+				req.Text, req.InputX, req.InputY, req.ButtonX, req.ButtonY),
+			"input": gin.H{
+				"x": req.InputX,
+				"y": req.InputY,
+			},
+			"text": req.Text,
+			"button": gin.H{
+				"x":      req.ButtonX,
+				"y":      req.ButtonY,
+				"button": req.Button,
+			},
+		}, nil
+	}))
 }
@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/api"
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/executor"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/safety"
+	"goszakup-automation/internal/scenario"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/templates"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type testServer struct {
+	router   *gin.Engine
+	backend  *fake.Backend
+	executor *executor.Executor
+	failSafe *safety.FailSafe
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := zap.NewNop()
+	backend := fake.New(fake.Options{Platform: "linux"})
+	service := input.NewService(logger, backend)
+	e := executor.New(logger, 0)
+	failSafe := safety.NewFailSafe(logger, backend, safety.Options{}, nil)
+	service.SetGuard(failSafe)
+
+	handler := api.NewHandler(logger, service, e, failSafe,
+		scenario.NewLibrary(scenario.NewDir(t.TempDir())), time.Minute, recorder.New(""),
+		templates.NewLocator(templates.NewStore(t.TempDir()), service, screen.MatchOptions{}),
+		ocr.NewReader(service, ocr.Options{}))
+
+	router := gin.New()
+	group := router.Group("/api/robotogo")
+	group.POST("/mouse/move", handler.MoveMouse)
+	group.GET("/jobs/:id", handler.GetJob)
+	group.DELETE("/jobs/:id", handler.CancelJob)
+
+	return &testServer{router: router, backend: backend, executor: e, failSafe: failSafe}
+}
+
+// request выполняет запрос и разбирает JSON-ответ
+func (s *testServer) request(t *testing.T, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var response map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: %v: %s", method, path, err, w.Body.String())
+	}
+	return w.Code, response
+}
+
+// jobState возвращает состояние задания по GET /jobs/:id
+func (s *testServer) jobState(t *testing.T, id string) string {
+	t.Helper()
+	code, response := s.request(t, http.MethodGet, "/api/robotogo/jobs/"+id, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /jobs/%s: %d %v", id, code, response)
+	}
+	job, _ := response["job"].(map[string]any)
+	state, _ := job["state"].(string)
+	return state
+}
+
+// waitState опрашивает задание, пока оно не перейдет в состояние want
+func (s *testServer) waitState(t *testing.T, id, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state := s.jobState(t, id)
+		if state == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("задание %s в состоянии %s, ожидалось %s", id, state, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// occupy занимает рабочий стол заданием до закрытия возвращаемого канала
+func (s *testServer) occupy(t *testing.T) chan struct{} {
+	t.Helper()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.executor.Submit("blocker", func(ctx context.Context) (any, error) {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil, nil
+	})
+	<-started
+	return release
+}
+
+// submitMove ставит перемещение мыши в очередь через ?async=true и возвращает ID задания
+func (s *testServer) submitMove(t *testing.T, x, y int) string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]int{"x": x, "y": y})
+	code, response := s.request(t, http.MethodPost, "/api/robotogo/mouse/move?async=true", string(body))
+	if code != http.StatusAccepted || response["success"] != true {
+		t.Fatalf("POST /mouse/move: %d %v", code, response)
+	}
+	if response["state"] != string(executor.StateQueued) || response["queue_position"] != float64(1) {
+		t.Errorf("ответ о постановке в очередь %v", response)
+	}
+	id, _ := response["job_id"].(string)
+	return id
+}
+
+func TestAsyncJobSucceeded(t *testing.T) {
+	s := newTestServer(t)
+	release := s.occupy(t)
+
+	id := s.submitMove(t, 10, 20)
+	if state := s.jobState(t, id); state != string(executor.StateQueued) {
+		t.Errorf("задание до освобождения рабочего стола: %s", state)
+	}
+	if len(s.backend.Events()) != 0 {
+		t.Errorf("ввод до запуска задания: %v", s.backend.Events())
+	}
+
+	close(release)
+	s.waitState(t, id, string(executor.StateSucceeded))
+	if events := s.backend.Events(); len(events) != 1 || events[0].String() != "move(10,20)" {
+		t.Errorf("события %v", events)
+	}
+
+	// Завершенное задание отменить нельзя
+	if code, _ := s.request(t, http.MethodDelete, "/api/robotogo/jobs/"+id, ""); code != http.StatusConflict {
+		t.Errorf("DELETE завершенного задания: %d", code)
+	}
+}
+
+func TestAsyncJobFailed(t *testing.T) {
+	s := newTestServer(t)
+	release := s.occupy(t)
+
+	// Fail-safe срабатывает, пока задание ждет в очереди: ввод при запуске блокируется
+	id := s.submitMove(t, 10, 20)
+	s.failSafe.Trip("тест")
+	close(release)
+	s.waitState(t, id, string(executor.StateFailed))
+
+	_, response := s.request(t, http.MethodGet, "/api/robotogo/jobs/"+id, "")
+	job, _ := response["job"].(map[string]any)
+	if message, _ := job["error"].(string); !strings.Contains(message, safety.ErrTripped.Error()) {
+		t.Errorf("ошибка задания %q", message)
+	}
+	if len(s.backend.Events()) != 0 {
+		t.Errorf("ввод после срабатывания fail-safe: %v", s.backend.Events())
+	}
+
+	// После срабатывания новые задания не принимаются
+	code, _ := s.request(t, http.MethodPost, "/api/robotogo/mouse/move?async=true", `{"x": 1, "y": 2}`)
+	if code != http.StatusLocked {
+		t.Errorf("POST после срабатывания fail-safe: %d", code)
+	}
+}
+
+func TestAsyncJobCancelled(t *testing.T) {
+	s := newTestServer(t)
+	release := s.occupy(t)
+
+	id := s.submitMove(t, 10, 20)
+	code, response := s.request(t, http.MethodDelete, "/api/robotogo/jobs/"+id, "")
+	job, _ := response["job"].(map[string]any)
+	if code != http.StatusOK || job["state"] != string(executor.StateCancelled) {
+		t.Fatalf("DELETE /jobs/%s: %d %v", id, code, response)
+	}
+
+	// Отмененное в очереди задание не запускается и после освобождения рабочего стола
+	close(release)
+	if _, err := s.executor.Do(context.Background(), "next", func(ctx context.Context) (any, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if state := s.jobState(t, id); state != string(executor.StateCancelled) {
+		t.Errorf("задание %s", state)
+	}
+	if len(s.backend.Events()) != 0 {
+		t.Errorf("отмененное задание выполнено: %v", s.backend.Events())
+	}
+
+	if code, _ := s.request(t, http.MethodGet, "/api/robotogo/jobs/unknown", ""); code != http.StatusNotFound {
+		t.Errorf("GET неизвестного задания: %d", code)
+	}
+}
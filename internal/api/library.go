@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"goszakup-automation/internal/scenario"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveScenarioRequest новый сценарий или новая версия: текст content в формате format
+// или объект scenario (сохраняется как JSON)
+type SaveScenarioRequest struct {
+	// Name имя сценария (только при создании)
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	// Format yaml или json (по умолчанию yaml)
+	Format   string          `json:"format"`
+	Scenario json.RawMessage `json:"scenario"`
+	// Comment описание изменения для истории версий
+	Comment string `json:"comment"`
+}
+
+type RollbackScenarioRequest struct {
+	Version int    `json:"version" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// CreateScenario сохраняет новый сценарий как версию 1
+func (h *Handler) CreateScenario(c *gin.Context) {
+	var req SaveScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный формат запроса",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать name",
+		})
+		return
+	}
+
+	h.saveScenario(c, req, http.StatusCreated, gin.H{}, func(ext string, data []byte) (*scenario.Version, error) {
+		return h.scenarios.Create(req.Name, ext, data, req.Comment)
+	})
+}
+
+// UpdateScenario сохраняет новую версию существующего сценария
+func (h *Handler) UpdateScenario(c *gin.Context) {
+	var req SaveScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный формат запроса",
+			"error":   err.Error(),
+		})
+		return
+	}
+	name := c.Param("name")
+
+	h.saveScenario(c, req, http.StatusOK, gin.H{}, func(ext string, data []byte) (*scenario.Version, error) {
+		return h.scenarios.Update(name, ext, data, req.Comment)
+	})
+}
+
+// DeleteScenario удаляет сценарий из каталога. История версий остается для отката.
+func (h *Handler) DeleteScenario(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.scenarios.Delete(name); err != nil {
+		h.libraryError(c, err, "Ошибка удаления сценария")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Сценарий %s удален", name),
+	})
+}
+
+// ListScenarioVersions возвращает историю версий сценария
+func (h *Handler) ListScenarioVersions(c *gin.Context) {
+	name := c.Param("name")
+	versions, err := h.scenarios.Versions(name)
+	if err != nil {
+		h.libraryError(c, err, "Ошибка чтения истории версий")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"scenario": name,
+		"current":  h.scenarios.Current(name),
+		"versions": versions,
+	})
+}
+
+// DiffScenario возвращает разницу двух версий (?from=1&to=2) в формате unified diff.
+// Без to сравнивается с текущим файлом.
+func (h *Handler) DiffScenario(c *gin.Context) {
+	from, ok := h.versionQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := h.versionQuery(c, "to")
+	if !ok {
+		return
+	}
+	if from == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать версию from",
+		})
+		return
+	}
+
+	diff, err := h.scenarios.Diff(c.Param("name"), from, to)
+	if err != nil {
+		h.libraryError(c, err, "Ошибка сравнения версий")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"from":    from,
+		"to":      to,
+		"changed": diff != "",
+		"diff":    diff,
+	})
+}
+
+// RollbackScenario сохраняет содержимое старой версии как новую текущую версию
+func (h *Handler) RollbackScenario(c *gin.Context) {
+	var req RollbackScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать version",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	name := c.Param("name")
+	version, err := h.scenarios.Rollback(name, req.Version, req.Comment)
+	if err != nil {
+		h.libraryError(c, err, "Ошибка отката сценария")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Сценарий %s восстановлен из версии %d как версия %d", name, req.Version, version.Version),
+		"version": version,
+	})
+}
+
+// saveScenario проверяет сценарий линтером и сохраняет его функцией save.
+// fields добавляются к ответу.
+func (h *Handler) saveScenario(c *gin.Context, req SaveScenarioRequest, status int, fields gin.H, save func(ext string, data []byte) (*scenario.Version, error)) {
+	var (
+		ext  string
+		data []byte
+	)
+	switch {
+	case len(req.Scenario) > 0 && req.Content != "":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Укажите content или scenario, но не оба",
+		})
+		return
+	case len(req.Scenario) > 0:
+		var v any
+		if err := json.Unmarshal(req.Scenario, &v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный формат scenario",
+				"error":   err.Error(),
+			})
+			return
+		}
+		data, _ = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+		ext = ".json"
+	case req.Content != "":
+		switch strings.ToLower(req.Format) {
+		case "", "yaml", "yml":
+			ext = ".yaml"
+		case "json":
+			ext = ".json"
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный format, ожидается yaml или json",
+			})
+			return
+		}
+		data = []byte(req.Content)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать content или scenario",
+		})
+		return
+	}
+
+	var opts scenario.LintOptions
+	if width, height, err := h.inputService.Backend().ScreenSize(); err == nil {
+		opts.ScreenWidth, opts.ScreenHeight = width, height
+	}
+	issues := scenario.Lint("scenario"+ext, data, opts)
+	fields["issues"] = issues
+	if scenario.HasErrors(issues) {
+		fields["success"] = false
+		fields["message"] = "Сценарий содержит ошибки"
+		c.JSON(http.StatusUnprocessableEntity, fields)
+		return
+	}
+
+	version, err := save(ext, data)
+	if err != nil {
+		h.libraryError(c, err, "Ошибка сохранения сценария")
+		return
+	}
+
+	fields["success"] = true
+	fields["message"] = fmt.Sprintf("Сохранена версия %d", version.Version)
+	fields["version"] = version
+	c.JSON(status, fields)
+}
+
+// versionQuery читает номер версии из параметра запроса (0, если параметр не указан)
+func (h *Handler) versionQuery(c *gin.Context, key string) (int, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Неверный номер версии %s", key),
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+func (h *Handler) libraryError(c *gin.Context, err error, message string) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, scenario.ErrNotFound):
+		status = http.StatusNotFound
+		message = "Сценарий не найден"
+	case errors.Is(err, scenario.ErrVersionNotFound):
+		status = http.StatusNotFound
+		message = "Версия сценария не найдена"
+	case errors.Is(err, scenario.ErrExists):
+		status = http.StatusConflict
+		message = "Сценарий уже существует"
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"image"
+	"net/http"
+
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/screen"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadTextRequest параметры распознавания текста на экране (JSON)
+type ReadTextRequest struct {
+	// X, Y, Width, Height область распознавания (не указана — весь экран)
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Options языки и режим сегментации (по умолчанию OCR_LANGUAGES и OCR_PSM)
+	ocr.Options
+}
+
+// ReadText распознает текст в области экрана и возвращает текст, слова с их областями
+// в координатах экрана и уверенность распознавания. Распознавание не занимает место
+// в очереди: ввод не выполняется.
+func (h *Handler) ReadText(c *gin.Context) {
+	var req ReadTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверные параметры распознавания текста",
+			"error":   err.Error(),
+		})
+		return
+	}
+	opts := req.Options.WithDefaults(h.reader.Defaults())
+	if err := opts.Validate(); err != nil {
+		h.captureError(c, fmt.Errorf("%w: %v", screen.ErrBadCapture, err))
+		return
+	}
+	if req.X < 0 || req.Y < 0 || req.Width < 0 || req.Height < 0 {
+		h.captureError(c, fmt.Errorf("%w: область (%d, %d) %dx%d", screen.ErrBadCapture, req.X, req.Y, req.Width, req.Height))
+		return
+	}
+	region := image.Rect(req.X, req.Y, req.X+req.Width, req.Y+req.Height)
+
+	result, err := h.reader.ReadText(c.Request.Context(), region, opts)
+	if err != nil {
+		h.captureError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"text":       result.Text,
+		"confidence": result.Confidence,
+		"words":      result.Words,
+		"region":     gin.H{"x": req.X, "y": req.Y, "width": req.Width, "height": req.Height},
+		"languages":  opts.Languages,
+		"psm":        opts.PSM,
+	})
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/scenario"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StopRecordingRequest struct {
+	// Name имя сценария: если указано, запись сохраняется в библиотеку как новый сценарий
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Format yaml или json (по умолчанию yaml)
+	Format string `json:"format"`
+	// Parameterize заменить введенные тексты параметрами text1, text2, ...
+	Parameterize bool `json:"parameterize"`
+	// Comment описание версии в истории
+	Comment string `json:"comment"`
+}
+
+// StartRecording начинает запись действий. Пока запись идет, в нее попадают все успешно
+// выполненные действия API (мышь, клавиатура, input, fill-and-click, последовательности и сценарии),
+// а с source=x11 — физический ввод оператора.
+func (h *Handler) StartRecording(c *gin.Context) {
+	var opts recorder.Options
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный формат запроса",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	rec, err := h.recorder.Start(opts)
+	if err != nil {
+		status := http.StatusBadRequest
+		message := "Ошибка начала записи"
+		switch {
+		case errors.Is(err, recorder.ErrActive):
+			status = http.StatusConflict
+			message = "Запись уже идет, остановите ее перед началом новой"
+		case errors.Is(err, recorder.ErrSourceUnavailable):
+			status = http.StatusServiceUnavailable
+			message = "Источник записи недоступен"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":   true,
+		"message":   "Запись начата",
+		"recording": rec.Info(),
+	})
+}
+
+// ListRecordings возвращает записи (идущую и остановленные за последний час)
+func (h *Handler) ListRecordings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"recordings": h.recorder.List(),
+	})
+}
+
+// GetRecording возвращает записанные действия
+func (h *Handler) GetRecording(c *gin.Context) {
+	rec, err := h.recorder.Get(c.Param("id"))
+	if err != nil {
+		h.recordingNotFound(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"recording": rec.Info(),
+	})
+}
+
+// StopRecording останавливает запись и возвращает сценарий из записанных шагов.
+// С name сценарий сохраняется в библиотеку. Остановленную запись можно остановить повторно,
+// например чтобы сохранить ее под другим именем или в другом формате.
+func (h *Handler) StopRecording(c *gin.Context) {
+	var req StopRecordingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный формат запроса",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	var ext string
+	switch strings.ToLower(req.Format) {
+	case "", "yaml", "yml":
+		ext = ".yaml"
+	case "json":
+		ext = ".json"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный format, ожидается yaml или json",
+		})
+		return
+	}
+
+	rec, err := h.recorder.Stop(c.Param("id"))
+	if err != nil {
+		h.recordingNotFound(c, err)
+		return
+	}
+	info := rec.Info()
+	if len(info.Actions) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success":   true,
+			"message":   "Запись остановлена, действий не записано",
+			"recording": info,
+		})
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = rec.ID()
+	}
+	sc := rec.Scenario(name, recorder.ScenarioOptions{
+		Description:  req.Description,
+		Parameterize: req.Parameterize,
+	})
+	data, err := scenario.Marshal(name+ext, sc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Ошибка построения сценария",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fields := gin.H{
+		"recording": info,
+		"scenario":  sc,
+		"content":   string(data),
+	}
+	if req.Name == "" {
+		fields["success"] = true
+		fields["message"] = fmt.Sprintf("Запись остановлена, шагов: %d", len(sc.Steps))
+		c.JSON(http.StatusOK, fields)
+		return
+	}
+
+	comment := req.Comment
+	if comment == "" {
+		comment = "запись " + rec.ID()
+	}
+	save := SaveScenarioRequest{Name: req.Name, Content: string(data), Format: strings.TrimPrefix(ext, "."), Comment: comment}
+	h.saveScenario(c, save, http.StatusCreated, fields, func(ext string, data []byte) (*scenario.Version, error) {
+		return h.scenarios.Create(req.Name, ext, data, comment)
+	})
+}
+
+// DeleteRecording останавливает и удаляет запись без сохранения
+func (h *Handler) DeleteRecording(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.recorder.Delete(id); err != nil {
+		h.recordingNotFound(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Запись %s удалена", id),
+	})
+}
+
+func (h *Handler) recordingNotFound(c *gin.Context, err error) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"success": false,
+		"message": "Запись не найдена",
+		"error":   err.Error(),
+	})
+}
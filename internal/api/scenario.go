@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"goszakup-automation/internal/scenario"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateScenarioRequest сценарий для проверки: имя файла из каталога сценариев
+// или текст сценария с именем файла (по расширению определяется формат)
+type ValidateScenarioRequest struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+type RunScenarioRequest struct {
+	Params map[string]any `json:"params"`
+	// Version версия сценария из истории (0 — текущий файл)
+	Version int `json:"version"`
+	// DryRun вернуть план событий без реального ввода (то же, что ?dry_run=true)
+	DryRun bool `json:"dry_run"`
+}
+
+// ListScenarios возвращает сценарии из каталога сценариев
+func (h *Handler) ListScenarios(c *gin.Context) {
+	scenarios, err := h.scenarios.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Ошибка чтения сценариев",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"scenarios": scenarios,
+	})
+}
+
+// GetScenario возвращает сценарий по имени: текущий файл или версию ?version=N
+func (h *Handler) GetScenario(c *gin.Context) {
+	version, ok := h.versionQuery(c, "version")
+	if !ok {
+		return
+	}
+	sc, ok := h.loadScenario(c, version)
+	if !ok {
+		return
+	}
+	if version == 0 {
+		version = h.scenarios.Current(sc.Name)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"scenario": sc,
+		"version":  version,
+	})
+}
+
+// RunScenario подставляет параметры в сценарий и выполняет его шаги за одно место в очереди
+func (h *Handler) RunScenario(c *gin.Context) {
+	var req RunScenarioRequest
+	// Тело запроса необязательно: сценарий может не иметь параметров
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Неверный формат запроса",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	sc, ok := h.loadScenario(c, req.Version)
+	if !ok {
+		return
+	}
+	version := req.Version
+	if version == 0 {
+		version = h.scenarios.Current(sc.Name)
+	}
+
+	scope, err := sc.Prepare(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Некорректные параметры сценария",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if h.isDryRun(c, req.DryRun) {
+		h.plan(c, sc.Steps, sc.Cleanup(), scope, gin.H{"scenario": sc.Name, "version": version})
+		return
+	}
+
+	h.run(c, "scenario/"+sc.Name, "Ошибка выполнения сценария", h.recorded("scenario/"+sc.Name, scope.Params, withFinally(sc.Steps, sc.Finally), func(ctx context.Context) (any, error) {
+		report, err := h.sequenceRunner.RunWithCleanup(ctx, sc.Steps, sc.Cleanup(), scope, nil)
+		return cleanupResults(gin.H{
+			"message":     fmt.Sprintf("Сценарий %s: выполнено шагов %d из %d", sc.Name, report.Completed(), len(sc.Steps)),
+			"scenario":    sc.Name,
+			"version":     version,
+			"steps":       report.Steps,
+			"vars":        report.Vars,
+			"duration_ms": report.DurationMs,
+		}, report), err
+	}))
+}
+
+// ValidateScenario проверяет сценарий линтером: синтаксис, схему, имена клавиш,
+// координаты относительно экрана и объявления параметров
+func (h *Handler) ValidateScenario(c *gin.Context) {
+	var req ValidateScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Name == "") == (req.Content == "") {
+		message := "Необходимо указать name или content"
+		if err != nil {
+			message = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный формат запроса",
+			"error":   message,
+		})
+		return
+	}
+
+	filename, data := req.Filename, []byte(req.Content)
+	if req.Name != "" {
+		path, content, err := h.scenarios.Dir().Read(req.Name)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, scenario.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"message": "Сценарий не найден",
+				"error":   err.Error(),
+			})
+			return
+		}
+		filename, data = filepath.Base(path), content
+	}
+	if filename == "" {
+		filename = "scenario.yaml"
+	}
+
+	var opts scenario.LintOptions
+	if width, height, err := h.inputService.Backend().ScreenSize(); err == nil {
+		opts.ScreenWidth, opts.ScreenHeight = width, height
+	}
+	issues := scenario.Lint(filename, data, opts)
+
+	if scenario.HasErrors(issues) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Сценарий содержит ошибки",
+			"file":    filename,
+			"issues":  issues,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Сценарий корректен, предупреждений: %d", len(issues)),
+		"file":    filename,
+		"issues":  issues,
+	})
+}
+
+// loadScenario загружает сценарий из параметра пути (версию version или текущий файл при 0)
+// и отправляет ошибку, если это не удалось
+func (h *Handler) loadScenario(c *gin.Context, version int) (*scenario.Scenario, bool) {
+	sc, err := h.scenarios.Get(c.Param("name"), version)
+	if err != nil {
+		status := http.StatusBadRequest
+		message := "Некорректный сценарий"
+		switch {
+		case errors.Is(err, scenario.ErrNotFound):
+			status = http.StatusNotFound
+			message = "Сценарий не найден"
+		case errors.Is(err, scenario.ErrVersionNotFound):
+			status = http.StatusNotFound
+			message = "Версия сценария не найдена"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+	return sc, true
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"net/http"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/safety"
+	"goszakup-automation/internal/screen"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptureRequest параметры снимка экрана (query)
+type CaptureRequest struct {
+	// X, Y, Width, Height область снимка (не указана — весь экран или монитор)
+	X      int `form:"x"`
+	Y      int `form:"y"`
+	Width  int `form:"width"`
+	Height int `form:"height"`
+	// Display номер монитора, область задается относительно него
+	Display *int `form:"display"`
+	// Scale коэффициент уменьшения от 0 до 1
+	Scale float64 `form:"scale"`
+	// Format png (по умолчанию) или jpeg, Quality — качество JPEG
+	Format  string `form:"format"`
+	Quality int    `form:"quality"`
+	// Base64 вернуть JSON с изображением в base64 вместо двоичных данных
+	Base64 bool `form:"base64"`
+}
+
+// CaptureScreen возвращает снимок экрана, области или монитора. Снимок не занимает место
+// в очереди: ввод не выполняется.
+func (h *Handler) CaptureScreen(c *gin.Context) {
+	var req CaptureRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверные параметры снимка",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	format, err := screen.NormalizeFormat(req.Format)
+	if err != nil {
+		h.captureError(c, err)
+		return
+	}
+
+	img, rect, err := screen.Capture(c.Request.Context(), h.inputService, screen.CaptureOptions{
+		Region:  image.Rect(req.X, req.Y, req.X+req.Width, req.Y+req.Height),
+		Display: req.Display,
+		Scale:   req.Scale,
+	})
+	if err != nil {
+		h.captureError(c, err)
+		return
+	}
+
+	data, err := screen.Encode(img, format, req.Quality)
+	if err != nil {
+		h.captureError(c, err)
+		return
+	}
+
+	if !req.Base64 {
+		c.Data(http.StatusOK, screen.ContentType(format), data)
+		return
+	}
+
+	size := img.Bounds().Size()
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"format":       format,
+		"content_type": screen.ContentType(format),
+		"width":        size.X,
+		"height":       size.Y,
+		"region": gin.H{
+			"x":      rect.Min.X,
+			"y":      rect.Min.Y,
+			"width":  rect.Dx(),
+			"height": rect.Dy(),
+		},
+		"image": base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// PixelRequest параметры пробы цвета (query)
+type PixelRequest struct {
+	X int `form:"x"`
+	Y int `form:"y"`
+	// Width, Height размер области для среднего цвета (не указан — один пиксель)
+	Width  int `form:"width"`
+	Height int `form:"height"`
+	// Color, Tolerance ожидаемый цвет #RRGGBB и допуск для поля matches
+	Color     string `form:"color"`
+	Tolerance int    `form:"tolerance"`
+}
+
+// GetPixel возвращает цвет пикселя или средний цвет небольшой области экрана.
+// С параметром color дополнительно сообщает, совпадает ли цвет с ожидаемым.
+func (h *Handler) GetPixel(c *gin.Context) {
+	var req PixelRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверные параметры пробы цвета",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	width, height := max(req.Width, 1), max(req.Height, 1)
+	if req.X < 0 || req.Y < 0 || req.Width < 0 || req.Height < 0 {
+		h.captureError(c, fmt.Errorf("%w: область (%d, %d) %dx%d", screen.ErrBadCapture, req.X, req.Y, req.Width, req.Height))
+		return
+	}
+
+	var want color.RGBA
+	if req.Color != "" {
+		var err error
+		if want, err = screen.ParseColor(req.Color); err != nil {
+			h.captureError(c, fmt.Errorf("%w: %v", screen.ErrBadCapture, err))
+			return
+		}
+	}
+
+	got, err := screen.AverageColor(c.Request.Context(), h.inputService, image.Rect(req.X, req.Y, req.X+width, req.Y+height))
+	if err != nil {
+		h.captureError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"x":       req.X,
+		"y":       req.Y,
+		"width":   width,
+		"height":  height,
+		"color":   screen.FormatColor(got),
+		"rgb":     gin.H{"r": got.R, "g": got.G, "b": got.B},
+	}
+	if req.Color != "" {
+		response["matches"] = screen.ColorMatches(got, want, req.Tolerance)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// captureError отправляет ошибку снимка экрана с подходящим статусом
+func (h *Handler) captureError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, screen.ErrBadCapture), errors.Is(err, screen.ErrDisplayNotFound):
+		status = http.StatusBadRequest
+	case errors.Is(err, input.ErrCaptureNotSupported), errors.Is(err, ocr.ErrNotSupported):
+		status = http.StatusNotImplemented
+	case errors.Is(err, safety.ErrTripped):
+		status = http.StatusLocked
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": "Ошибка снимка экрана",
+		"error":   err.Error(),
+	})
+}
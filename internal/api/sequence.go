@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goszakup-automation/internal/sequence"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SequenceRequest struct {
+	Steps []sequence.Step `json:"steps" binding:"required"`
+	// OnFailure шаги после ошибки или отмены, Finally — шаги, выполняемые всегда
+	OnFailure []sequence.Step `json:"on_failure"`
+	Finally   []sequence.Step `json:"finally"`
+	// Params значения для шаблонов шагов ({{ .params.имя }})
+	Params map[string]any `json:"params"`
+	// DryRun вернуть план событий без реального ввода (то же, что ?dry_run=true)
+	DryRun bool `json:"dry_run"`
+}
+
+// RunSequence выполняет упорядоченный список шагов за одно место в очереди:
+// действия других запросов не могут вклиниться между шагами
+func (h *Handler) RunSequence(c *gin.Context) {
+	var req SequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверный формат запроса",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	cleanup := sequence.Cleanup{OnFailure: req.OnFailure, Finally: req.Finally}
+	err := sequence.Validate(req.Steps)
+	if err == nil {
+		err = cleanup.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Некорректная последовательность",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if h.isDryRun(c, req.DryRun) {
+		h.plan(c, req.Steps, cleanup, sequence.NewScope(req.Params), gin.H{})
+		return
+	}
+
+	h.run(c, "sequence", "Ошибка выполнения последовательности", h.recorded("sequence", req.Params, withFinally(req.Steps, req.Finally), func(ctx context.Context) (any, error) {
+		report, err := h.sequenceRunner.RunWithCleanup(ctx, req.Steps, cleanup, sequence.NewScope(req.Params), nil)
+		return cleanupResults(gin.H{
+			"message":     fmt.Sprintf("Выполнено шагов: %d из %d", report.Completed(), len(req.Steps)),
+			"steps":       report.Steps,
+			"vars":        report.Vars,
+			"duration_ms": report.DurationMs,
+		}, report), err
+	}))
+}
+
+// isDryRun сообщает, запрошен ли пробный прогон полем dry_run или параметром ?dry_run=true
+func (h *Handler) isDryRun(c *gin.Context, field bool) bool {
+	if field {
+		return true
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	return dryRun
+}
+
+// plan выполняет пробный прогон и отправляет план событий. Очередь и fail-safe
+// не используются: реальный ввод не выполняется. fields добавляются к ответу.
+func (h *Handler) plan(c *gin.Context, steps []sequence.Step, cleanup sequence.Cleanup, scope *sequence.Scope, fields gin.H) {
+	plan, err := h.planner.Plan(c.Request.Context(), steps, cleanup, scope)
+
+	fields["dry_run"] = true
+	fields["events"] = plan.Events
+	fields["estimated_ms"] = plan.EstimatedMs
+	fields["steps"] = plan.Report.Steps
+	fields["vars"] = plan.Report.Vars
+	cleanupResults(fields, plan.Report)
+
+	if err != nil {
+		fields["success"] = false
+		fields["message"] = "Ошибка пробного прогона"
+		fields["error"] = err.Error()
+		c.JSON(http.StatusBadRequest, fields)
+		return
+	}
+
+	fields["success"] = true
+	fields["message"] = fmt.Sprintf("План: %d событий, расчетное время %d мс", len(plan.Events), plan.EstimatedMs)
+	c.JSON(http.StatusOK, fields)
+}
+
+// cleanupResults добавляет к полям ответа результаты блоков on_failure и finally, если они выполнялись
+func cleanupResults(fields gin.H, report *sequence.Report) gin.H {
+	if report.OnFailure != nil {
+		fields["on_failure"] = report.OnFailure
+	}
+	if report.Finally != nil {
+		fields["finally"] = report.Finally
+	}
+	return fields
+}
+
+// withFinally возвращает шаги для записи: основные и finally, которые выполняются после них.
+// on_failure не записывается: в запись попадают только успешные выполнения.
+func withFinally(steps, finally []sequence.Step) []sequence.Step {
+	if len(finally) == 0 {
+		return steps
+	}
+	return append(append([]sequence.Step{}, steps...), finally...)
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+	"goszakup-automation/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTemplates возвращает изображения-шаблоны из каталога TEMPLATES_DIR
+func (h *Handler) ListTemplates(c *gin.Context) {
+	infos, err := h.locator.Store().List()
+	if err != nil {
+		h.templateError(c, err, "Ошибка чтения каталога шаблонов")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"templates": infos,
+		"defaults":  h.locator.Defaults(),
+	})
+}
+
+// GetTemplate возвращает PNG файл шаблона, с параметром info=true — его описание
+func (h *Handler) GetTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if c.Query("info") == "true" {
+		info, err := h.locator.Store().Info(name)
+		if err != nil {
+			h.templateError(c, err, "Ошибка чтения шаблона")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"template": info,
+		})
+		return
+	}
+
+	data, err := h.locator.Store().Read(name)
+	if err != nil {
+		h.templateError(c, err, "Ошибка чтения шаблона")
+		return
+	}
+	c.Data(http.StatusOK, screen.ContentType(screen.FormatPNG), data)
+}
+
+// UploadTemplate сохраняет шаблон. Запрос multipart/form-data: name — имя шаблона,
+// file — изображение PNG или JPEG. Существующий шаблон с тем же именем заменяется.
+func (h *Handler) UploadTemplate(c *gin.Context) {
+	name := c.PostForm("name")
+	header, err := c.FormFile("file")
+	if err != nil || name == "" {
+		message := "Необходимо передать name и изображение в поле file (multipart/form-data)"
+		response := gin.H{"success": false, "message": message}
+		if err != nil {
+			response["error"] = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	data, err := readFormFile(c, "file")
+	if err != nil {
+		h.templateError(c, fmt.Errorf("%w: %v", templates.ErrBadTemplate, err), "Ошибка чтения файла "+header.Filename)
+		return
+	}
+
+	info, err := h.locator.Store().Save(name, data)
+	if err != nil {
+		h.templateError(c, err, "Ошибка сохранения шаблона")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  fmt.Sprintf("Шаблон %s сохранен", info.Name),
+		"template": info,
+	})
+}
+
+// DeleteTemplate удаляет шаблон
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.locator.Store().Delete(name); err != nil {
+		h.templateError(c, err, "Ошибка удаления шаблона")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Шаблон %s удален", name),
+	})
+}
+
+// FindImageRequest параметры поиска изображения на экране (JSON или multipart/form-data)
+type FindImageRequest struct {
+	// Template имя сохраненного шаблона. В multipart вместо него можно передать файл image.
+	Template string `json:"template" form:"template"`
+	// X, Y, Width, Height область поиска (не указана — весь экран)
+	X      int `json:"x" form:"x"`
+	Y      int `json:"y" form:"y"`
+	Width  int `json:"width" form:"width"`
+	Height int `json:"height" form:"height"`
+	// MatchOptions порог совпадения, режим и диапазон масштабов (по умолчанию MATCH_*)
+	screen.MatchOptions
+}
+
+// FindImage ищет шаблон на экране и возвращает координаты центра и степень совпадения.
+// Поиск не занимает место в очереди: ввод не выполняется. Если совпадение ниже порога,
+// возвращается found: false с лучшим найденным совпадением.
+func (h *Handler) FindImage(c *gin.Context) {
+	var req FindImageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Неверные параметры поиска изображения",
+			"error":   err.Error(),
+		})
+		return
+	}
+	opts := req.MatchOptions.WithDefaults(h.locator.Defaults())
+	if err := opts.Validate(); err != nil {
+		h.captureError(c, fmt.Errorf("%w: %v", screen.ErrBadCapture, err))
+		return
+	}
+	if req.X < 0 || req.Y < 0 || req.Width < 0 || req.Height < 0 {
+		h.captureError(c, fmt.Errorf("%w: область (%d, %d) %dx%d", screen.ErrBadCapture, req.X, req.Y, req.Width, req.Height))
+		return
+	}
+	region := image.Rect(req.X, req.Y, req.X+req.Width, req.Y+req.Height)
+
+	var match screen.Match
+	var err error
+	if data, fileErr := readFormFile(c, "image"); fileErr == nil {
+		tmpl, _, decodeErr := image.Decode(bytes.NewReader(data))
+		if decodeErr != nil {
+			h.templateError(c, fmt.Errorf("%w: ошибка декодирования изображения: %v", templates.ErrBadTemplate, decodeErr), "Ошибка поиска изображения")
+			return
+		}
+		match, err = h.locator.FindImage(c.Request.Context(), tmpl, region, opts)
+	} else if req.Template != "" {
+		match, err = h.locator.Find(c.Request.Context(), req.Template, region, opts)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Необходимо указать template или передать изображение в поле image (multipart/form-data)",
+		})
+		return
+	}
+
+	found := err == nil
+	if err != nil && !errors.Is(err, screen.ErrImageNotFound) {
+		h.templateError(c, err, "Ошибка поиска изображения")
+		return
+	}
+
+	response := gin.H{
+		"success":    true,
+		"found":      found,
+		"threshold":  opts.Threshold,
+		"match_mode": opts.Mode,
+		"match":      match,
+	}
+	if req.Template != "" {
+		response["template"] = req.Template
+	}
+	if !found {
+		response["message"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// locateTarget возвращает координаты цели "image:имя" для простых действий мыши и клавиатуры
+func (h *Handler) locateTarget(ctx context.Context, target string, opts screen.MatchOptions) (int, int, error) {
+	name, err := sequence.ParseTarget(target)
+	if err != nil {
+		return 0, 0, err
+	}
+	p, err := h.locator.LocateImage(ctx, name, nil, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	return p.X, p.Y, nil
+}
+
+// checkTarget проверяет цель запроса до постановки в очередь: target заменяет координаты
+func checkTarget(field, target string, opts screen.MatchOptions, hasCoordinates bool) error {
+	if target == "" {
+		return nil
+	}
+	if hasCoordinates {
+		return fmt.Errorf("%s нельзя указывать вместе с координатами", field)
+	}
+	if _, err := sequence.ParseTarget(target); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return opts.Validate()
+}
+
+// readFormFile читает файл из поля формы multipart
+func readFormFile(c *gin.Context, field string) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// templateError отправляет ошибку работы с шаблонами с подходящим статусом
+func (h *Handler) templateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, templates.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, templates.ErrBadTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+	default:
+		h.captureError(c, err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package all подключает все бэкенды ввода, доступные в текущей сборке.
+//
+// robotgo требует cgo и исключается при CGO_ENABLED=0 или тегом norobotgo,
+// x11 написан на чистом Go и исключается тегом nox11. fake записывает события
+// в память и нужен для запуска без рабочего стола (CI, проверка сценариев).
+package all
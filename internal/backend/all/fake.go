@@ -0,0 +1,3 @@
+package all
+
+import _ "goszakup-automation/internal/backend/fake"
@@ -0,0 +1,5 @@
+//go:build cgo && !norobotgo
+
+package all
+
+import _ "goszakup-automation/internal/backend/robotgo"
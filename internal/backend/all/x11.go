@@ -0,0 +1,5 @@
+//go:build !nox11
+
+package all
+
+import _ "goszakup-automation/internal/backend/x11"
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"goszakup-automation/internal/input"
+)
+
+// preferred порядок выбора бэкенда, если имя не указано в конфигурации:
+// robotgo доступен только в сборке с cgo, x11 — всегда
+var preferred = []string{"robotgo", "x11"}
+
+// Options параметры создания бэкенда
+type Options struct {
+	// Display адрес X-сервера (для бэкендов, работающих с X11)
+	Display string
+}
+
+// Factory создает экземпляр бэкенда
+type Factory func(opts Options) (input.Backend, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register регистрирует бэкенд под указанным именем.
+// Вызывается из init() пакетов с реализациями.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("backend: бэкенд %q уже зарегистрирован", name))
+	}
+	factories[name] = factory
+}
+
+// New создает бэкенд по имени. Пустое имя означает первый доступный
+// в этой сборке бэкенд (robotgo, затем x11).
+func New(name string, opts Options) (input.Backend, error) {
+	if name == "" {
+		name = defaultName()
+	}
+
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("неизвестный бэкенд ввода %q (доступны: %v)", name, Names())
+	}
+
+	b, err := factory(opts)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации бэкенда %q: %w", name, err)
+	}
+	return b, nil
+}
+
+// Names возвращает имена зарегистрированных бэкендов
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func defaultName() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, name := range preferred {
+		if _, ok := factories[name]; ok {
+			return name
+		}
+	}
+	return preferred[0]
+}
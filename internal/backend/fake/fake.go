@@ -0,0 +1,250 @@
+// Package fake реализует input.Backend в памяти: записывает все низкоуровневые
+// события с виртуальными временными метками и отдает скриншоты из заранее
+// подготовленных кадров. Не трогает реальные мышь и клавиатуру.
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/backend"
+	"goszakup-automation/internal/input"
+)
+
+// Name имя бэкенда в конфигурации
+const Name = "fake"
+
+func init() {
+	backend.Register(Name, func(backend.Options) (input.Backend, error) {
+		return New(Options{}), nil
+	})
+}
+
+// EventType тип записанного события
+type EventType string
+
+const (
+	EventMove           EventType = "move"
+	EventClick          EventType = "click"
+	EventMouseDown      EventType = "mouse_down"
+	EventMouseUp        EventType = "mouse_up"
+	EventScroll         EventType = "scroll"
+	EventKeyTap         EventType = "key_tap"
+	EventKeyDown        EventType = "key_down"
+	EventKeyUp          EventType = "key_up"
+	EventTypeRune       EventType = "type"
+	EventClipboardWrite EventType = "clipboard_write"
+)
+
+// Event низкоуровневое событие ввода
+type Event struct {
+	// At виртуальное время события от начала записи
+	At time.Duration `json:"-"`
+	// AtMs то же время в миллисекундах (для JSON)
+	AtMs      int64     `json:"at_ms"`
+	Type      EventType `json:"type"`
+	X         int       `json:"x,omitempty"`
+	Y         int       `json:"y,omitempty"`
+	Button    string    `json:"button,omitempty"`
+	Double    bool      `json:"double,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Modifiers []string  `json:"modifiers,omitempty"`
+	Text      string    `json:"text,omitempty"`
+}
+
+// String возвращает компактное текстовое представление события для сравнения в тестах
+func (e Event) String() string {
+	switch e.Type {
+	case EventMove:
+		return fmt.Sprintf("%s(%d,%d)", e.Type, e.X, e.Y)
+	case EventClick, EventMouseDown, EventMouseUp:
+		if e.Double {
+			return fmt.Sprintf("%s(%s,double)", e.Type, e.Button)
+		}
+		return fmt.Sprintf("%s(%s)", e.Type, e.Button)
+	case EventScroll:
+		return fmt.Sprintf("%s(%d,%d)", e.Type, e.X, e.Y)
+	case EventKeyTap:
+		if len(e.Modifiers) > 0 {
+			return fmt.Sprintf("%s(%s+%v)", e.Type, e.Key, e.Modifiers)
+		}
+		return fmt.Sprintf("%s(%s)", e.Type, e.Key)
+	case EventKeyDown, EventKeyUp:
+		return fmt.Sprintf("%s(%s)", e.Type, e.Key)
+	}
+	return fmt.Sprintf("%s(%q)", e.Type, e.Text)
+}
+
+// Options параметры fake бэкенда
+type Options struct {
+	// Platform платформа, под которую Service выбирает ветку поведения
+	// (windows, darwin, linux). Пустое значение — runtime.GOOS.
+	Platform string
+	// Width, Height размер виртуального экрана (по умолчанию 1920x1080)
+	Width  int
+	Height int
+}
+
+// Backend записывающий бэкенд с виртуальными часами
+type Backend struct {
+	opts Options
+
+	mu        sync.Mutex
+	now       time.Duration
+	events    []Event
+	x, y      int
+	clipboard string
+	frames    []Frame
+}
+
+// New создает fake бэкенд
+func New(opts Options) *Backend {
+	if opts.Width <= 0 {
+		opts.Width = 1920
+	}
+	if opts.Height <= 0 {
+		opts.Height = 1080
+	}
+	// Курсор изначально в центре экрана, как после запуска X-сервера,
+	// чтобы не попасть в угол срабатывания fail-safe
+	return &Backend{opts: opts, x: opts.Width / 2, y: opts.Height / 2}
+}
+
+// Name возвращает имя бэкенда
+func (b *Backend) Name() string {
+	return Name
+}
+
+// Platform возвращает платформу, которую эмулирует бэкенд
+func (b *Backend) Platform() string {
+	return b.opts.Platform
+}
+
+// Sleep продвигает виртуальные часы без реального ожидания
+func (b *Backend) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.now += d
+	b.mu.Unlock()
+}
+
+// Now возвращает текущее виртуальное время
+func (b *Backend) Now() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.now
+}
+
+// Events возвращает копию записанных событий
+func (b *Backend) Events() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make([]Event, len(b.events))
+	copy(events, b.events)
+	return events
+}
+
+// Reset очищает записанные события и сбрасывает виртуальные часы
+func (b *Backend) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = nil
+	b.now = 0
+}
+
+func (b *Backend) record(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.At = b.now
+	e.AtMs = b.now.Milliseconds()
+	b.events = append(b.events, e)
+}
+
+// MoveMouse запоминает позицию курсора
+func (b *Backend) MoveMouse(x, y int) error {
+	b.mu.Lock()
+	b.x, b.y = x, y
+	b.mu.Unlock()
+
+	b.record(Event{Type: EventMove, X: x, Y: y})
+	return nil
+}
+
+// Click записывает клик
+func (b *Backend) Click(button string, double bool) error {
+	b.record(Event{Type: EventClick, Button: button, Double: double})
+	return nil
+}
+
+// MouseToggle записывает нажатие или отпускание кнопки мыши
+func (b *Backend) MouseToggle(button string, down bool) error {
+	if down {
+		b.record(Event{Type: EventMouseDown, Button: button})
+	} else {
+		b.record(Event{Type: EventMouseUp, Button: button})
+	}
+	return nil
+}
+
+// Scroll записывает прокрутку
+func (b *Backend) Scroll(x, y int) error {
+	b.record(Event{Type: EventScroll, X: x, Y: y})
+	return nil
+}
+
+// MousePosition возвращает последнюю позицию курсора
+func (b *Backend) MousePosition() (int, int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.x, b.y, nil
+}
+
+// KeyTap записывает нажатие клавиши
+func (b *Backend) KeyTap(key string, modifiers ...string) error {
+	b.record(Event{Type: EventKeyTap, Key: key, Modifiers: append([]string(nil), modifiers...)})
+	return nil
+}
+
+// KeyToggle записывает нажатие или отпускание клавиши
+func (b *Backend) KeyToggle(key string, down bool) error {
+	if down {
+		b.record(Event{Type: EventKeyDown, Key: key})
+	} else {
+		b.record(Event{Type: EventKeyUp, Key: key})
+	}
+	return nil
+}
+
+// TypeRune записывает ввод символа
+func (b *Backend) TypeRune(r rune) error {
+	b.record(Event{Type: EventTypeRune, Text: string(r)})
+	return nil
+}
+
+// ReadClipboard возвращает содержимое виртуального буфера обмена
+func (b *Backend) ReadClipboard() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.clipboard, nil
+}
+
+// WriteClipboard записывает текст в виртуальный буфер обмена
+func (b *Backend) WriteClipboard(text string) error {
+	b.mu.Lock()
+	b.clipboard = text
+	b.mu.Unlock()
+
+	b.record(Event{Type: EventClipboardWrite, Text: text})
+	return nil
+}
+
+// ScreenSize возвращает размер виртуального экрана
+func (b *Backend) ScreenSize() (int, int, error) {
+	return b.opts.Width, b.opts.Height, nil
+}
@@ -0,0 +1,75 @@
+package fake
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"sort"
+	"time"
+)
+
+// Frame кадр виртуального экрана, который показывается начиная с виртуального времени At
+type Frame struct {
+	At    time.Duration
+	Image image.Image
+}
+
+// AddFrame добавляет кадр, который становится текущим в момент at виртуального времени
+func (b *Backend) AddFrame(at time.Duration, img image.Image) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.frames = append(b.frames, Frame{At: at, Image: img})
+	sort.SliceStable(b.frames, func(i, j int) bool {
+		return b.frames[i].At < b.frames[j].At
+	})
+}
+
+// LoadFrame загружает PNG файл как кадр, показываемый начиная с момента at
+func (b *Backend) LoadFrame(at time.Duration, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия кадра: %w", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return fmt.Errorf("ошибка декодирования кадра %s: %w", path, err)
+	}
+
+	b.AddFrame(at, img)
+	return nil
+}
+
+// CaptureScreen возвращает область текущего кадра. Если кадров нет или текущий момент
+// раньше первого кадра, возвращается черный экран.
+func (b *Backend) CaptureScreen(rect image.Rectangle) (image.Image, error) {
+	b.mu.Lock()
+	var current image.Image
+	for _, frame := range b.frames {
+		if frame.At > b.now {
+			break
+		}
+		current = frame.Image
+	}
+	b.mu.Unlock()
+
+	screen := image.Rect(0, 0, b.opts.Width, b.opts.Height)
+	if rect.Empty() {
+		rect = screen
+	}
+	rect = rect.Intersect(screen)
+	if rect.Empty() {
+		return nil, fmt.Errorf("область захвата вне экрана")
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(out, out.Bounds(), image.Black, image.Point{}, draw.Src)
+	if current != nil {
+		draw.Draw(out, out.Bounds(), current, rect.Min, draw.Src)
+	}
+	return out, nil
+}
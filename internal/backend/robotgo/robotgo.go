@@ -0,0 +1,138 @@
+//go:build cgo
+
+// Package robotgo реализует input.Backend поверх github.com/go-vgo/robotgo
+// (локальный рабочий стол, требует cgo).
+package robotgo
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+
+	"github.com/go-vgo/robotgo"
+
+	"goszakup-automation/internal/backend"
+	"goszakup-automation/internal/input"
+)
+
+// Name имя бэкенда в конфигурации
+const Name = "robotgo"
+
+func init() {
+	backend.Register(Name, func(backend.Options) (input.Backend, error) {
+		return New(), nil
+	})
+}
+
+// Backend бэкенд ввода на основе robotgo
+type Backend struct{}
+
+// New создает robotgo бэкенд
+func New() *Backend {
+	return &Backend{}
+}
+
+// Name возвращает имя бэкенда
+func (b *Backend) Name() string {
+	return Name
+}
+
+// MoveMouse перемещает курсор
+func (b *Backend) MoveMouse(x, y int) error {
+	robotgo.MoveMouse(x, y)
+	return nil
+}
+
+// Click выполняет клик
+func (b *Backend) Click(button string, double bool) error {
+	robotgo.MouseClick(button, double)
+	return nil
+}
+
+// MouseToggle нажимает или отпускает кнопку мыши
+func (b *Backend) MouseToggle(button string, down bool) error {
+	if down {
+		return robotgo.Toggle(button)
+	}
+	return robotgo.Toggle(button, "up")
+}
+
+// Scroll прокручивает колесико мыши
+func (b *Backend) Scroll(x, y int) error {
+	robotgo.Scroll(x, y)
+	return nil
+}
+
+// MousePosition возвращает позицию курсора
+func (b *Backend) MousePosition() (int, int, error) {
+	x, y := robotgo.GetMousePos()
+	return x, y, nil
+}
+
+// KeyTap нажимает клавишу с модификаторами
+func (b *Backend) KeyTap(key string, modifiers ...string) error {
+	if len(modifiers) == 0 {
+		return robotgo.KeyTap(key)
+	}
+	return robotgo.KeyTap(key, modifiers)
+}
+
+// KeyToggle нажимает или отпускает клавишу
+func (b *Backend) KeyToggle(key string, down bool) error {
+	if down {
+		return robotgo.KeyToggle(key, "down", []string{})
+	}
+	return robotgo.KeyToggle(key, "up", []string{})
+}
+
+// TypeRune вводит один символ
+func (b *Backend) TypeRune(r rune) error {
+	// На Windows используем Unicode события напрямую (работает в модальных окнах,
+	// где TypeStr может не работать)
+	if runtime.GOOS == "windows" {
+		robotgo.UnicodeType(uint32(r))
+		return nil
+	}
+	robotgo.TypeStr(string(r))
+	return nil
+}
+
+// ReadClipboard читает буфер обмена
+func (b *Backend) ReadClipboard() (string, error) {
+	return robotgo.ReadAll()
+}
+
+// WriteClipboard записывает текст в буфер обмена
+func (b *Backend) WriteClipboard(text string) error {
+	return robotgo.WriteAll(text)
+}
+
+// ScreenSize возвращает размер основного экрана
+func (b *Backend) ScreenSize() (int, int, error) {
+	w, h := robotgo.GetScreenSize()
+	return w, h, nil
+}
+
+// Displays возвращает границы мониторов
+func (b *Backend) Displays() ([]image.Rectangle, error) {
+	n := robotgo.DisplaysNum()
+	displays := make([]image.Rectangle, 0, n)
+	for i := 0; i < n; i++ {
+		x, y, w, h := robotgo.GetDisplayBounds(i)
+		displays = append(displays, image.Rect(x, y, x+w, y+h))
+	}
+	return displays, nil
+}
+
+// CaptureScreen снимает скриншот области экрана
+func (b *Backend) CaptureScreen(rect image.Rectangle) (image.Image, error) {
+	if rect.Empty() {
+		return robotgo.CaptureImg()
+	}
+
+	img, err := robotgo.CaptureImg(rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, fmt.Errorf("robotgo: %w", err)
+	}
+	return img, nil
+}
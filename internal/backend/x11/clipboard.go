@@ -0,0 +1,191 @@
+package x11
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// clipboardTimeout время ожидания ответа владельца буфера обмена
+const clipboardTimeout = time.Second
+
+// clipboard состояние буфера обмена (X11 selection CLIPBOARD)
+type clipboard struct {
+	selection xproto.Atom
+	utf8      xproto.Atom
+	targets   xproto.Atom
+	property  xproto.Atom
+
+	mu    sync.Mutex
+	owned bool
+	text  string
+	// notifications ответы на ConvertSelection
+	notifications chan xproto.SelectionNotifyEvent
+}
+
+func (b *Backend) initClipboard() error {
+	wid, err := xproto.NewWindowId(b.conn)
+	if err != nil {
+		return fmt.Errorf("ошибка выделения id окна: %w", err)
+	}
+	err = xproto.CreateWindowChecked(b.conn, 0, wid, b.screen.Root,
+		0, 0, 1, 1, 0, xproto.WindowClassInputOnly, 0, 0, nil).Check()
+	if err != nil {
+		return fmt.Errorf("ошибка создания окна для буфера обмена: %w", err)
+	}
+	b.window = wid
+
+	clip := &clipboard{notifications: make(chan xproto.SelectionNotifyEvent, 1)}
+	atoms := []struct {
+		name string
+		atom *xproto.Atom
+	}{
+		{"CLIPBOARD", &clip.selection},
+		{"UTF8_STRING", &clip.utf8},
+		{"TARGETS", &clip.targets},
+		{"GOSZAKUP_CLIPBOARD", &clip.property},
+	}
+	for _, a := range atoms {
+		reply, err := xproto.InternAtom(b.conn, false, uint16(len(a.name)), a.name).Reply()
+		if err != nil {
+			return fmt.Errorf("ошибка InternAtom %s: %w", a.name, err)
+		}
+		*a.atom = reply.Atom
+	}
+	b.clip = clip
+
+	return nil
+}
+
+// ReadClipboard читает текст из буфера обмена
+func (b *Backend) ReadClipboard() (string, error) {
+	clip := b.clip
+
+	clip.mu.Lock()
+	if clip.owned {
+		text := clip.text
+		clip.mu.Unlock()
+		return text, nil
+	}
+	clip.mu.Unlock()
+
+	// Сбрасываем устаревшие ответы
+	select {
+	case <-clip.notifications:
+	default:
+	}
+
+	err := xproto.ConvertSelectionChecked(b.conn, b.window, clip.selection, clip.utf8,
+		clip.property, xproto.TimeCurrentTime).Check()
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса буфера обмена: %w", err)
+	}
+
+	var notify xproto.SelectionNotifyEvent
+	select {
+	case notify = <-clip.notifications:
+	case <-time.After(clipboardTimeout):
+		return "", fmt.Errorf("владелец буфера обмена не ответил за %s", clipboardTimeout)
+	}
+	if notify.Property == xproto.AtomNone {
+		// Буфер обмена пуст или владелец не поддерживает UTF8_STRING
+		return "", nil
+	}
+
+	reply, err := xproto.GetProperty(b.conn, true, b.window, notify.Property,
+		xproto.GetPropertyTypeAny, 0, 1<<24).Reply()
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения буфера обмена: %w", err)
+	}
+	return string(reply.Value), nil
+}
+
+// WriteClipboard записывает текст в буфер обмена. Текст хранится в бэкенде
+// и отдается другим приложениям по запросу, пока бэкенд владеет буфером.
+func (b *Backend) WriteClipboard(text string) error {
+	clip := b.clip
+
+	clip.mu.Lock()
+	clip.text = text
+	clip.owned = true
+	clip.mu.Unlock()
+
+	err := xproto.SetSelectionOwnerChecked(b.conn, b.window, clip.selection, xproto.TimeCurrentTime).Check()
+	if err != nil {
+		return fmt.Errorf("ошибка захвата буфера обмена: %w", err)
+	}
+
+	reply, err := xproto.GetSelectionOwner(b.conn, clip.selection).Reply()
+	if err != nil {
+		return fmt.Errorf("ошибка проверки владельца буфера обмена: %w", err)
+	}
+	if reply.Owner != b.window {
+		clip.mu.Lock()
+		clip.owned = false
+		clip.mu.Unlock()
+		return fmt.Errorf("не удалось стать владельцем буфера обмена")
+	}
+	return nil
+}
+
+// handleSelectionRequest отдает содержимое буфера обмена другому приложению
+func (b *Backend) handleSelectionRequest(e xproto.SelectionRequestEvent) {
+	clip := b.clip
+
+	clip.mu.Lock()
+	text := clip.text
+	clip.mu.Unlock()
+
+	property := e.Property
+	if property == xproto.AtomNone {
+		// Устаревшие клиенты не указывают свойство
+		property = e.Target
+	}
+
+	switch e.Target {
+	case clip.targets:
+		// xgb всегда подключается с порядком байт little-endian
+		data := make([]byte, 0, 12)
+		for _, atom := range []xproto.Atom{clip.targets, clip.utf8, xproto.AtomString} {
+			data = binary.LittleEndian.AppendUint32(data, uint32(atom))
+		}
+		xproto.ChangeProperty(b.conn, xproto.PropModeReplace, e.Requestor, property,
+			xproto.AtomAtom, 32, 3, data)
+	case clip.utf8, xproto.AtomString:
+		xproto.ChangeProperty(b.conn, xproto.PropModeReplace, e.Requestor, property,
+			e.Target, 8, uint32(len(text)), []byte(text))
+	default:
+		property = xproto.AtomNone
+	}
+
+	notify := xproto.SelectionNotifyEvent{
+		Time:      e.Time,
+		Requestor: e.Requestor,
+		Selection: e.Selection,
+		Target:    e.Target,
+		Property:  property,
+	}
+	xproto.SendEvent(b.conn, false, e.Requestor, 0, string(notify.Bytes()))
+}
+
+// notify передает ответ на ConvertSelection ожидающему ReadClipboard
+func (c *clipboard) notify(e xproto.SelectionNotifyEvent) {
+	select {
+	case c.notifications <- e:
+	default:
+	}
+}
+
+// clear вызывается, когда другое приложение забирает буфер обмена
+func (c *clipboard) clear(e xproto.SelectionClearEvent) {
+	if e.Selection != c.selection {
+		return
+	}
+	c.mu.Lock()
+	c.owned = false
+	c.text = ""
+	c.mu.Unlock()
+}
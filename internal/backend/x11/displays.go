@@ -0,0 +1,32 @@
+package x11
+
+import (
+	"image"
+
+	"github.com/jezek/xgb/xinerama"
+)
+
+// Displays возвращает границы мониторов через расширение Xinerama. Если расширение
+// недоступно или неактивно (например, в Xvfb), весь экран считается одним монитором.
+func (b *Backend) Displays() ([]image.Rectangle, error) {
+	root := image.Rect(0, 0, int(b.screen.WidthInPixels), int(b.screen.HeightInPixels))
+	if !b.xinerama {
+		return []image.Rectangle{root}, nil
+	}
+
+	active, err := xinerama.IsActive(b.conn).Reply()
+	if err != nil || active.State == 0 {
+		return []image.Rectangle{root}, nil
+	}
+	reply, err := xinerama.QueryScreens(b.conn).Reply()
+	if err != nil || len(reply.ScreenInfo) == 0 {
+		return []image.Rectangle{root}, nil
+	}
+
+	displays := make([]image.Rectangle, 0, len(reply.ScreenInfo))
+	for _, info := range reply.ScreenInfo {
+		x, y := int(info.XOrg), int(info.YOrg)
+		displays = append(displays, image.Rect(x, y, x+int(info.Width), y+int(info.Height)))
+	}
+	return displays, nil
+}
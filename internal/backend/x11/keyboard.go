@@ -0,0 +1,286 @@
+package x11
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// keysyms соответствие имен клавиш robotgo и X11 keysym
+var keysyms = map[string]xproto.Keysym{
+	"enter":       0xff0d,
+	"return":      0xff0d,
+	"tab":         0xff09,
+	"space":       0x0020,
+	"backspace":   0xff08,
+	"delete":      0xffff,
+	"escape":      0xff1b,
+	"esc":         0xff1b,
+	"up":          0xff52,
+	"down":        0xff54,
+	"left":        0xff51,
+	"right":       0xff53,
+	"home":        0xff50,
+	"end":         0xff57,
+	"pageup":      0xff55,
+	"pagedown":    0xff56,
+	"insert":      0xff63,
+	"capslock":    0xffe5,
+	"printscreen": 0xff61,
+	"menu":        0xff67,
+
+	"ctrl":    0xffe3,
+	"control": 0xffe3,
+	"lctrl":   0xffe3,
+	"rctrl":   0xffe4,
+	"shift":   0xffe1,
+	"lshift":  0xffe1,
+	"rshift":  0xffe2,
+	"alt":     0xffe9,
+	"lalt":    0xffe9,
+	"ralt":    0xffea,
+	"cmd":     0xffeb,
+	"command": 0xffeb,
+	"lcmd":    0xffeb,
+	"rcmd":    0xffec,
+}
+
+const shiftKeysym xproto.Keysym = 0xffe1
+
+// keyCode код клавиши и необходимость удерживать Shift
+type keyCode struct {
+	code  xproto.Keycode
+	shift bool
+}
+
+// keyboard раскладка клавиатуры X-сервера
+type keyboard struct {
+	minCode  xproto.Keycode
+	perCode  byte
+	codes    map[xproto.Keysym]keyCode
+	shiftKey xproto.Keycode
+
+	// spare свободный код клавиши, на который временно назначаются символы,
+	// отсутствующие в текущей раскладке (например, кириллица при английской раскладке)
+	spare       xproto.Keycode
+	spareKeysym xproto.Keysym
+}
+
+func loadKeyboard(conn *xgb.Conn, setup *xproto.SetupInfo) (*keyboard, error) {
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+	reply, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения раскладки клавиатуры: %w", err)
+	}
+
+	kb := &keyboard{
+		minCode: setup.MinKeycode,
+		perCode: reply.KeysymsPerKeycode,
+		codes:   make(map[xproto.Keysym]keyCode),
+	}
+
+	per := int(reply.KeysymsPerKeycode)
+	for i := 0; i < int(count); i++ {
+		code := setup.MinKeycode + xproto.Keycode(i)
+		syms := reply.Keysyms[i*per : (i+1)*per]
+
+		empty := true
+		for _, sym := range syms {
+			if sym != 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			kb.spare = code
+			continue
+		}
+
+		// Учитываем только первую группу: без Shift и с Shift
+		for level := 0; level < 2 && level < per; level++ {
+			sym := syms[level]
+			if sym == 0 {
+				continue
+			}
+			if _, exists := kb.codes[sym]; !exists {
+				kb.codes[sym] = keyCode{code: code, shift: level == 1}
+			}
+		}
+	}
+
+	shift, ok := kb.codes[shiftKeysym]
+	if !ok {
+		return nil, fmt.Errorf("в раскладке клавиатуры нет клавиши Shift")
+	}
+	kb.shiftKey = shift.code
+
+	return kb, nil
+}
+
+// lookup находит код клавиши для keysym. Если символа нет в раскладке,
+// он назначается на свободный код клавиши.
+func (kb *keyboard) lookup(conn *xgb.Conn, sym xproto.Keysym) (keyCode, error) {
+	if kc, ok := kb.codes[sym]; ok {
+		return kc, nil
+	}
+	if kb.spare == 0 {
+		return keyCode{}, fmt.Errorf("символ 0x%x отсутствует в раскладке и нет свободного кода клавиши", sym)
+	}
+
+	if kb.spareKeysym != sym {
+		syms := make([]xproto.Keysym, kb.perCode)
+		// Назначаем символ на оба уровня, чтобы состояние Shift не влияло на ввод
+		syms[0] = sym
+		if len(syms) > 1 {
+			syms[1] = sym
+		}
+		err := xproto.ChangeKeyboardMappingChecked(conn, 1, kb.spare, kb.perCode, syms).Check()
+		if err != nil {
+			return keyCode{}, fmt.Errorf("ошибка переназначения клавиши: %w", err)
+		}
+		// Дожидаемся, пока сервер разошлет MappingNotify клиентам
+		if _, err := xproto.GetInputFocus(conn).Reply(); err != nil {
+			return keyCode{}, fmt.Errorf("ошибка синхронизации с X-сервером: %w", err)
+		}
+		kb.spareKeysym = sym
+	}
+
+	return keyCode{code: kb.spare}, nil
+}
+
+// restore возвращает свободному коду клавиши пустое назначение
+func (kb *keyboard) restore(conn *xgb.Conn) {
+	if kb.spare == 0 || kb.spareKeysym == 0 {
+		return
+	}
+	syms := make([]xproto.Keysym, kb.perCode)
+	_ = xproto.ChangeKeyboardMappingChecked(conn, 1, kb.spare, kb.perCode, syms).Check()
+	kb.spareKeysym = 0
+}
+
+// keySymFor преобразует имя клавиши robotgo в keysym
+func keySymFor(key string) (xproto.Keysym, error) {
+	if sym, ok := keysyms[strings.ToLower(key)]; ok {
+		return sym, nil
+	}
+
+	lower := strings.ToLower(key)
+	if strings.HasPrefix(lower, "f") && len(lower) > 1 {
+		var n int
+		if _, err := fmt.Sscanf(lower, "f%d", &n); err == nil && n >= 1 && n <= 24 {
+			return xproto.Keysym(0xffbe + n - 1), nil
+		}
+	}
+
+	if utf8.RuneCountInString(key) == 1 {
+		r, _ := utf8.DecodeRuneInString(key)
+		return runeKeySym(r), nil
+	}
+
+	return 0, fmt.Errorf("неизвестная клавиша %q", key)
+}
+
+// runeKeySym преобразует символ в keysym
+func runeKeySym(r rune) xproto.Keysym {
+	switch r {
+	case '\n', '\r':
+		return keysyms["enter"]
+	case '\t':
+		return keysyms["tab"]
+	case '\b':
+		return keysyms["backspace"]
+	}
+	// Latin-1 keysym совпадают с кодами символов, остальные символы Unicode
+	// кодируются как 0x01000000 + код символа
+	if (r >= 0x20 && r <= 0x7e) || (r >= 0xa0 && r <= 0xff) {
+		return xproto.Keysym(r)
+	}
+	return xproto.Keysym(0x01000000 + r)
+}
+
+// KeyTap нажимает клавишу с модификаторами
+func (b *Backend) KeyTap(key string, modifiers ...string) error {
+	sym, err := keySymFor(key)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pressed := make([]xproto.Keycode, 0, len(modifiers))
+	defer func() {
+		// Модификаторы отпускаем в обратном порядке даже при ошибке
+		for i := len(pressed) - 1; i >= 0; i-- {
+			_ = b.fakeInput(xproto.KeyRelease, byte(pressed[i]), 0, 0)
+		}
+	}()
+
+	for _, modifier := range modifiers {
+		modSym, err := keySymFor(modifier)
+		if err != nil {
+			return err
+		}
+		kc, err := b.keyboard.lookup(b.conn, modSym)
+		if err != nil {
+			return err
+		}
+		if err := b.fakeInput(xproto.KeyPress, byte(kc.code), 0, 0); err != nil {
+			return err
+		}
+		pressed = append(pressed, kc.code)
+	}
+
+	return b.tapSym(sym)
+}
+
+// KeyToggle нажимает или отпускает клавишу
+func (b *Backend) KeyToggle(key string, down bool) error {
+	sym, err := keySymFor(key)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	kc, err := b.keyboard.lookup(b.conn, sym)
+	if err != nil {
+		return err
+	}
+	if down {
+		return b.fakeInput(xproto.KeyPress, byte(kc.code), 0, 0)
+	}
+	return b.fakeInput(xproto.KeyRelease, byte(kc.code), 0, 0)
+}
+
+// TypeRune вводит один символ
+func (b *Backend) TypeRune(r rune) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tapSym(runeKeySym(r))
+}
+
+// tapSym нажимает и отпускает клавишу с keysym, при необходимости удерживая Shift
+func (b *Backend) tapSym(sym xproto.Keysym) error {
+	kc, err := b.keyboard.lookup(b.conn, sym)
+	if err != nil {
+		return err
+	}
+
+	if kc.shift {
+		if err := b.fakeInput(xproto.KeyPress, byte(b.keyboard.shiftKey), 0, 0); err != nil {
+			return err
+		}
+		defer b.fakeInput(xproto.KeyRelease, byte(b.keyboard.shiftKey), 0, 0)
+	}
+
+	if err := b.fakeInput(xproto.KeyPress, byte(kc.code), 0, 0); err != nil {
+		return err
+	}
+	return b.fakeInput(xproto.KeyRelease, byte(kc.code), 0, 0)
+}
@@ -0,0 +1,144 @@
+package x11
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dialRaw открывает соединение с X-сервером и выполняет рукопожатие без xgb.
+// Используется для потока данных RECORD, который xgb не умеет читать.
+func dialRaw(display string) (net.Conn, error) {
+	if display == "" {
+		display = os.Getenv("DISPLAY")
+	}
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return nil, fmt.Errorf("неверный адрес X-сервера %q", display)
+	}
+	host, number := display[:colon], display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("неверный адрес X-сервера %q", display)
+	}
+
+	var conn net.Conn
+	switch {
+	case strings.HasPrefix(host, "/"):
+		// Сокет launchd на macOS: /private/tmp/com.apple.launchd.xxx/org.xquartz:0
+		conn, err = net.Dial("unix", display)
+	case host == "" || host == "unix":
+		host = ""
+		conn, err = net.Dial("unix", filepath.Join("/tmp/.X11-unix", "X"+number))
+	default:
+		protocol := "tcp"
+		if slash := strings.LastIndex(host, "/"); slash >= 0 {
+			protocol, host = host[:slash], host[slash+1:]
+		}
+		conn, err = net.Dial(protocol, net.JoinHostPort(host, strconv.Itoa(6000+n)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к X-серверу %q: %w", display, err)
+	}
+
+	if err := handshake(conn, host, number); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка подключения к X-серверу %q: %w", display, err)
+	}
+	return conn, nil
+}
+
+// handshake отправляет запрос на подключение (порядок байтов little-endian, протокол 11.0)
+// с cookie MIT-MAGIC-COOKIE-1 из Xauthority, если он есть, и пропускает описание сервера
+func handshake(conn net.Conn, host, number string) error {
+	authName, authData := readXauthority(host, number)
+
+	pad := func(n int) int { return (n + 3) &^ 3 }
+	buf := make([]byte, 12+pad(len(authName))+pad(len(authData)))
+	buf[0] = 'l'
+	binary.LittleEndian.PutUint16(buf[2:], 11)
+	binary.LittleEndian.PutUint16(buf[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(buf[8:], uint16(len(authData)))
+	copy(buf[12:], authName)
+	copy(buf[12+pad(len(authName)):], authData)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	rest := make([]byte, int(binary.LittleEndian.Uint16(head[6:]))*4)
+	if _, err := io.ReadFull(conn, rest); err != nil {
+		return err
+	}
+	if head[0] != 1 {
+		reason := rest
+		if int(head[1]) <= len(rest) {
+			reason = rest[:head[1]]
+		}
+		return fmt.Errorf("сервер отклонил подключение: %s", strings.TrimSpace(string(reason)))
+	}
+	return nil
+}
+
+// readXauthority находит cookie для дисплея в файле XAUTHORITY (или ~/.Xauthority).
+// Без подходящей записи подключение выполняется без авторизации.
+func readXauthority(host, number string) (string, []byte) {
+	const familyWild = 65535
+
+	if host == "" || host == "localhost" {
+		host, _ = os.Hostname()
+	}
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+
+	readField := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(f, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		field := make([]byte, n)
+		_, err := io.ReadFull(f, field)
+		return field, err
+	}
+
+	for {
+		var family uint16
+		if err := binary.Read(f, binary.BigEndian, &family); err != nil {
+			return "", nil
+		}
+		var fields [4][]byte
+		for i := range fields {
+			if fields[i], err = readField(); err != nil {
+				return "", nil
+			}
+		}
+		addr, disp, name, data := string(fields[0]), string(fields[1]), string(fields[2]), fields[3]
+
+		addrMatch := family == familyWild || addr == host
+		if addrMatch && (disp == "" || disp == number) && name == "MIT-MAGIC-COOKIE-1" {
+			return name, data
+		}
+	}
+}
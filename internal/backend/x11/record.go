@@ -0,0 +1,408 @@
+package x11
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+	"unicode"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/record"
+	"github.com/jezek/xgb/xproto"
+
+	"goszakup-automation/internal/recorder"
+)
+
+func init() {
+	recorder.RegisterSource(Name, func(display string) (recorder.Source, error) {
+		return NewInputSource(display)
+	})
+}
+
+// Категории данных в ответах EnableContext
+const (
+	categoryFromServer  = 0
+	categoryStartOfData = 4
+	categoryEndOfData   = 5
+)
+
+// Биты состояния модификаторов в событиях X11
+const (
+	stateShift   = 1 << 0
+	stateLock    = 1 << 1
+	stateNumLock = 1 << 4
+)
+
+// InputSource перехватывает физические события мыши и клавиатуры всех клиентов
+// X-сервера через расширение RECORD.
+//
+// RECORD передает события потоком ответов на один запрос EnableContext, а xgb поддерживает
+// только один ответ на запрос, поэтому контекст создается и выключается через xgb,
+// а поток данных читается из отдельного соединения напрямую.
+type InputSource struct {
+	control *xgb.Conn
+	data    net.Conn
+	context record.Context
+	opcode  byte
+	keymap  *keymap
+}
+
+// NewInputSource подключается к X-серверу и создает контекст записи событий устройств ввода.
+// Пустой display означает переменную окружения DISPLAY.
+func NewInputSource(display string) (*InputSource, error) {
+	control, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к X-серверу %q: %w", display, err)
+	}
+	if err := record.Init(control); err != nil {
+		control.Close()
+		return nil, fmt.Errorf("расширение RECORD недоступно: %w", err)
+	}
+	if _, err := record.QueryVersion(control, 1, 13).Reply(); err != nil {
+		control.Close()
+		return nil, fmt.Errorf("ошибка RECORD QueryVersion: %w", err)
+	}
+
+	setup := xproto.Setup(control)
+	keymap, err := loadKeymap(control, setup)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+
+	ctx, err := record.NewContextId(control)
+	if err != nil {
+		control.Close()
+		return nil, fmt.Errorf("ошибка создания контекста RECORD: %w", err)
+	}
+	ranges := []record.Range{{
+		DeviceEvents: record.Range8{First: xproto.KeyPress, Last: xproto.MotionNotify},
+	}}
+	err = record.CreateContextChecked(control, ctx, 0, 1, uint32(len(ranges)),
+		[]record.ClientSpec{record.CsAllClients}, ranges).Check()
+	if err != nil {
+		control.Close()
+		return nil, fmt.Errorf("ошибка создания контекста RECORD: %w", err)
+	}
+
+	data, err := dialRaw(display)
+	if err != nil {
+		_ = record.FreeContextChecked(control, ctx).Check()
+		control.Close()
+		return nil, err
+	}
+
+	control.ExtLock.RLock()
+	opcode := control.Extensions["RECORD"]
+	control.ExtLock.RUnlock()
+
+	return &InputSource{
+		control: control,
+		data:    data,
+		context: ctx,
+		opcode:  opcode,
+		keymap:  keymap,
+	}, nil
+}
+
+// Capture включает контекст записи и передает события в emit, пока не отменен ctx.
+// После возврата источник закрыт.
+func (s *InputSource) Capture(ctx context.Context, emit func(recorder.InputEvent)) error {
+	defer s.control.Close()
+	defer s.data.Close()
+
+	// EnableContext: код расширения, минорный код 5, длина 2 слова, контекст
+	request := make([]byte, 8)
+	request[0] = s.opcode
+	request[1] = 5
+	binary.LittleEndian.PutUint16(request[2:], 2)
+	binary.LittleEndian.PutUint32(request[4:], uint32(s.context))
+	if _, err := s.data.Write(request); err != nil {
+		return fmt.Errorf("ошибка RECORD EnableContext: %w", err)
+	}
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+		// После выключения контекста сервер отправляет EndOfData и поток завершается
+		_ = record.DisableContextChecked(s.control, s.context).Check()
+		_ = record.FreeContextChecked(s.control, s.context).Check()
+		_ = s.data.SetReadDeadline(time.Now().Add(time.Second))
+	}()
+
+	clock := &serverClock{}
+	header := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(s.data, header); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("ошибка чтения событий RECORD: %w", err)
+		}
+		switch header[0] {
+		case 0:
+			return fmt.Errorf("ошибка X-сервера при записи событий (код %d)", header[1])
+		case 1:
+		default:
+			// В соединении записи других событий нет
+			continue
+		}
+
+		body := make([]byte, int(binary.LittleEndian.Uint32(header[4:]))*4)
+		if _, err := io.ReadFull(s.data, body); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("ошибка чтения событий RECORD: %w", err)
+		}
+
+		switch header[1] {
+		case categoryEndOfData:
+			return nil
+		case categoryFromServer:
+			for i := 0; i+32 <= len(body); i += 32 {
+				if ev, ok := s.parseEvent(body[i:i+32], clock); ok {
+					emit(ev)
+				}
+			}
+		}
+	}
+}
+
+// parseEvent разбирает событие устройства в формате протокола X11
+func (s *InputSource) parseEvent(buf []byte, clock *serverClock) (recorder.InputEvent, bool) {
+	detail := buf[1]
+	ev := recorder.InputEvent{
+		At: clock.at(binary.LittleEndian.Uint32(buf[4:])),
+		X:  int(int16(binary.LittleEndian.Uint16(buf[20:]))),
+		Y:  int(int16(binary.LittleEndian.Uint16(buf[22:]))),
+	}
+	state := binary.LittleEndian.Uint16(buf[28:])
+
+	switch buf[0] & 0x7f {
+	case xproto.KeyPress, xproto.KeyRelease:
+		ev.Kind = recorder.EventKeyDown
+		if buf[0]&0x7f == xproto.KeyRelease {
+			ev.Kind = recorder.EventKeyUp
+		}
+		ev.Key, ev.Rune = s.keymap.translate(xproto.Keycode(detail), state)
+		return ev, ev.Key != "" || ev.Rune != 0
+
+	case xproto.ButtonPress:
+		// Колесико мыши — нажатия кнопок 4-7, отпускания не нужны
+		switch detail {
+		case 4:
+			ev.Kind, ev.DY = recorder.EventScroll, 1
+		case 5:
+			ev.Kind, ev.DY = recorder.EventScroll, -1
+		case 6:
+			ev.Kind, ev.DX = recorder.EventScroll, -1
+		case 7:
+			ev.Kind, ev.DX = recorder.EventScroll, 1
+		default:
+			ev.Kind = recorder.EventButtonDown
+		}
+		ev.Button = buttonName(detail)
+		return ev, ev.Kind == recorder.EventScroll || ev.Button != ""
+
+	case xproto.ButtonRelease:
+		ev.Kind = recorder.EventButtonUp
+		ev.Button = buttonName(detail)
+		return ev, ev.Button != ""
+
+	case xproto.MotionNotify:
+		ev.Kind = recorder.EventMotion
+		return ev, true
+	}
+	return ev, false
+}
+
+func buttonName(detail byte) string {
+	switch detail {
+	case 1:
+		return "left"
+	case 2:
+		return "center"
+	case 3:
+		return "right"
+	}
+	return ""
+}
+
+// serverClock переводит время сервера (миллисекунды, 32 бита) в локальное время,
+// чтобы паузы между событиями не зависели от задержек чтения
+type serverClock struct {
+	base   time.Time
+	origin uint32
+	set    bool
+}
+
+func (c *serverClock) at(serverTime uint32) time.Time {
+	if !c.set {
+		c.base, c.origin, c.set = time.Now(), serverTime, true
+	}
+	return c.base.Add(time.Duration(serverTime-c.origin) * time.Millisecond)
+}
+
+// keymap раскладка для перевода кодов клавиш в имена и символы
+type keymap struct {
+	minCode xproto.Keycode
+	perCode int
+	syms    []xproto.Keysym
+}
+
+func loadKeymap(conn *xgb.Conn, setup *xproto.SetupInfo) (*keymap, error) {
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+	reply, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения раскладки клавиатуры: %w", err)
+	}
+	return &keymap{
+		minCode: setup.MinKeycode,
+		perCode: int(reply.KeysymsPerKeycode),
+		syms:    reply.Keysyms,
+	}, nil
+}
+
+// translate возвращает имя клавиши (по первой раскладке без Shift, как в hotkey)
+// и символ, который она вводит с учетом Shift, CapsLock, NumLock и текущей раскладки
+func (km *keymap) translate(code xproto.Keycode, state uint16) (string, rune) {
+	i := int(code-km.minCode) * km.perCode
+	if code < km.minCode || i+km.perCode > len(km.syms) {
+		return "", 0
+	}
+	syms := km.syms[i : i+km.perCode]
+	sym := func(index int) xproto.Keysym {
+		if index < len(syms) {
+			return syms[index]
+		}
+		return 0
+	}
+
+	name := keysymName(sym(0))
+	if base := keysymRune(sym(0)); name == "" && base != 0 {
+		name = string(unicode.ToLower(base))
+	}
+
+	// Раскладка (группа XKB) передается в битах 13-14 состояния, в каждой группе два уровня
+	group := int(state>>13) & 3
+	lower, upper := sym(group*2), sym(group*2+1)
+	if lower == 0 && upper == 0 {
+		lower, upper = sym(0), sym(1)
+	}
+
+	shift := state&stateShift != 0
+	var r rune
+	switch {
+	case state&stateNumLock != 0 && isKeypad(upper):
+		// С NumLock цифровой блок вводит цифры, Shift временно отменяет это
+		if shift {
+			r = keysymRune(lower)
+		} else {
+			r = keysymRune(upper)
+		}
+	case shift && upper != 0:
+		r = keysymRune(upper)
+	default:
+		r = keysymRune(lower)
+		if shift {
+			r = unicode.ToUpper(r)
+		}
+	}
+	if state&stateLock != 0 && unicode.IsLetter(r) {
+		// CapsLock меняет регистр букв, а вместе с Shift возвращает строчные
+		if shift {
+			r = unicode.ToLower(r)
+		} else {
+			r = unicode.ToUpper(r)
+		}
+	}
+	return name, r
+}
+
+// keysymNames имена клавиш без символа, обратное соответствие keysyms
+var keysymNames = func() map[xproto.Keysym]string {
+	names := make(map[xproto.Keysym]string)
+	for _, name := range []string{
+		"enter", "tab", "space", "backspace", "delete", "escape", "up", "down", "left", "right",
+		"home", "end", "pageup", "pagedown", "insert", "capslock", "printscreen", "menu",
+		"ctrl", "shift", "alt", "cmd",
+	} {
+		names[keysyms[name]] = name
+	}
+	// Правые модификаторы, Meta, Shift+Tab и навигация цифрового блока
+	for sym, name := range map[xproto.Keysym]string{
+		0xffe4: "ctrl", 0xffe2: "shift", 0xffea: "alt", 0xffe7: "alt", 0xffe8: "alt", 0xffec: "cmd",
+		0xfe20: "tab", 0xff8d: "enter",
+		0xff95: "home", 0xff96: "left", 0xff97: "up", 0xff98: "right", 0xff99: "down",
+		0xff9a: "pageup", 0xff9b: "pagedown", 0xff9c: "end", 0xff9e: "insert", 0xff9f: "delete",
+	} {
+		names[sym] = name
+	}
+	return names
+}()
+
+// keysymName возвращает имя клавиши robotgo для клавиш без символа ("" для символьных)
+func keysymName(sym xproto.Keysym) string {
+	if name, ok := keysymNames[sym]; ok {
+		return name
+	}
+	if sym >= 0xffbe && sym <= 0xffd5 {
+		return fmt.Sprintf("f%d", sym-0xffbe+1)
+	}
+	return ""
+}
+
+// cyrillicKeysyms символы keysym 0x6c0-0x6df (строчные), заглавные идут с 0x6e0 в том же порядке
+var cyrillicKeysyms = []rune("юабцдефгхийклмнопярстужвьызшэщчъ")
+
+// keysymRune возвращает символ, который вводит keysym (0 — клавиша без символа)
+func keysymRune(sym xproto.Keysym) rune {
+	switch {
+	case sym >= 0x20 && sym <= 0x7e, sym >= 0xa0 && sym <= 0xff:
+		return rune(sym)
+	case sym >= 0x01000100 && sym <= 0x0110ffff:
+		// Символы Unicode (например, казахские буквы)
+		return rune(sym - 0x01000000)
+	case sym >= 0x6c0 && sym <= 0x6df:
+		return cyrillicKeysyms[sym-0x6c0]
+	case sym >= 0x6e0 && sym <= 0x6ff:
+		return unicode.ToUpper(cyrillicKeysyms[sym-0x6e0])
+	case sym == 0x6a3:
+		return 'ё'
+	case sym == 0x6b3:
+		return 'Ё'
+	case sym == 0x6b0:
+		return '№'
+	case sym >= 0xffb0 && sym <= 0xffb9:
+		return rune('0' + sym - 0xffb0)
+	}
+	switch sym {
+	case 0xff80:
+		return ' '
+	case 0xffaa:
+		return '*'
+	case 0xffab:
+		return '+'
+	case 0xffad:
+		return '-'
+	case 0xffae:
+		return '.'
+	case 0xffaf:
+		return '/'
+	}
+	return 0
+}
+
+// isKeypad сообщает, что keysym — символ цифрового блока
+func isKeypad(sym xproto.Keysym) bool {
+	return sym >= 0xffaa && sym <= 0xffb9
+}
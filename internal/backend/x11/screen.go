@@ -0,0 +1,53 @@
+package x11
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// CaptureScreen снимает скриншот области экрана через GetImage (аналог XGetImage).
+// Пустой прямоугольник означает весь экран.
+func (b *Backend) CaptureScreen(rect image.Rectangle) (image.Image, error) {
+	screenRect := image.Rect(0, 0, int(b.screen.WidthInPixels), int(b.screen.HeightInPixels))
+	if rect.Empty() {
+		rect = screenRect
+	}
+	rect = rect.Intersect(screenRect)
+	if rect.Empty() {
+		return nil, fmt.Errorf("область захвата вне экрана")
+	}
+
+	reply, err := xproto.GetImage(b.conn, xproto.ImageFormatZPixmap, xproto.Drawable(b.screen.Root),
+		int16(rect.Min.X), int16(rect.Min.Y), uint16(rect.Dx()), uint16(rect.Dy()), 0xffffffff).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка GetImage: %w", err)
+	}
+
+	if reply.Depth != 24 && reply.Depth != 32 {
+		return nil, fmt.Errorf("неподдерживаемая глубина цвета экрана: %d", reply.Depth)
+	}
+
+	w, h := rect.Dx(), rect.Dy()
+	if len(reply.Data) < w*h*4 {
+		return nil, fmt.Errorf("неожиданный размер данных изображения: %d байт", len(reply.Data))
+	}
+
+	// Для глубины 24/32 бит каждый пиксель занимает 4 байта: BGRX при порядке LSBFirst
+	// и XRGB при MSBFirst
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	msb := b.setup.ImageByteOrder == xproto.ImageOrderMSBFirst
+	for i := 0; i < w*h; i++ {
+		src := reply.Data[i*4 : i*4+4]
+		dst := img.Pix[i*4 : i*4+4]
+		if msb {
+			dst[0], dst[1], dst[2] = src[1], src[2], src[3]
+		} else {
+			dst[0], dst[1], dst[2] = src[2], src[1], src[0]
+		}
+		dst[3] = 0xff
+	}
+
+	return img, nil
+}
@@ -0,0 +1,241 @@
+// Package x11 реализует input.Backend напрямую через протокол X11 (расширение XTEST)
+// без cgo. Работает с любым X-сервером, в том числе с Xvfb.
+package x11
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xinerama"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+
+	"goszakup-automation/internal/backend"
+	"goszakup-automation/internal/input"
+)
+
+// Name имя бэкенда в конфигурации
+const Name = "x11"
+
+func init() {
+	backend.Register(Name, func(opts backend.Options) (input.Backend, error) {
+		return New(opts.Display)
+	})
+}
+
+// Backend бэкенд ввода, работающий с X-сервером через XTEST
+type Backend struct {
+	conn   *xgb.Conn
+	setup  *xproto.SetupInfo
+	screen *xproto.ScreenInfo
+	// window невидимое окно для работы с буфером обмена
+	window xproto.Window
+	// xinerama расширение Xinerama доступно (границы мониторов)
+	xinerama bool
+
+	mu       sync.Mutex
+	keyboard *keyboard
+	clip     *clipboard
+}
+
+// New подключается к X-серверу. Пустой display означает переменную окружения DISPLAY.
+func New(display string) (*Backend, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к X-серверу %q: %w", display, err)
+	}
+
+	if err := xtest.Init(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("расширение XTEST недоступно: %w", err)
+	}
+
+	setup := xproto.Setup(conn)
+	b := &Backend{
+		conn:   conn,
+		setup:  setup,
+		screen: setup.DefaultScreen(conn),
+		// Без Xinerama весь экран считается одним монитором
+		xinerama: xinerama.Init(conn) == nil,
+	}
+
+	if b.keyboard, err = loadKeyboard(conn, setup); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := b.initClipboard(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go b.eventLoop()
+
+	return b, nil
+}
+
+// Close восстанавливает раскладку и закрывает соединение с X-сервером
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.keyboard.restore(b.conn)
+	b.conn.Close()
+	return nil
+}
+
+// Name возвращает имя бэкенда
+func (b *Backend) Name() string {
+	return Name
+}
+
+// MoveMouse перемещает курсор
+func (b *Backend) MoveMouse(x, y int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// detail=0 означает абсолютные координаты
+	return b.fakeInput(xproto.MotionNotify, 0, int16(x), int16(y))
+}
+
+// Click выполняет клик
+func (b *Backend) Click(button string, double bool) error {
+	detail, err := buttonDetail(button)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clicks := 1
+	if double {
+		clicks = 2
+	}
+	for i := 0; i < clicks; i++ {
+		if err := b.fakeInput(xproto.ButtonPress, detail, 0, 0); err != nil {
+			return err
+		}
+		if err := b.fakeInput(xproto.ButtonRelease, detail, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MouseToggle нажимает или отпускает кнопку мыши
+func (b *Backend) MouseToggle(button string, down bool) error {
+	detail, err := buttonDetail(button)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if down {
+		return b.fakeInput(xproto.ButtonPress, detail, 0, 0)
+	}
+	return b.fakeInput(xproto.ButtonRelease, detail, 0, 0)
+}
+
+// Scroll прокручивает колесико мыши. В X11 прокрутка — это нажатия кнопок 4-7.
+func (b *Backend) Scroll(x, y int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Положительный y — вверх (кнопка 4), отрицательный — вниз (кнопка 5),
+	// положительный x — вправо (кнопка 7), отрицательный — влево (кнопка 6)
+	var yButton, xButton byte = 4, 7
+	if y < 0 {
+		yButton, y = 5, -y
+	}
+	if x < 0 {
+		xButton, x = 6, -x
+	}
+
+	for i := 0; i < y; i++ {
+		if err := b.pressButton(yButton); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < x; i++ {
+		if err := b.pressButton(xButton); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MousePosition возвращает позицию курсора
+func (b *Backend) MousePosition() (int, int, error) {
+	reply, err := xproto.QueryPointer(b.conn, b.screen.Root).Reply()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка QueryPointer: %w", err)
+	}
+	return int(reply.RootX), int(reply.RootY), nil
+}
+
+// ScreenSize возвращает размер экрана по умолчанию
+func (b *Backend) ScreenSize() (int, int, error) {
+	return int(b.screen.WidthInPixels), int(b.screen.HeightInPixels), nil
+}
+
+func (b *Backend) pressButton(detail byte) error {
+	if err := b.fakeInput(xproto.ButtonPress, detail, 0, 0); err != nil {
+		return err
+	}
+	return b.fakeInput(xproto.ButtonRelease, detail, 0, 0)
+}
+
+// fakeInput отправляет синтетическое событие через XTEST и дожидается его обработки
+func (b *Backend) fakeInput(eventType, detail byte, x, y int16) error {
+	err := xtest.FakeInputChecked(b.conn, eventType, detail, 0, b.screen.Root, x, y, 0).Check()
+	if err != nil {
+		return fmt.Errorf("ошибка XTEST FakeInput: %w", err)
+	}
+	return nil
+}
+
+// eventLoop обрабатывает события X-сервера (запросы к буферу обмена)
+func (b *Backend) eventLoop() {
+	for {
+		ev, err := b.conn.WaitForEvent()
+		if ev == nil && err == nil {
+			// Соединение закрыто
+			return
+		}
+		if err != nil {
+			continue
+		}
+
+		switch e := ev.(type) {
+		case xproto.SelectionRequestEvent:
+			b.handleSelectionRequest(e)
+		case xproto.SelectionNotifyEvent:
+			b.clip.notify(e)
+		case xproto.SelectionClearEvent:
+			b.clip.clear(e)
+		}
+	}
+}
+
+func buttonDetail(button string) (byte, error) {
+	switch button {
+	case "", "left":
+		return 1, nil
+	case "center", "middle":
+		return 2, nil
+	case "right":
+		return 3, nil
+	case "wheelUp":
+		return 4, nil
+	case "wheelDown":
+		return 5, nil
+	case "wheelLeft":
+		return 6, nil
+	case "wheelRight":
+		return 7, nil
+	}
+	return 0, fmt.Errorf("неизвестная кнопка мыши %q", button)
+}
@@ -0,0 +1,510 @@
+// Package batch выполняет сценарий для каждой строки таблицы CSV или XLSX: колонки
+// становятся параметрами сценария, итог каждой строки записывается в колонки status и error.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/scenario"
+)
+
+var (
+	// ErrNotFound возвращается, если пакетного запуска с указанным ID нет
+	ErrNotFound = errors.New("пакетный запуск не найден")
+	// ErrRowsFailed возвращается, если хотя бы одна строка выполнилась с ошибкой
+	ErrRowsFailed = errors.New("строки выполнены с ошибками")
+)
+
+// Колонки результата. Если они уже есть в таблице (повторный запуск по файлу результата),
+// значения перезаписываются.
+const (
+	StatusColumn = "status"
+	ErrorColumn  = "error"
+)
+
+// RowStatus итог строки
+type RowStatus string
+
+const (
+	RowPending RowStatus = "pending"
+	RowRunning RowStatus = "running"
+	RowOK      RowStatus = "ok"
+	RowFailed  RowStatus = "failed"
+	// RowSkipped строка не выполнялась: запуск остановлен после ошибки или отменен
+	RowSkipped RowStatus = "skipped"
+)
+
+// State состояние пакетного запуска
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateFinished  State = "finished"
+	StateCancelled State = "cancelled"
+)
+
+// batchRetention время хранения завершенных запусков
+const batchRetention = time.Hour
+
+// Options настройки пакетного запуска
+type Options struct {
+	// Mapping соответствие параметр сценария -> колонка таблицы. Параметры, которых нет
+	// в Mapping, берутся из колонок с тем же именем (без учета регистра).
+	Mapping map[string]string `json:"mapping,omitempty"`
+	// Params общие значения параметров для всех строк; непустые ячейки их переопределяют
+	Params map[string]any `json:"params,omitempty"`
+	// Resume продолжить с первой строки, у которой в колонке status не ok
+	// (таблица — файл результата предыдущего запуска)
+	Resume bool `json:"resume"`
+	// StopOnError остановить запуск после первой строки с ошибкой
+	StopOnError bool `json:"stop_on_error"`
+}
+
+// RowFunc выполняет сценарий для одной строки. line — номер строки в файле.
+type RowFunc func(ctx context.Context, line int, params map[string]any) error
+
+// RowResult итог выполнения строки
+type RowResult struct {
+	// Line номер строки в файле (заголовок — строка 1)
+	Line   int       `json:"line"`
+	Status RowStatus `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	// Params параметры, переданные сценарию
+	Params     map[string]any `json:"params,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	// Previous строка выполнена успешно в предыдущем запуске (resume) и не повторяется
+	Previous bool `json:"previous,omitempty"`
+}
+
+// Summary число строк по итогам
+type Summary struct {
+	Total   int `json:"total"`
+	OK      int `json:"ok"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	Pending int `json:"pending"`
+}
+
+// Batch пакетный запуск сценария по таблице
+type Batch struct {
+	mu       sync.Mutex
+	id       string
+	scenario *scenario.Scenario
+	version  int
+	file     string
+	options  Options
+	table    *Table
+	// columns колонка таблицы для каждого параметра сценария
+	columns map[string]int
+	// start первая выполняемая строка (индекс в table.Rows). При resume после нее
+	// могут быть и другие строки, выполненные ранее (RowResult.Previous).
+	start      int
+	state      State
+	rows       []RowResult
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	err        error
+}
+
+// Info состояние пакетного запуска для API
+type Info struct {
+	ID       string `json:"id"`
+	Scenario string `json:"scenario"`
+	Version  int    `json:"version"`
+	File     string `json:"file"`
+	Sheet    string `json:"sheet,omitempty"`
+	State    State  `json:"state"`
+	// Mapping итоговое соответствие параметров и колонок
+	Mapping map[string]string `json:"mapping"`
+	// UnusedColumns колонки таблицы, не связанные с параметрами
+	UnusedColumns []string `json:"unused_columns,omitempty"`
+	// StartLine строка файла, с которой начинается (или продолжается) выполнение
+	StartLine  int         `json:"start_line"`
+	Summary    Summary     `json:"summary"`
+	Rows       []RowResult `json:"rows,omitempty"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Manager хранит пакетные запуски
+type Manager struct {
+	mu      sync.Mutex
+	seq     int
+	batches map[string]*Batch
+	now     func() time.Time
+}
+
+// NewManager создает хранилище пакетных запусков
+func NewManager() *Manager {
+	return &Manager{
+		batches: make(map[string]*Batch),
+		now:     time.Now,
+	}
+}
+
+// Create проверяет соответствие колонок параметрам сценария и регистрирует запуск.
+// file — имя файла таблицы, по нему выбирается формат файла результата.
+func (m *Manager) Create(sc *scenario.Scenario, version int, file string, table *Table, opts Options) (*Batch, error) {
+	if len(table.Rows) == 0 {
+		return nil, errors.New("в таблице нет строк данных")
+	}
+
+	declared := make(map[string]bool, len(sc.Params))
+	for _, p := range sc.Params {
+		declared[p.Name] = true
+	}
+	for name := range opts.Params {
+		if !declared[name] {
+			return nil, fmt.Errorf("неизвестный параметр %q", name)
+		}
+	}
+
+	columns := make(map[string]int)
+	for name, column := range opts.Mapping {
+		if !declared[name] {
+			return nil, fmt.Errorf("mapping: неизвестный параметр %q", name)
+		}
+		col := table.Column(column)
+		if col < 0 {
+			return nil, fmt.Errorf("mapping: в таблице нет колонки %q для параметра %q", column, name)
+		}
+		columns[name] = col
+	}
+	for _, p := range sc.Params {
+		if _, ok := columns[p.Name]; ok {
+			continue
+		}
+		if col := table.Column(p.Name); col >= 0 {
+			columns[p.Name] = col
+		}
+	}
+	if len(columns) == 0 && len(sc.Params) > 0 {
+		return nil, errors.New("ни одна колонка таблицы не связана с параметрами сценария, укажите mapping")
+	}
+	for _, p := range sc.Params {
+		if _, ok := columns[p.Name]; !ok && p.Required && opts.Params[p.Name] == nil {
+			return nil, fmt.Errorf("для обязательного параметра %q нет колонки, укажите mapping", p.Name)
+		}
+	}
+
+	table = table.clone()
+	statusCol := table.Column(StatusColumn)
+	if opts.Resume && statusCol < 0 {
+		return nil, fmt.Errorf("resume: в таблице нет колонки %q, ожидается файл результата", StatusColumn)
+	}
+
+	// При resume повторяются только строки, у которых status не ok: успешные строки
+	// пропускаются, даже если ниже или выше них есть строки с ошибками
+	rows := make([]RowResult, len(table.Rows))
+	start := -1
+	for i := range rows {
+		rows[i] = RowResult{Line: table.Lines[i], Status: RowPending}
+		if opts.Resume && RowStatus(strings.TrimSpace(table.Cell(i, statusCol))) == RowOK {
+			rows[i].Status, rows[i].Previous = RowOK, true
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, errors.New("resume: все строки уже выполнены успешно")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cleanupLocked()
+
+	m.seq++
+	b := &Batch{
+		id:        "batch-" + strconv.Itoa(m.seq),
+		scenario:  sc,
+		version:   version,
+		file:      file,
+		options:   opts,
+		table:     table,
+		columns:   columns,
+		start:     start,
+		state:     StateQueued,
+		rows:      rows,
+		createdAt: m.now(),
+	}
+	m.batches[b.id] = b
+	return b, nil
+}
+
+// Get возвращает пакетный запуск по ID
+func (m *Manager) Get(id string) (*Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.batches[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return b, nil
+}
+
+// List возвращает пакетные запуски без итогов строк, от ранних к поздним
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	m.cleanupLocked()
+	batches := make([]*Batch, 0, len(m.batches))
+	for _, b := range m.batches {
+		batches = append(batches, b)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].createdAt.Before(batches[j].createdAt)
+	})
+	infos := make([]Info, 0, len(batches))
+	for _, b := range batches {
+		info := b.Info()
+		info.Rows = nil
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// cleanupLocked удаляет запуски, созданные больше batchRetention назад и не выполняющиеся.
+// Запуск, задание которого отменили в очереди, так и остается queued и тоже удаляется.
+func (m *Manager) cleanupLocked() {
+	now := m.now()
+	for id, b := range m.batches {
+		b.mu.Lock()
+		expired := b.state != StateRunning && now.Sub(b.createdAt) > batchRetention
+		b.mu.Unlock()
+		if expired {
+			delete(m.batches, id)
+		}
+	}
+}
+
+// ID возвращает идентификатор запуска
+func (b *Batch) ID() string {
+	return b.id
+}
+
+// Run выполняет по очереди строки, не выполненные в предыдущем запуске. Ошибка строки не
+// прерывает запуск (если не задан StopOnError); отмена ctx оставляет оставшиеся строки
+// со статусом skipped. Возвращает ErrRowsFailed, если были строки с ошибками.
+func (b *Batch) Run(ctx context.Context, run RowFunc) error {
+	b.mu.Lock()
+	b.state = StateRunning
+	b.startedAt = time.Now()
+	b.mu.Unlock()
+
+	failed, total := 0, 0
+	var stopErr error
+	for i := b.start; i < len(b.rows); i++ {
+		if b.rows[i].Previous {
+			continue
+		}
+		total++
+		if stopErr == nil {
+			stopErr = ctx.Err()
+		}
+		if stopErr != nil {
+			b.setRow(i, RowResult{Status: RowSkipped, Error: stopErr.Error()})
+			continue
+		}
+
+		params, err := b.params(i)
+		if err != nil {
+			failed++
+			b.setRow(i, RowResult{Status: RowFailed, Error: err.Error()})
+		} else {
+			b.setRow(i, RowResult{Status: RowRunning, Params: params})
+			started := time.Now()
+			err = run(ctx, b.rows[i].Line, params)
+			result := RowResult{Status: RowOK, Params: params, DurationMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				failed++
+				result.Status, result.Error = RowFailed, err.Error()
+			}
+			b.setRow(i, result)
+		}
+
+		if err != nil && b.options.StopOnError {
+			stopErr = fmt.Errorf("запуск остановлен после ошибки в строке %d", b.rows[i].Line)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finishedAt = time.Now()
+	b.state = StateFinished
+	switch {
+	case ctx.Err() != nil:
+		b.state = StateCancelled
+		b.err = ctx.Err()
+	case failed > 0:
+		b.err = fmt.Errorf("%w: %d из %d", ErrRowsFailed, failed, total)
+	}
+	return b.err
+}
+
+// setRow записывает итог строки, сохраняя ее номер
+func (b *Batch) setRow(i int, result RowResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result.Line = b.rows[i].Line
+	b.rows[i] = result
+}
+
+// params собирает параметры сценария из ячеек строки i
+func (b *Batch) params(i int) (map[string]any, error) {
+	params := make(map[string]any, len(b.options.Params)+len(b.columns))
+	for name, value := range b.options.Params {
+		params[name] = value
+	}
+	for _, p := range b.scenario.Params {
+		col, ok := b.columns[p.Name]
+		if !ok {
+			continue
+		}
+		cell := strings.TrimSpace(b.table.Cell(i, col))
+		if cell == "" {
+			// Пустая ячейка — параметр не передан: действует общее значение или значение по умолчанию
+			continue
+		}
+		value, err := parseCell(p, cell)
+		if err != nil {
+			return nil, fmt.Errorf("колонка %q: %w", b.table.Header[col], err)
+		}
+		params[p.Name] = value
+	}
+	return params, nil
+}
+
+// parseCell переводит текст ячейки в значение типа параметра
+func parseCell(p scenario.Param, cell string) (any, error) {
+	switch p.Type {
+	case scenario.ParamNumber:
+		// Допускаем запись чисел в русской локали: "1 234,56"
+		number := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(cell)
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return nil, fmt.Errorf("ожидается число, получено %q", cell)
+		}
+		return json.Number(number), nil
+	case scenario.ParamBool:
+		switch strings.ToLower(cell) {
+		case "1", "true", "yes", "да", "+":
+			return true, nil
+		case "0", "false", "no", "нет", "-":
+			return false, nil
+		}
+		return nil, fmt.Errorf("ожидается да/нет или true/false, получено %q", cell)
+	case scenario.ParamList:
+		if strings.HasPrefix(cell, "[") {
+			var list []any
+			if err := json.Unmarshal([]byte(cell), &list); err != nil {
+				return nil, fmt.Errorf("ошибка разбора списка JSON: %w", err)
+			}
+			return list, nil
+		}
+		var list []any
+		for _, item := range strings.Split(cell, ";") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	default:
+		return cell, nil
+	}
+}
+
+// Info возвращает снимок состояния запуска
+func (b *Batch) Info() Info {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info := Info{
+		ID:       b.id,
+		Scenario: b.scenario.Name,
+		Version:  b.version,
+		File:     b.file,
+		Sheet:    b.table.Sheet,
+		State:    b.state,
+		Mapping:  make(map[string]string, len(b.columns)),
+		Rows:     append([]RowResult(nil), b.rows...),
+	}
+	used := make(map[int]bool)
+	for name, col := range b.columns {
+		info.Mapping[name] = b.table.Header[col]
+		used[col] = true
+	}
+	for col, header := range b.table.Header {
+		if !used[col] && header != "" && header != StatusColumn && header != ErrorColumn {
+			info.UnusedColumns = append(info.UnusedColumns, header)
+		}
+	}
+	if b.start < len(b.rows) {
+		info.StartLine = b.rows[b.start].Line
+	}
+
+	info.Summary.Total = len(b.rows)
+	for _, row := range b.rows {
+		switch row.Status {
+		case RowOK:
+			info.Summary.OK++
+		case RowFailed:
+			info.Summary.Failed++
+		case RowSkipped:
+			info.Summary.Skipped++
+		default:
+			info.Summary.Pending++
+		}
+	}
+
+	if !b.startedAt.IsZero() {
+		started := b.startedAt
+		info.StartedAt = &started
+	}
+	if !b.finishedAt.IsZero() {
+		finished := b.finishedAt
+		info.FinishedAt = &finished
+	}
+	if b.err != nil {
+		info.Error = b.err.Error()
+	}
+	return info
+}
+
+// Result возвращает таблицу с итогами в колонках status и error. Строки, до которых
+// запуск не дошел, остаются со статусом pending; строки, выполненные в предыдущем запуске
+// (resume), не меняются.
+func (b *Batch) Result() *Table {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	table := b.table.clone()
+	statusCol, errorCol := table.Column(StatusColumn), table.Column(ErrorColumn)
+	if statusCol < 0 {
+		table.Header = append(table.Header, StatusColumn)
+		statusCol = len(table.Header) - 1
+	}
+	if errorCol < 0 {
+		table.Header = append(table.Header, ErrorColumn)
+		errorCol = len(table.Header) - 1
+	}
+	for i, row := range b.rows {
+		if row.Previous {
+			continue
+		}
+		table.setCell(i, statusCol, string(row.Status))
+		table.setCell(i, errorCol, row.Error)
+	}
+	return table
+}
@@ -0,0 +1,371 @@
+package batch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"goszakup-automation/internal/batch"
+	"goszakup-automation/internal/scenario"
+)
+
+// testScenario сценарий с параметрами всех типов
+var testScenario = &scenario.Scenario{
+	Name: "fill-lot",
+	Params: []scenario.Param{
+		{Name: "bin", Required: true},
+		{Name: "amount", Type: scenario.ParamNumber},
+		{Name: "urgent", Type: scenario.ParamBool},
+		{Name: "items", Type: scenario.ParamList},
+	},
+}
+
+func readCSV(t *testing.T, data string) *batch.Table {
+	t.Helper()
+	table, err := batch.ReadTable("lots.csv", []byte(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+// call выполненная строка: номер и параметры
+type call struct {
+	line   int
+	params map[string]any
+}
+
+// recordRows возвращает RowFunc, запоминающую вызовы; строки из failLines завершаются ошибкой
+func recordRows(calls *[]call, failLines ...int) batch.RowFunc {
+	return func(ctx context.Context, line int, params map[string]any) error {
+		*calls = append(*calls, call{line: line, params: params})
+		for _, l := range failLines {
+			if l == line {
+				return fmt.Errorf("ошибка в строке %d", line)
+			}
+		}
+		return nil
+	}
+}
+
+func lines(calls []call) []int {
+	result := make([]int, len(calls))
+	for i, c := range calls {
+		result[i] = c.line
+	}
+	return result
+}
+
+func statuses(info batch.Info) []string {
+	result := make([]string, len(info.Rows))
+	for i, row := range info.Rows {
+		result[i] = fmt.Sprintf("%d:%s", row.Line, row.Status)
+	}
+	return result
+}
+
+func TestCreateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		opts  batch.Options
+		want  string
+	}{
+		{
+			name:  "нет строк",
+			table: "bin\n",
+			want:  "в таблице нет строк данных",
+		},
+		{
+			name:  "неизвестный общий параметр",
+			table: "bin\n1\n",
+			opts:  batch.Options{Params: map[string]any{"price": 1}},
+			want:  `неизвестный параметр "price"`,
+		},
+		{
+			name:  "неизвестный параметр в mapping",
+			table: "bin\n1\n",
+			opts:  batch.Options{Mapping: map[string]string{"price": "bin"}},
+			want:  `mapping: неизвестный параметр "price"`,
+		},
+		{
+			name:  "нет колонки из mapping",
+			table: "bin\n1\n",
+			opts:  batch.Options{Mapping: map[string]string{"amount": "Сумма"}},
+			want:  `mapping: в таблице нет колонки "Сумма" для параметра "amount"`,
+		},
+		{
+			name:  "нет связанных колонок",
+			table: "БИН\n1\n",
+			want:  "ни одна колонка таблицы не связана с параметрами сценария, укажите mapping",
+		},
+		{
+			name:  "нет колонки обязательного параметра",
+			table: "amount\n1\n",
+			want:  `для обязательного параметра "bin" нет колонки, укажите mapping`,
+		},
+		{
+			name:  "resume без колонки status",
+			table: "bin\n1\n",
+			opts:  batch.Options{Resume: true},
+			want:  `resume: в таблице нет колонки "status", ожидается файл результата`,
+		},
+		{
+			name:  "resume без невыполненных строк",
+			table: "bin,status\n1,ok\n2,ok\n",
+			opts:  batch.Options{Resume: true},
+			want:  "resume: все строки уже выполнены успешно",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := batch.NewManager().Create(testScenario, 1, "lots.csv", readCSV(t, tt.table), tt.opts)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("ошибка %v, ожидалась %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMapping(t *testing.T) {
+	table := readCSV(t, "БИН,Amount,Комментарий\n1,2,x\n")
+	b, err := batch.NewManager().Create(testScenario, 1, "lots.csv", table, batch.Options{
+		Mapping: map[string]string{"bin": "БИН"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := b.Info()
+	// Колонка amount связана по имени без учета регистра
+	wantMapping := map[string]string{"bin": "БИН", "amount": "Amount"}
+	if !reflect.DeepEqual(info.Mapping, wantMapping) {
+		t.Errorf("mapping %v, ожидалось %v", info.Mapping, wantMapping)
+	}
+	if !reflect.DeepEqual(info.UnusedColumns, []string{"Комментарий"}) {
+		t.Errorf("неиспользуемые колонки %v", info.UnusedColumns)
+	}
+	if info.State != batch.StateQueued || info.StartLine != 2 || info.Summary.Pending != 1 {
+		t.Errorf("запуск %+v", info)
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		param string
+		cell  string
+		want  any
+		// err ожидаемый текст ошибки строки
+		err string
+	}{
+		{param: "bin", cell: "010140000123", want: "010140000123"},
+		{param: "amount", cell: "1 234,56", want: json.Number("1234.56")},
+		{param: "amount", cell: "1 000", want: json.Number("1000")},
+		{param: "amount", cell: "abc", err: `колонка "amount": ожидается число, получено "abc"`},
+		{param: "urgent", cell: "да", want: true},
+		{param: "urgent", cell: "FALSE", want: false},
+		{param: "urgent", cell: "-", want: false},
+		{param: "urgent", cell: "может быть", err: `колонка "urgent": ожидается да/нет или true/false, получено "может быть"`},
+		{param: "items", cell: "a; b ;;c", want: []any{"a", "b", "c"}},
+		{param: "items", cell: `["a", 1]`, want: []any{"a", float64(1)}},
+		{param: "items", cell: `[1,`, err: `колонка "items": ошибка разбора списка JSON: unexpected end of JSON input`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.param+"/"+tt.cell, func(t *testing.T) {
+			header := "bin"
+			row := `"1"`
+			if tt.param != "bin" {
+				header += "," + tt.param
+				row += `,"` + strings.ReplaceAll(tt.cell, `"`, `""`) + `"`
+			} else {
+				row = tt.cell
+			}
+			b, err := batch.NewManager().Create(testScenario, 1, "lots.csv", readCSV(t, header+"\n"+row+"\n"), batch.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var calls []call
+			runErr := b.Run(context.Background(), recordRows(&calls))
+			if tt.err != "" {
+				if !errors.Is(runErr, batch.ErrRowsFailed) || len(calls) != 0 {
+					t.Fatalf("ошибка запуска %v, вызовы %v", runErr, calls)
+				}
+				if got := b.Info().Rows[0].Error; got != tt.err {
+					t.Errorf("ошибка строки %q, ожидалась %q", got, tt.err)
+				}
+				return
+			}
+			if runErr != nil {
+				t.Fatal(runErr)
+			}
+			if got := calls[0].params[tt.param]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("значение %#v, ожидалось %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	table := readCSV(t, "bin,amount\n1,10\n2,\n3,30\n,\n4,abc\n")
+	b, err := batch.NewManager().Create(testScenario, 1, "lots.csv", table, batch.Options{
+		Params: map[string]any{"amount": 5, "urgent": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []call
+	err = b.Run(context.Background(), recordRows(&calls, 4))
+	if !errors.Is(err, batch.ErrRowsFailed) || err.Error() != "строки выполнены с ошибками: 2 из 4" {
+		t.Fatalf("ошибка %v", err)
+	}
+
+	// Пустая строка файла пропускается, строка с ошибкой значения не выполняется
+	if got := lines(calls); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("выполнены строки %v", got)
+	}
+	// Пустая ячейка — действует общее значение
+	if calls[1].params["amount"] != 5 || calls[1].params["urgent"] != true || calls[0].params["amount"] != json.Number("10") {
+		t.Errorf("параметры %v", calls)
+	}
+
+	info := b.Info()
+	want := []string{"2:ok", "3:ok", "4:failed", "6:failed"}
+	if got := statuses(info); !reflect.DeepEqual(got, want) {
+		t.Errorf("статусы %v, ожидались %v", got, want)
+	}
+	if info.State != batch.StateFinished || info.Summary != (batch.Summary{Total: 4, OK: 2, Failed: 2}) {
+		t.Errorf("запуск %+v", info)
+	}
+
+	result, err := b.Result().Marshal("lots-result.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "bin,amount,status,error\n" +
+		"1,10,ok,\n" +
+		"2,,ok,\n" +
+		"3,30,failed,ошибка в строке 4\n" +
+		"4,abc,failed,\"колонка \"\"amount\"\": ожидается число, получено \"\"abc\"\"\"\n"
+	if string(result) != wantCSV {
+		t.Errorf("результат:\n%s\nожидался:\n%s", result, wantCSV)
+	}
+}
+
+func TestRunStopOnError(t *testing.T) {
+	b, err := batch.NewManager().Create(testScenario, 1, "lots.csv", readCSV(t, "bin\n1\n2\n3\n"), batch.Options{StopOnError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []call
+	if err := b.Run(context.Background(), recordRows(&calls, 3)); !errors.Is(err, batch.ErrRowsFailed) {
+		t.Fatalf("ошибка %v", err)
+	}
+	if got := lines(calls); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("выполнены строки %v", got)
+	}
+	info := b.Info()
+	if got := statuses(info); !reflect.DeepEqual(got, []string{"2:ok", "3:failed", "4:skipped"}) {
+		t.Errorf("статусы %v", got)
+	}
+	if info.Rows[2].Error != "запуск остановлен после ошибки в строке 3" {
+		t.Errorf("ошибка пропущенной строки %q", info.Rows[2].Error)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	b, err := batch.NewManager().Create(testScenario, 1, "lots.csv", readCSV(t, "bin\n1\n2\n3\n"), batch.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls []call
+	err = b.Run(ctx, func(ctx context.Context, line int, params map[string]any) error {
+		calls = append(calls, call{line: line})
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ошибка %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("выполнены строки %v", lines(calls))
+	}
+	info := b.Info()
+	if got := statuses(info); !reflect.DeepEqual(got, []string{"2:failed", "3:skipped", "4:skipped"}) {
+		t.Errorf("статусы %v", got)
+	}
+	if info.State != batch.StateCancelled {
+		t.Errorf("состояние %s", info.State)
+	}
+}
+
+func TestRunResume(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		// run строки, которые должны выполниться заново
+		run       []int
+		startLine int
+		result    string
+	}{
+		{
+			name:      "ошибки в конце",
+			table:     "bin,status,error\n1,ok,\n2,ok,\n3,failed,нет окна\n4,pending,\n",
+			run:       []int{4, 5},
+			startLine: 4,
+			result:    "bin,status,error\n1,ok,\n2,ok,\n3,ok,\n4,ok,\n",
+		},
+		{
+			// Успешные строки после строки с ошибкой не повторяются: повторная подача заявки недопустима
+			name:      "успешные строки после ошибки",
+			table:     "bin,status,error\n1,ok,\n2,failed,нет окна\n3,ok,\n4,ok,\n5,skipped,отменено\n",
+			run:       []int{3, 6},
+			startLine: 3,
+			result:    "bin,status,error\n1,ok,\n2,ok,\n3,ok,\n4,ok,\n5,ok,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := batch.NewManager().Create(testScenario, 1, "lots-result.csv", readCSV(t, tt.table), batch.Options{Resume: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Info().StartLine; got != tt.startLine {
+				t.Errorf("start_line %d, ожидалась %d", got, tt.startLine)
+			}
+
+			var calls []call
+			if err := b.Run(context.Background(), recordRows(&calls)); err != nil {
+				t.Fatal(err)
+			}
+			if got := lines(calls); !reflect.DeepEqual(got, tt.run) {
+				t.Errorf("выполнены строки %v, ожидались %v", got, tt.run)
+			}
+			for _, row := range b.Info().Rows {
+				if row.Previous == slices.Contains(tt.run, row.Line) {
+					t.Errorf("строка %d: previous=%v", row.Line, row.Previous)
+				}
+			}
+
+			result, err := b.Result().Marshal("lots-result.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(result) != tt.result {
+				t.Errorf("результат:\n%s\nожидался:\n%s", result, tt.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"goszakup-automation/internal/executor"
+)
+
+// defaultPollInterval период опроса состояния запуска
+const defaultPollInterval = time.Second
+
+// Client запускает сценарий по таблице через HTTP API работающего сервиса
+// и печатает ход выполнения
+type Client struct {
+	// Server адрес сервиса, например http://localhost:3001
+	Server string
+	// HTTPClient клиент запросов (по умолчанию http.DefaultClient)
+	HTTPClient *http.Client
+	// PollInterval период опроса состояния (по умолчанию 1 с)
+	PollInterval time.Duration
+	// Log вывод хода выполнения (по умолчанию не выводится)
+	Log io.Writer
+}
+
+// Request параметры пакетного запуска
+type Request struct {
+	// Scenario имя сценария из каталога сценариев
+	Scenario string
+	// File имя таблицы (по расширению определяется формат), Data ее содержимое
+	File string
+	Data []byte
+	// Sheet лист XLSX (по умолчанию первый)
+	Sheet string
+	// Version версия сценария (0 — текущая)
+	Version int
+	// Mapping соответствие параметр сценария -> колонка таблицы
+	Mapping     map[string]string
+	Resume      bool
+	StopOnError bool
+	// ResultFormat формат файла результата: csv или xlsx (по умолчанию как у таблицы)
+	ResultFormat string
+}
+
+// Report итог пакетного запуска
+type Report struct {
+	Batch Info
+	Job   executor.JobInfo
+	// Result файл результата: таблица с колонками status и error
+	Result []byte
+}
+
+// Succeeded сообщает, что все строки выполнены успешно
+func (r *Report) Succeeded() bool {
+	return r.Job.State == executor.StateSucceeded
+}
+
+// ParseMapping разбирает соответствие параметров и колонок вида "параметр=колонка,..."
+func ParseMapping(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		param, column, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(param) == "" {
+			return nil, fmt.Errorf("неверное соответствие %q, ожидается параметр=колонка", pair)
+		}
+		mapping[strings.TrimSpace(param)] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// Run ставит пакетный запуск в очередь сервиса, печатает итоги строк по мере выполнения
+// и после завершения скачивает файл результата. При отмене ctx запуск отменяется
+// на сервисе, а Run дожидается его остановки.
+func (c *Client) Run(ctx context.Context, req Request) (*Report, error) {
+	body, contentType, err := req.form()
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(c.Server, "/") + "/api/robotogo"
+	var started struct {
+		BatchID string `json:"batch_id"`
+		JobID   string `json:"job_id"`
+	}
+	err = c.call(http.MethodPost, base+"/scenarios/"+url.PathEscape(req.Scenario)+"/batch?async=true", body, contentType, &started)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запуска: %w", err)
+	}
+	c.logf("Запуск %s поставлен в очередь, для отмены нажмите Ctrl+C\n", started.BatchID)
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var (
+		status  struct{ Batch Info }
+		job     struct{ Job executor.JobInfo }
+		printed = make(map[int]bool)
+		done    = ctx.Done()
+	)
+	for {
+		if err := c.call(http.MethodGet, base+"/jobs/"+started.JobID, nil, "", &job); err != nil {
+			return nil, fmt.Errorf("ошибка получения состояния: %w", err)
+		}
+		if err := c.call(http.MethodGet, base+"/batches/"+started.BatchID, nil, "", &status); err != nil {
+			return nil, fmt.Errorf("ошибка получения состояния: %w", err)
+		}
+		for i, row := range status.Batch.Rows {
+			if printed[i] || row.Previous {
+				continue
+			}
+			switch row.Status {
+			case RowOK:
+				c.logf("строка %d: ok (%d мс)\n", row.Line, row.DurationMs)
+			case RowFailed, RowSkipped:
+				c.logf("строка %d: %s: %s\n", row.Line, row.Status, row.Error)
+			default:
+				continue
+			}
+			printed[i] = true
+		}
+		if job.Job.State.Finished() {
+			break
+		}
+
+		select {
+		case <-done:
+			// Отмена отправляется один раз, дальше ждем остановки задания
+			done = nil
+			c.logf("Отмена запуска...\n")
+			if err := c.call(http.MethodDelete, base+"/jobs/"+started.JobID, nil, "", nil); err != nil {
+				c.logf("ошибка отмены: %v\n", err)
+			}
+		case <-time.After(interval):
+		}
+	}
+
+	summary := status.Batch.Summary
+	c.logf("Итого строк: %d, успешно: %d, с ошибками: %d, пропущено: %d, не выполнено: %d\n",
+		summary.Total, summary.OK, summary.Failed, summary.Skipped, summary.Pending)
+	if job.Job.Error != "" {
+		c.logf("запуск завершился ошибкой: %s\n", job.Job.Error)
+	}
+
+	format := req.ResultFormat
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(req.File)), ".")
+	}
+	result, err := c.download(base + "/batches/" + started.BatchID + "/result?format=" + url.QueryEscape(format))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения результата: %w", err)
+	}
+	return &Report{Batch: status.Batch, Job: job.Job, Result: result}, nil
+}
+
+// form формирует тело запроса multipart/form-data
+func (req Request) form() (*bytes.Buffer, string, error) {
+	fields := map[string]string{
+		"sheet":         req.Sheet,
+		"version":       strconv.Itoa(req.Version),
+		"resume":        strconv.FormatBool(req.Resume),
+		"stop_on_error": strconv.FormatBool(req.StopOnError),
+	}
+	if len(req.Mapping) > 0 {
+		encoded, err := json.Marshal(req.Mapping)
+		if err != nil {
+			return nil, "", err
+		}
+		fields["mapping"] = string(encoded)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for field, value := range fields {
+		if err := form.WriteField(field, value); err != nil {
+			return nil, "", err
+		}
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(req.File))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(req.Data); err != nil {
+		return nil, "", err
+	}
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, form.FormDataContentType(), nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.Log != nil {
+		fmt.Fprintf(c.Log, format, args...)
+	}
+}
+
+// call выполняет запрос к API сервиса и разбирает ответ в out.
+// Ответ с success=false возвращается как ошибка с текстом message и error.
+func (c *Client) call(method, address string, body io.Reader, contentType string, out any) error {
+	req, err := http.NewRequest(method, address, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var reply struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return fmt.Errorf("HTTP %s: %w", resp.Status, err)
+	}
+	if !reply.Success {
+		if reply.Error != "" {
+			return fmt.Errorf("%s: %s", reply.Message, reply.Error)
+		}
+		return errors.New(reply.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// download скачивает файл по адресу address
+func (c *Client) download(address string) ([]byte, error) {
+	resp, err := c.httpClient().Get(address)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,191 @@
+package batch_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/batch"
+	"goszakup-automation/internal/executor"
+)
+
+// fakeService имитирует API сервиса: задание завершается на втором опросе
+// (вторая строка с ошибкой) или после отмены
+type fakeService struct {
+	t     *testing.T
+	mu    sync.Mutex
+	polls int
+	// cancelled задание отменено запросом DELETE
+	cancelled bool
+	// form поля формы запроса на запуск
+	form map[string]string
+	file string
+}
+
+func (s *fakeService) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/robotogo/scenarios/{name}/batch", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "anketa" || r.URL.Query().Get("async") != "true" {
+			s.t.Errorf("запуск %s", r.URL)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		defer file.Close()
+		var data bytes.Buffer
+		_, _ = data.ReadFrom(file)
+
+		s.mu.Lock()
+		s.form = map[string]string{}
+		for field, values := range r.MultipartForm.Value {
+			s.form[field] = values[0]
+		}
+		s.file = header.Filename + ":" + data.String()
+		s.mu.Unlock()
+		reply(w, map[string]any{"success": true, "batch_id": "b1", "job_id": "j1"})
+	})
+	mux.HandleFunc("GET /api/robotogo/jobs/j1", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.polls++
+		state := executor.StateRunning
+		switch {
+		case s.cancelled:
+			state = executor.StateCancelled
+		case s.polls >= 2:
+			state = executor.StateSucceeded
+		}
+		reply(w, map[string]any{"success": true, "job": executor.JobInfo{ID: "j1", State: state}})
+	})
+	mux.HandleFunc("GET /api/robotogo/batches/b1", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rows := []batch.RowResult{{Line: 2, Status: batch.RowOK, DurationMs: 10}, {Line: 3, Status: batch.RowRunning}}
+		summary := batch.Summary{Total: 2, OK: 1, Pending: 1}
+		switch {
+		case s.cancelled:
+			rows[1] = batch.RowResult{Line: 3, Status: batch.RowSkipped, Error: "отменено"}
+			summary = batch.Summary{Total: 2, OK: 1, Skipped: 1}
+		case s.polls >= 2:
+			rows[1] = batch.RowResult{Line: 3, Status: batch.RowFailed, Error: "нет окна"}
+			summary = batch.Summary{Total: 2, OK: 1, Failed: 1}
+		}
+		reply(w, map[string]any{"success": true, "batch": batch.Info{ID: "b1", StartLine: 2, Rows: rows, Summary: summary}})
+	})
+	mux.HandleFunc("DELETE /api/robotogo/jobs/j1", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.cancelled = true
+		s.mu.Unlock()
+		reply(w, map[string]any{"success": true})
+	})
+	mux.HandleFunc("GET /api/robotogo/batches/b1/result", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("format=" + r.URL.Query().Get("format")))
+	})
+	return mux
+}
+
+func reply(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestClientRun(t *testing.T) {
+	service := &fakeService{t: t}
+	server := httptest.NewServer(service.handler())
+	defer server.Close()
+
+	var log strings.Builder
+	client := &batch.Client{Server: server.URL + "/", PollInterval: 1, Log: &log}
+	report, err := client.Run(context.Background(), batch.Request{
+		Scenario:     "anketa",
+		File:         "dir/data.csv",
+		Data:         []byte("name\nA\nB\n"),
+		Version:      2,
+		Mapping:      map[string]string{"fio": "name"},
+		ResultFormat: "xlsx",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if service.file != "data.csv:name\nA\nB\n" {
+		t.Errorf("файл %q", service.file)
+	}
+	wantForm := map[string]string{"sheet": "", "version": "2", "resume": "false", "stop_on_error": "false", "mapping": `{"fio":"name"}`}
+	for field, want := range wantForm {
+		if got := service.form[field]; got != want {
+			t.Errorf("поле %s = %q, ожидалось %q", field, got, want)
+		}
+	}
+
+	if !report.Succeeded() || string(report.Result) != "format=xlsx" || report.Batch.Summary.Failed != 1 {
+		t.Errorf("итог %+v, результат %q", report.Job, report.Result)
+	}
+	wantLog := "Запуск b1 поставлен в очередь, для отмены нажмите Ctrl+C\n" +
+		"строка 2: ok (10 мс)\n" +
+		"строка 3: failed: нет окна\n" +
+		"Итого строк: 2, успешно: 1, с ошибками: 1, пропущено: 0, не выполнено: 0\n"
+	if log.String() != wantLog {
+		t.Errorf("вывод:\n%s\nожидался:\n%s", log.String(), wantLog)
+	}
+}
+
+func TestClientRunCancel(t *testing.T) {
+	service := &fakeService{t: t}
+	server := httptest.NewServer(service.handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var log strings.Builder
+	// Опрос раз в час: выйти из ожидания можно только по отмене
+	client := &batch.Client{Server: server.URL, PollInterval: time.Hour, Log: &log}
+	report, err := client.Run(ctx, batch.Request{Scenario: "anketa", File: "data.xlsx", Data: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !service.cancelled || report.Succeeded() || report.Job.State != executor.StateCancelled {
+		t.Errorf("задание %+v, отменено на сервисе: %v", report.Job, service.cancelled)
+	}
+	if string(report.Result) != "format=xlsx" {
+		t.Errorf("результат %q", report.Result)
+	}
+	if !strings.Contains(log.String(), "Отмена запуска...\n") || !strings.Contains(log.String(), "строка 3: skipped: отменено\n") {
+		t.Errorf("вывод:\n%s", log.String())
+	}
+}
+
+func TestClientRunError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		reply(w, map[string]any{"success": false, "message": "Сценарий не найден", "error": "anketa"})
+	}))
+	defer server.Close()
+
+	client := &batch.Client{Server: server.URL}
+	_, err := client.Run(context.Background(), batch.Request{Scenario: "anketa", File: "data.csv"})
+	if err == nil || err.Error() != "ошибка запуска: Сценарий не найден: anketa" {
+		t.Fatalf("ошибка %v", err)
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	got, err := batch.ParseMapping(" fio = ФИО ,iin=ИИН")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["fio"] != "ФИО" || got["iin"] != "ИИН" {
+		t.Errorf("соответствие %v", got)
+	}
+	if _, err := batch.ParseMapping("fio"); err == nil {
+		t.Error("ожидалась ошибка для соответствия без =")
+	}
+}
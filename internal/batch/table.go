@@ -0,0 +1,238 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// utf8BOM метка порядка байтов, с которой Excel сохраняет CSV в UTF-8
+const utf8BOM = "\ufeff"
+
+// Table таблица данных: заголовок и строки. Все значения хранятся текстом в том виде,
+// в котором их показывает Excel, чтобы не потерять ведущие нули в БИН/ИИН и форматы дат.
+type Table struct {
+	Header []string
+	// Rows строки данных без заголовка, Lines — их номера в файле (заголовок — строка 1)
+	Rows  [][]string
+	Lines []int
+	// Sheet лист XLSX, из которого прочитана таблица
+	Sheet string
+	// Comma разделитель CSV, BOM — файл CSV начинался с метки UTF-8
+	Comma rune
+	BOM   bool
+}
+
+// IsSpreadsheet сообщает, что файл с таким именем можно прочитать как таблицу (.csv, .xlsx)
+func IsSpreadsheet(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".xlsx":
+		return true
+	}
+	return false
+}
+
+// ReadTable читает таблицу. Формат определяется по расширению файла (.csv, .xlsx).
+// Для XLSX sheet — имя листа (пусто — первый лист). Пустые строки пропускаются.
+func ReadTable(filename string, data []byte, sheet string) (*Table, error) {
+	var (
+		table *Table
+		rows  [][]string
+		err   error
+	)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		table, rows, err = readCSV(data)
+	case ".xlsx":
+		table, rows, err = readXLSX(data, sheet)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат таблицы %q, ожидается .csv или .xlsx", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("таблица пуста: нет строки заголовка")
+	}
+
+	seen := make(map[string]bool)
+	for i, name := range rows[0] {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("колонка %q встречается в заголовке дважды", name)
+		}
+		seen[strings.ToLower(name)] = true
+		rows[0][i] = name
+	}
+	table.Header = rows[0]
+
+	for i, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+		table.Rows = append(table.Rows, row)
+		table.Lines = append(table.Lines, i+2)
+	}
+	return table, nil
+}
+
+func readCSV(data []byte) (*Table, [][]string, error) {
+	table := &Table{Comma: ','}
+	if bytes.HasPrefix(data, []byte(utf8BOM)) {
+		data = data[len(utf8BOM):]
+		table.BOM = true
+	}
+
+	// Excel с русской локалью сохраняет CSV с разделителем ";", разделитель определяем по заголовку
+	header := data
+	if end := bytes.IndexByte(data, '\n'); end >= 0 {
+		header = data[:end]
+	}
+	best := 0
+	for _, comma := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(header, []byte(string(comma))); n > best {
+			best, table.Comma = n, comma
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = table.Comma
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка разбора CSV: %w", err)
+	}
+	return table, rows, nil
+}
+
+func readXLSX(data []byte, sheet string) (*Table, [][]string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка разбора XLSX: %w", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if sheet == "" {
+		if len(sheets) == 0 {
+			return nil, nil, errors.New("в файле XLSX нет листов")
+		}
+		sheet = sheets[0]
+	}
+	rows, err := file.GetRows(sheet)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка чтения листа %q (листы: %s): %w", sheet, strings.Join(sheets, ", "), err)
+	}
+	return &Table{Sheet: sheet}, rows, nil
+}
+
+// Marshal записывает таблицу в формате, соответствующем расширению файла (.csv, .xlsx).
+// CSV записывается с тем же разделителем и меткой UTF-8, что и исходный файл.
+func (t *Table) Marshal(filename string) ([]byte, error) {
+	rows := append([][]string{t.Header}, t.Rows...)
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		var buf bytes.Buffer
+		if t.BOM {
+			buf.WriteString(utf8BOM)
+		}
+		writer := csv.NewWriter(&buf)
+		if t.Comma != 0 {
+			writer.Comma = t.Comma
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return nil, fmt.Errorf("ошибка записи CSV: %w", err)
+		}
+		return buf.Bytes(), nil
+
+	case ".xlsx":
+		file := excelize.NewFile()
+		defer file.Close()
+
+		sheet := t.Sheet
+		if sheet == "" {
+			sheet = "Sheet1"
+		}
+		if err := file.SetSheetName(file.GetSheetList()[0], sheet); err != nil {
+			return nil, fmt.Errorf("ошибка записи XLSX: %w", err)
+		}
+		for i, row := range rows {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка записи XLSX: %w", err)
+			}
+			// Значения пишутся текстом, как были прочитаны, чтобы Excel не превратил БИН в число
+			values := make([]any, len(row))
+			for j, value := range row {
+				values[j] = value
+			}
+			if err := file.SetSheetRow(sheet, cell, &values); err != nil {
+				return nil, fmt.Errorf("ошибка записи XLSX: %w", err)
+			}
+		}
+		buf, err := file.WriteToBuffer()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка записи XLSX: %w", err)
+		}
+		return buf.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат таблицы %q, ожидается .csv или .xlsx", filepath.Ext(filename))
+	}
+}
+
+// Column возвращает индекс колонки по имени без учета регистра (-1 — колонки нет)
+func (t *Table) Column(name string) int {
+	for i, header := range t.Header {
+		if strings.EqualFold(header, strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Cell возвращает значение колонки col строки row (пусто, если в строке меньше колонок)
+func (t *Table) Cell(row, col int) string {
+	if col < 0 || col >= len(t.Rows[row]) {
+		return ""
+	}
+	return t.Rows[row][col]
+}
+
+// setCell записывает значение, дополняя строку пустыми колонками
+func (t *Table) setCell(row, col int, value string) {
+	for len(t.Rows[row]) <= col {
+		t.Rows[row] = append(t.Rows[row], "")
+	}
+	t.Rows[row][col] = value
+}
+
+// clone копирует таблицу вместе со строками
+func (t *Table) clone() *Table {
+	cloned := *t
+	cloned.Header = append([]string(nil), t.Header...)
+	cloned.Rows = make([][]string, len(t.Rows))
+	for i, row := range t.Rows {
+		cloned.Rows[i] = append([]string(nil), row...)
+	}
+	cloned.Lines = append([]int(nil), t.Lines...)
+	return &cloned
+}
+
+func isEmptyRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -9,6 +11,51 @@ import (
 type Config struct {
 	Port        string
 	Environment string
+
+	// InputBackend имя бэкенда ввода (robotgo, x11). Пустое значение — первый доступный в сборке
+	InputBackend string
+	// Display адрес X-сервера для бэкенда x11 (по умолчанию DISPLAY)
+	Display string
+
+	// QueueWaitTimeout максимальное время ожидания действия в очереди (0 — без ограничения)
+	QueueWaitTimeout time.Duration
+
+	// FailSafeCorners углы экрана, при попадании курсора в которые срабатывает аварийный останов
+	// (top-left, top-right, bottom-left, bottom-right через запятую, all или off)
+	FailSafeCorners string
+	// FailSafeMargin расстояние от угла в пикселях, считающееся попаданием в угол
+	FailSafeMargin int
+	// FailSafePollInterval период опроса позиции курсора
+	FailSafePollInterval time.Duration
+
+	// ScenariosDir каталог с файлами сценариев (YAML/JSON)
+	ScenariosDir string
+
+	// TemplatesDir каталог изображений-шаблонов для поиска на экране (image:имя)
+	TemplatesDir string
+	// MatchThreshold порог совпадения шаблона по умолчанию (0-1)
+	MatchThreshold float64
+	// MatchMode способ сравнения шаблона по умолчанию: gray (яркость) или edge (контуры)
+	MatchMode string
+	// MatchMinScale, MatchMaxScale диапазон масштабов шаблона по умолчанию
+	// (например 0.65-1.55 для экранов с масштабированием 100%, 125% и 150%)
+	MatchMinScale float64
+	MatchMaxScale float64
+	// MatchScaleStep шаг перебора масштабов
+	MatchScaleStep float64
+
+	// OCRLanguages языки распознавания текста по умолчанию через "+"
+	OCRLanguages string
+	// OCRPSM режим сегментации страницы tesseract по умолчанию (6 — блок текста)
+	OCRPSM int
+
+	// DebugPauseTimeout максимальное время паузы отладчика, после которого отладка прерывается
+	// и рабочий стол освобождается (0 — без ограничения)
+	DebugPauseTimeout time.Duration
+
+	// ShutdownTimeout сколько ждать остановки выполняемого действия (с блоками on_failure
+	// и finally) и HTTP сервера при завершении
+	ShutdownTimeout time.Duration
 }
 
 func Load() *Config {
@@ -18,6 +65,31 @@ func Load() *Config {
 	cfg := &Config{
 		Port:        getEnv("PORT", "3007"),
 		Environment: getEnv("ENVIRONMENT", "development"),
+
+		InputBackend: getEnv("INPUT_BACKEND", ""),
+		Display:      getEnv("X11_DISPLAY", os.Getenv("DISPLAY")),
+
+		QueueWaitTimeout: getDurationEnv("QUEUE_WAIT_TIMEOUT", 60*time.Second),
+
+		FailSafeCorners:      getEnv("FAILSAFE_CORNERS", "top-left"),
+		FailSafeMargin:       getIntEnv("FAILSAFE_MARGIN", 0),
+		FailSafePollInterval: getDurationEnv("FAILSAFE_POLL_INTERVAL", 100*time.Millisecond),
+
+		ScenariosDir: getEnv("SCENARIOS_DIR", "scenarios"),
+
+		TemplatesDir:   getEnv("TEMPLATES_DIR", "templates"),
+		MatchThreshold: getFloatEnv("MATCH_THRESHOLD", 0.9),
+		MatchMode:      getEnv("MATCH_MODE", "gray"),
+		MatchMinScale:  getFloatEnv("MATCH_MIN_SCALE", 1),
+		MatchMaxScale:  getFloatEnv("MATCH_MAX_SCALE", 1),
+		MatchScaleStep: getFloatEnv("MATCH_SCALE_STEP", 0.05),
+
+		OCRLanguages: getEnv("OCR_LANGUAGES", "rus+kaz+eng"),
+		OCRPSM:       getIntEnv("OCR_PSM", 6),
+
+		DebugPauseTimeout: getDurationEnv("DEBUG_PAUSE_TIMEOUT", 10*time.Minute),
+
+		ShutdownTimeout: getDurationEnv("SHUTDOWN_TIMEOUT", 35*time.Second),
 	}
 
 	return cfg
@@ -29,3 +101,30 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
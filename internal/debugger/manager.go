@@ -0,0 +1,52 @@
+package debugger
+
+import (
+	"sync"
+	"time"
+)
+
+// sessionRetention время хранения завершенных сеансов
+const sessionRetention = time.Hour
+
+// Manager хранит сеансы отладки по ID
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager создает хранилище сеансов
+func NewManager() *Manager {
+	return &Manager{sessions: make(map[string]*Session)}
+}
+
+// Add регистрирует сеанс под ID его задания и удаляет давно завершенные сеансы
+func (m *Manager) Add(id string, session *Session) {
+	session.mu.Lock()
+	session.id = id
+	session.mu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for oldID, old := range m.sessions {
+		old.mu.Lock()
+		expired := old.state == StateFinished && time.Since(old.finishedAt) > sessionRetention
+		old.mu.Unlock()
+		if expired {
+			delete(m.sessions, oldID)
+		}
+	}
+	m.sessions[id] = session
+}
+
+// Get возвращает сеанс по ID
+func (m *Manager) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
@@ -0,0 +1,402 @@
+// Package debugger реализует пошаговую отладку последовательностей: остановку на точках
+// останова, просмотр переменных и скриншота на паузе и управление выполнением через API.
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSessionNotFound возвращается, если сеанс отладки с указанным ID не найден
+	ErrSessionNotFound = errors.New("сеанс отладки не найден")
+	// ErrNotPaused возвращается для команд, отправленных не на паузе
+	ErrNotPaused = errors.New("выполнение не на паузе")
+	// ErrAborted возвращается последовательностью, прерванной командой abort
+	ErrAborted = errors.New("отладка прервана оператором")
+	// ErrPauseTimeout возвращается, если оператор не ответил за отведенное время
+	ErrPauseTimeout = errors.New("превышено время ожидания на паузе")
+)
+
+// State состояние сеанса отладки
+type State string
+
+const (
+	StateRunning  State = "running"
+	StatePaused   State = "paused"
+	StateFinished State = "finished"
+)
+
+// Command команда оператора на паузе
+type Command string
+
+const (
+	// CommandStep выполнить текущий шаг и остановиться перед следующим
+	CommandStep Command = "step"
+	// CommandContinue выполнять до следующей точки останова
+	CommandContinue Command = "continue"
+	// CommandSkip пропустить текущий шаг и остановиться перед следующим
+	CommandSkip Command = "skip"
+	// CommandAbort прервать последовательность
+	CommandAbort Command = "abort"
+	// commandSetVars изменить переменные, оставаясь на паузе
+	commandSetVars Command = "set_vars"
+)
+
+// Pause сведения о паузе перед шагом
+type Pause struct {
+	Step sequence.StepInfo `json:"step"`
+	// Reason причина остановки: breakpoint или step
+	Reason string         `json:"reason"`
+	Vars   map[string]any `json:"vars"`
+	Params map[string]any `json:"params,omitempty"`
+	Since  time.Time      `json:"since"`
+	// ScreenshotError ошибка снятия скриншота (скриншот недоступен)
+	ScreenshotError string `json:"screenshot_error,omitempty"`
+}
+
+// Info состояние сеанса для API
+type Info struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	State       State    `json:"state"`
+	Breakpoints []string `json:"breakpoints"`
+	Pause       *Pause   `json:"pause,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type command struct {
+	cmd   Command
+	vars  map[string]any
+	reply chan error
+}
+
+// Session сеанс отладки одной последовательности. Hook подключается к sequence.Runner
+// и выполняется в горутине задания; команды приходят из обработчиков API.
+type Session struct {
+	logger  *zap.Logger
+	service *input.Service
+	name    string
+	// pauseTimeout максимальное время паузы (0 — без ограничения)
+	pauseTimeout time.Duration
+
+	commands chan command
+	done     chan struct{}
+
+	mu sync.Mutex
+	// cancel отменяет контекст задания при abort и таймауте паузы,
+	// чтобы retry и вложенные блоки не продолжали выполнение
+	cancel      context.CancelCauseFunc
+	id          string
+	breakpoints []string
+	stepping    bool
+	state       State
+	pause       *Pause
+	screenshot  []byte
+	err         error
+	finishedAt  time.Time
+	// changed закрывается при каждой смене состояния
+	changed chan struct{}
+}
+
+// NewSession создает сеанс. breakpoints — пути шагов ("3", "2.then.1") или имена шагов.
+// Со stepping выполнение останавливается перед первым шагом.
+func NewSession(logger *zap.Logger, service *input.Service, name string, breakpoints []string, stepping bool, pauseTimeout time.Duration) *Session {
+	if breakpoints == nil {
+		breakpoints = []string{}
+	}
+	return &Session{
+		logger:       logger,
+		service:      service,
+		name:         name,
+		pauseTimeout: pauseTimeout,
+		commands:     make(chan command),
+		done:         make(chan struct{}),
+		breakpoints:  breakpoints,
+		stepping:     stepping,
+		state:        StateRunning,
+		changed:      make(chan struct{}),
+	}
+}
+
+// Attach связывает сеанс с контекстом задания. Возвращенный контекст передается в Runner.
+func (s *Session) Attach(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	return ctx
+}
+
+// ID возвращает идентификатор сеанса (совпадает с ID задания)
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Info возвращает состояние сеанса
+func (s *Session) Info() Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := Info{
+		ID:          s.id,
+		Name:        s.name,
+		State:       s.state,
+		Breakpoints: s.breakpoints,
+		Pause:       s.pause,
+	}
+	if s.err != nil {
+		info.Error = s.err.Error()
+	}
+	return info
+}
+
+// Screenshot возвращает PNG скриншот, снятый при последней паузе
+func (s *Session) Screenshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != StatePaused {
+		return nil, ErrNotPaused
+	}
+	if s.screenshot == nil {
+		return nil, fmt.Errorf("скриншот недоступен: %s", s.pause.ScreenshotError)
+	}
+	return s.screenshot, nil
+}
+
+// Send передает команду выполнению, стоящему на паузе. После step и skip ждет
+// следующей паузы или завершения, чтобы ответ содержал новое состояние.
+func (s *Session) Send(ctx context.Context, cmd Command) error {
+	if err := s.send(ctx, command{cmd: cmd}); err != nil {
+		return err
+	}
+	if cmd == CommandStep || cmd == CommandSkip {
+		return s.waitStopped(ctx)
+	}
+	return nil
+}
+
+// SetVars изменяет переменные последовательности, оставаясь на паузе
+func (s *Session) SetVars(ctx context.Context, vars map[string]any) error {
+	return s.send(ctx, command{cmd: commandSetVars, vars: vars})
+}
+
+func (s *Session) send(ctx context.Context, c command) error {
+	if s.Info().State != StatePaused {
+		return ErrNotPaused
+	}
+
+	c.reply = make(chan error, 1)
+	select {
+	case s.commands <- c:
+	case <-s.done:
+		return ErrNotPaused
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-c.reply
+}
+
+// waitStopped ждет паузы или завершения
+func (s *Session) waitStopped(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		state, changed := s.state, s.changed
+		s.mu.Unlock()
+		if state != StateRunning {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setStateLocked меняет состояние и будит ожидающих waitStopped
+func (s *Session) setStateLocked(state State) {
+	s.state = state
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Finish отмечает завершение последовательности
+func (s *Session) Finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setStateLocked(StateFinished)
+	s.pause = nil
+	s.screenshot = nil
+	s.err = err
+	s.finishedAt = time.Now()
+	close(s.done)
+	if s.cancel != nil {
+		s.cancel(nil)
+	}
+}
+
+// Hook останавливает выполнение перед шагом на точке останова или в пошаговом режиме
+// и ждет команды оператора. Используется как sequence.Hook.
+func (s *Session) Hook(ctx context.Context, info sequence.StepInfo, scope *sequence.Scope) (sequence.StepAction, error) {
+	reason := s.breakReason(info)
+	if reason == "" {
+		return sequence.ActionRun, nil
+	}
+
+	pause := &Pause{
+		Step:   info,
+		Reason: reason,
+		Vars:   copyVars(scope.Vars),
+		Params: scope.Params,
+		Since:  time.Now(),
+	}
+	screenshot, err := s.capture(ctx)
+	if err != nil {
+		pause.ScreenshotError = err.Error()
+	}
+
+	s.mu.Lock()
+	s.pause = pause
+	s.screenshot = screenshot
+	s.setStateLocked(StatePaused)
+	s.mu.Unlock()
+
+	s.logger.Info("Отладка: пауза перед шагом",
+		zap.String("session", s.ID()),
+		zap.String("path", info.Path),
+		zap.String("reason", reason))
+
+	defer s.resume()
+
+	var timeout <-chan time.Time
+	if s.pauseTimeout > 0 {
+		timer := time.NewTimer(s.pauseTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sequence.ActionRun, fmt.Errorf("действие прервано: %w", ctx.Err())
+		case <-timeout:
+			s.abort(ErrPauseTimeout)
+			return sequence.ActionRun, ErrPauseTimeout
+		case c := <-s.commands:
+			if c.cmd == commandSetVars {
+				for name, value := range c.vars {
+					scope.Vars[name] = value
+				}
+				s.mu.Lock()
+				s.pause.Vars = copyVars(scope.Vars)
+				s.mu.Unlock()
+				c.reply <- nil
+				continue
+			}
+
+			// Состояние меняется до ответа, чтобы Send не увидел прежнюю паузу
+			s.resume()
+			c.reply <- nil
+			s.logger.Info("Отладка: команда оператора",
+				zap.String("session", s.ID()),
+				zap.String("command", string(c.cmd)))
+
+			switch c.cmd {
+			case CommandStep:
+				s.setStepping(true)
+				return sequence.ActionRun, nil
+			case CommandSkip:
+				s.setStepping(true)
+				return sequence.ActionSkip, nil
+			case CommandContinue:
+				s.setStepping(false)
+				return sequence.ActionRun, nil
+			default:
+				s.abort(ErrAborted)
+				return sequence.ActionRun, ErrAborted
+			}
+		}
+	}
+}
+
+// breakReason возвращает причину остановки перед шагом или пустую строку
+func (s *Session) breakReason(info sequence.StepInfo) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, bp := range s.breakpoints {
+		if bp == info.Path || (info.Name != "" && bp == info.Name) {
+			return "breakpoint"
+		}
+	}
+	if s.stepping {
+		return "step"
+	}
+	return ""
+}
+
+// resume снимает паузу
+func (s *Session) resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == StatePaused {
+		s.setStateLocked(StateRunning)
+		s.pause = nil
+		s.screenshot = nil
+	}
+}
+
+// abort отменяет контекст задания с причиной err
+func (s *Session) abort(err error) {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel(err)
+	}
+}
+
+func (s *Session) setStepping(stepping bool) {
+	s.mu.Lock()
+	s.stepping = stepping
+	s.mu.Unlock()
+}
+
+// capture снимает весь экран в PNG
+func (s *Session) capture(ctx context.Context) ([]byte, error) {
+	img, err := s.service.CaptureScreen(ctx, image.Rectangle{})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("ошибка кодирования скриншота: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func copyVars(vars map[string]any) map[string]any {
+	out := make(map[string]any, len(vars))
+	for name, value := range vars {
+		out[name] = value
+	}
+	return out
+}
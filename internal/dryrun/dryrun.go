@@ -0,0 +1,119 @@
+// Package dryrun выполняет пробный прогон последовательности шагов на fake бэкенде:
+// шаблоны, значения по умолчанию и платформенные задержки вычисляются так же, как
+// при обычном запуске, но вместо нажатий возвращается план низкоуровневых событий
+// с расчетным временем. Реальные мышь и клавиатура не затрагиваются.
+package dryrun
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"time"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+
+	"go.uber.org/zap"
+)
+
+// Plan результат пробного прогона
+type Plan struct {
+	// Events низкоуровневые события в порядке выполнения с расчетным временем at_ms
+	Events []fake.Event `json:"events"`
+	// EstimatedMs расчетная длительность всей последовательности
+	EstimatedMs int64 `json:"estimated_ms"`
+	// Report результат шагов (длительности шагов расчетные)
+	Report *sequence.Report `json:"report"`
+}
+
+// Planner строит планы выполнения для рабочего input.Service
+type Planner struct {
+	logger  *zap.Logger
+	service *input.Service
+}
+
+// NewPlanner создает планировщик. service используется только для чтения параметров
+// окружения: платформы, размера экрана и положения курсора.
+func NewPlanner(logger *zap.Logger, service *input.Service) *Planner {
+	return &Planner{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Plan выполняет шаги на отдельном fake бэкенде с той же платформой и размером экрана,
+// что у рабочего бэкенда. Условия image и text в пробном прогоне считаются невыполненными,
+// read_text возвращает пустой текст, а цели image:имя указывают на центр области поиска.
+// Ожидаемый цвет wait_for_color и условий pixel считается найденным сразу: иначе ожидание
+// цвета на пустом экране всегда заканчивалось бы ошибкой по timeout_ms. Ошибка шага
+// возвращается вместе с планом событий, выполненных до нее, и блоков завершения cleanup.
+func (p *Planner) Plan(ctx context.Context, steps []sequence.Step, cleanup sequence.Cleanup, scope *sequence.Scope) (*Plan, error) {
+	opts := fake.Options{Platform: p.service.Platform()}
+	if width, height, err := p.service.Backend().ScreenSize(); err == nil {
+		opts.Width, opts.Height = width, height
+	}
+	backend := fake.New(opts)
+
+	// План начинается с текущего положения курсора, чтобы mouse_position вернул реальное значение
+	if x, y, err := p.service.Backend().MousePosition(); err == nil {
+		backend.MoveMouse(x, y)
+		backend.Reset()
+	}
+
+	started := time.Now()
+	runner := sequence.NewRunner(p.logger, input.NewService(p.logger, backend))
+	runner.SetClock(func() time.Time {
+		return started.Add(backend.Now())
+	})
+	runner.SetImageChecker(notFound{})
+	runner.SetImageLocator(regionCenter{width: opts.Width, height: opts.Height})
+	runner.SetTextReader(notFound{})
+	runner.SetTextChecker(notFound{})
+	runner.SetColorProbe(requestedColor{})
+
+	report, err := runner.RunWithCleanup(ctx, steps, cleanup, scope, nil)
+	return &Plan{
+		Events:      backend.Events(),
+		EstimatedMs: backend.Now().Milliseconds(),
+		Report:      report,
+	}, err
+}
+
+// notFound проверки экрана, которые в пробном прогоне ничего не находят
+type notFound struct{}
+
+func (notFound) CheckImage(ctx context.Context, cond *sequence.ImageCondition) (bool, error) {
+	return false, nil
+}
+
+func (notFound) CheckText(ctx context.Context, cond *sequence.TextCondition) (bool, error) {
+	return false, nil
+}
+
+func (notFound) ReadText(ctx context.Context, region image.Rectangle, opts ocr.Options) (*ocr.Result, error) {
+	return &ocr.Result{Words: []ocr.Word{}}, nil
+}
+
+// regionCenter поиск изображений для целей image:имя: в пробном прогоне цель
+// указывает на центр области поиска (или экрана)
+type regionCenter struct {
+	width, height int
+}
+
+func (c regionCenter) LocateImage(ctx context.Context, name string, region *sequence.Region, opts screen.MatchOptions) (image.Point, error) {
+	if region != nil {
+		rect := region.Rect()
+		return image.Pt(rect.Min.X+rect.Dx()/2, rect.Min.Y+rect.Dy()/2), nil
+	}
+	return image.Pt(c.width/2, c.height/2), nil
+}
+
+// requestedColor определение цвета в пробном прогоне: на экране всегда ожидаемый цвет
+type requestedColor struct{}
+
+func (requestedColor) ProbeColor(ctx context.Context, x, y int, region *sequence.Region, want color.RGBA) (color.RGBA, error) {
+	return want, nil
+}
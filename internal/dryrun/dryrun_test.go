@@ -0,0 +1,79 @@
+package dryrun_test
+
+import (
+	"context"
+	"testing"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/dryrun"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func parseSteps(t *testing.T, src string) []sequence.Step {
+	t.Helper()
+	var steps []sequence.Step
+	if err := yaml.Unmarshal([]byte(src), &steps); err != nil {
+		t.Fatal(err)
+	}
+	if err := sequence.Validate(steps); err != nil {
+		t.Fatal(err)
+	}
+	return steps
+}
+
+func newPlanner() *dryrun.Planner {
+	service := input.NewService(zap.NewNop(), fake.New(fake.Options{Platform: "linux"}))
+	return dryrun.NewPlanner(zap.NewNop(), service)
+}
+
+func TestPlanColorFound(t *testing.T) {
+	tests := []struct {
+		name string
+		// probe место ожидания цвета в шаге wait_for_color
+		probe string
+	}{
+		{name: "pixel", probe: "x: 10\n  y: 20"},
+		{name: "region", probe: "region: {x: 0, y: 0, width: 40, height: 30}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := parseSteps(t, `
+- type: wait_for_color
+  `+tt.probe+`
+  color: "#1A73E8"
+  timeout_ms: 5000
+  output: found
+- type: if
+  condition:
+    pixel: {x: 10, y: 20, color: "#ffffff"}
+  then:
+    - type: click
+      x: 10
+      y: 20
+`)
+			plan, err := newPlanner().Plan(context.Background(), steps, sequence.Cleanup{}, sequence.NewScope(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output, _ := plan.Report.Steps[0].Output.(map[string]any)
+			if output["color"] != "#1A73E8" || output["elapsed_ms"] != int64(0) {
+				t.Errorf("wait_for_color: %v", plan.Report.Steps[0].Output)
+			}
+			if plan.Report.Steps[1].Output != true {
+				t.Errorf("условие pixel не выполнено: %+v", plan.Report.Steps[1])
+			}
+			if len(plan.Events) == 0 || plan.Events[len(plan.Events)-1].String() != "click(left)" {
+				t.Errorf("события %v", plan.Events)
+			}
+			if plan.EstimatedMs >= 5000 {
+				t.Errorf("расчетное время %d мс: цвет ожидался до timeout_ms", plan.EstimatedMs)
+			}
+		})
+	}
+}
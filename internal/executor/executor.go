@@ -0,0 +1,382 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQueueTimeout возвращается, если действие не дождалось своей очереди за отведенное время
+	ErrQueueTimeout = errors.New("превышено время ожидания в очереди")
+	// ErrJobNotFound возвращается, если задание с указанным ID не найдено
+	ErrJobNotFound = errors.New("задание не найдено")
+	// ErrShuttingDown возвращается для заданий, поставленных после начала остановки
+	ErrShuttingDown = errors.New("сервер останавливается")
+	// ErrStopped причина отмены заданий аварийной остановкой (context.Cause контекста задания)
+	ErrStopped = input.ErrStopped
+)
+
+const (
+	// jobRetention время хранения завершенных заданий
+	jobRetention = time.Hour
+	// maxFinishedJobs максимальное число хранимых завершенных заданий
+	maxFinishedJobs = 1000
+)
+
+// Task действие, выполняемое с монопольным доступом к рабочему столу.
+// Возвращаемое значение сохраняется как результат задания.
+type Task func(ctx context.Context) (any, error)
+
+// Result результат синхронного выполнения действия
+type Result struct {
+	JobID string
+	Value any
+	// QueuePosition позиция в очереди при постановке (0 — выполнено сразу)
+	QueuePosition int
+	// Waited время ожидания в очереди
+	Waited time.Duration
+}
+
+// Status состояние очереди
+type Status struct {
+	Running *JobInfo  `json:"running"`
+	Queued  []JobInfo `json:"queued"`
+}
+
+// Executor выполняет задания строго по одному в порядке поступления (FIFO).
+// Только он владеет рабочим столом, поэтому действия разных запросов не перемешиваются.
+type Executor struct {
+	logger *zap.Logger
+	// maxWait максимальное время ожидания в очереди (0 — без ограничения)
+	maxWait time.Duration
+
+	// ctx родительский контекст всех заданий, отменяется при остановке
+	ctx    context.Context
+	cancel context.CancelFunc
+	// cleanup вызывается после неуспешного задания, пока рабочий стол еще не передан следующему
+	cleanup func()
+
+	mu      sync.Mutex
+	closed  bool
+	running *Job
+	queue   []*Job
+	jobs    map[string]*Job
+	// finished завершенные задания в порядке завершения (для очистки)
+	finished []*Job
+}
+
+// New создает очередь выполнения
+func New(logger *zap.Logger, maxWait time.Duration) *Executor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Executor{
+		logger:  logger,
+		maxWait: maxWait,
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*Job),
+	}
+}
+
+// SetCleanup задает функцию, которая вызывается после каждого задания, завершившегося
+// ошибкой, отменой или паникой, до запуска следующего. Вызывается до начала работы.
+func (e *Executor) SetCleanup(cleanup func()) {
+	e.cleanup = cleanup
+}
+
+// Submit ставит задание в очередь и сразу возвращает его. Задание выполняется
+// в отдельной горутине, когда подойдет его очередь.
+func (e *Executor) Submit(name string, task Task) *Job {
+	ctx, cancel := context.WithCancelCause(e.ctx)
+	job := &Job{
+		ID:      newJobID(),
+		Name:    name,
+		exec:    e,
+		task:    task,
+		ctx:     ctx,
+		cancel:  cancel,
+		state:   StateQueued,
+		granted: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	e.cleanupLocked()
+	job.enqueuedAt = time.Now()
+	e.jobs[job.ID] = job
+	if e.closed {
+		e.mu.Unlock()
+		e.finish(job, StateCancelled, nil, ErrShuttingDown)
+		cancel(nil)
+		return job
+	}
+	e.queue = append(e.queue, job)
+	e.dispatchLocked()
+	job.queuePosition = e.positionLocked(job)
+	position := job.queuePosition
+	e.mu.Unlock()
+
+	if position > 0 {
+		e.logger.Info("Действие поставлено в очередь",
+			zap.String("job_id", job.ID),
+			zap.String("action", name),
+			zap.Int("position", position))
+	}
+
+	go e.process(job)
+
+	return job
+}
+
+// Do ставит действие в очередь, дожидается его выполнения и возвращает результат.
+// Если ctx отменен, задание отменяется. Результат возвращается и вместе с ошибкой:
+// задача может вернуть частичное значение (например, отчет о выполненных шагах).
+func (e *Executor) Do(ctx context.Context, name string, task Task) (*Result, error) {
+	job := e.Submit(name, task)
+	value, err := job.Wait(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := &Result{
+		JobID:         job.ID,
+		Value:         value,
+		QueuePosition: job.queuePosition,
+	}
+	if !job.startedAt.IsZero() {
+		result.Waited = job.startedAt.Sub(job.enqueuedAt)
+	}
+	return result, err
+}
+
+// Job возвращает задание по ID
+func (e *Executor) Job(id string) (*Job, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	job, ok := e.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job, nil
+}
+
+// Status возвращает текущее состояние очереди
+func (e *Executor) Status() Status {
+	e.mu.Lock()
+	running := e.running
+	queued := append([]*Job(nil), e.queue...)
+	e.mu.Unlock()
+
+	status := Status{Queued: make([]JobInfo, 0, len(queued))}
+	if running != nil {
+		info := running.Info()
+		status.Running = &info
+	}
+	for _, job := range queued {
+		status.Queued = append(status.Queued, job.Info())
+	}
+	return status
+}
+
+// CancelAll отменяет все ожидающие и выполняемое задания с причиной ErrStopped, продолжая принимать новые.
+// Возвращает выполняемое задание (nil, если рабочий стол свободен) и число отмененных заданий.
+func (e *Executor) CancelAll() (*Job, int) {
+	e.mu.Lock()
+	running := e.running
+	jobs := append([]*Job(nil), e.queue...)
+	e.mu.Unlock()
+
+	// Сначала снимаем ожидающие, чтобы ни одно из них не успело стартовать
+	// после остановки текущего
+	if running != nil {
+		jobs = append(jobs, running)
+	}
+	for _, job := range jobs {
+		job.cancel(ErrStopped)
+	}
+
+	e.logger.Warn("Все действия отменены", zap.Int("cancelled", len(jobs)))
+	return running, len(jobs)
+}
+
+// Shutdown прекращает прием новых заданий, отменяет ожидающие и выполняемое задания
+// и дожидается, пока выполняемое задание остановится (или истечет ctx).
+func (e *Executor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	running := e.running
+	queued := len(e.queue)
+	e.mu.Unlock()
+
+	e.logger.Info("Остановка очереди действий", zap.Int("queued", queued))
+	e.cancel()
+
+	if running == nil {
+		return nil
+	}
+	select {
+	case <-running.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("действие %s не остановилось: %w", running.Name, ctx.Err())
+	}
+}
+
+// process дожидается очереди задания, выполняет его и передает очередь следующему
+func (e *Executor) process(job *Job) {
+	defer job.cancel(nil)
+
+	if err := e.wait(job); err != nil {
+		state := StateCancelled
+		if errors.Is(err, ErrQueueTimeout) {
+			state = StateFailed
+		}
+		e.logger.Warn("Действие снято с очереди",
+			zap.String("job_id", job.ID),
+			zap.String("action", job.Name),
+			zap.Error(err))
+		e.finish(job, state, nil, err)
+		return
+	}
+
+	value, err := e.execute(job)
+	if err != nil {
+		e.runCleanup(job)
+	}
+
+	state := StateSucceeded
+	switch {
+	case err != nil && job.ctx.Err() != nil:
+		state = StateCancelled
+	case err != nil:
+		state = StateFailed
+	}
+	e.finish(job, state, value, err)
+}
+
+// execute выполняет задачу, превращая панику в ошибку
+func (e *Executor) execute(job *Job) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error("Паника при выполнении действия",
+				zap.String("job_id", job.ID),
+				zap.String("action", job.Name),
+				zap.Any("panic", r))
+			err = fmt.Errorf("паника при выполнении действия: %v", r)
+		}
+	}()
+
+	return job.task(job.ctx)
+}
+
+// runCleanup выполняет cleanup после неуспешного задания, не давая его панике остановить очередь
+func (e *Executor) runCleanup(job *Job) {
+	if e.cleanup == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error("Паника при очистке после действия",
+				zap.String("job_id", job.ID),
+				zap.Any("panic", r))
+		}
+	}()
+	e.cleanup()
+}
+
+// wait дожидается очереди задания с учетом отмены и maxWait
+func (e *Executor) wait(job *Job) error {
+	var timeout <-chan time.Time
+	if e.maxWait > 0 {
+		timer := time.NewTimer(e.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var cause error
+	select {
+	case <-job.granted:
+		return nil
+	case <-job.ctx.Done():
+		cause = fmt.Errorf("задание отменено до начала выполнения: %w", job.ctx.Err())
+	case <-timeout:
+		cause = fmt.Errorf("%w (%s)", ErrQueueTimeout, e.maxWait)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Очередь могла подойти одновременно с отменой — тогда выполняем задание
+	select {
+	case <-job.granted:
+		return nil
+	default:
+	}
+
+	for i, queued := range e.queue {
+		if queued == job {
+			e.queue = append(e.queue[:i], e.queue[i+1:]...)
+			break
+		}
+	}
+	return cause
+}
+
+// finish фиксирует результат задания и передает рабочий стол следующему в очереди
+func (e *Executor) finish(job *Job, state State, value any, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	job.state = state
+	job.result = value
+	job.err = err
+	job.finishedAt = time.Now()
+	e.finished = append(e.finished, job)
+	close(job.done)
+
+	if e.running == job {
+		e.running = nil
+		e.dispatchLocked()
+	}
+}
+
+// dispatchLocked запускает следующее задание, если рабочий стол свободен
+func (e *Executor) dispatchLocked() {
+	if e.running != nil || len(e.queue) == 0 {
+		return
+	}
+
+	next := e.queue[0]
+	e.queue = e.queue[1:]
+	next.state = StateRunning
+	next.startedAt = time.Now()
+	e.running = next
+	close(next.granted)
+}
+
+// positionLocked возвращает позицию задания в очереди (0 — выполняется или не в очереди)
+func (e *Executor) positionLocked(job *Job) int {
+	for i, queued := range e.queue {
+		if queued == job {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// cleanupLocked удаляет устаревшие завершенные задания
+func (e *Executor) cleanupLocked() {
+	cutoff := time.Now().Add(-jobRetention)
+	n := 0
+	for n < len(e.finished) && (e.finished[n].finishedAt.Before(cutoff) || len(e.finished)-n > maxFinishedJobs) {
+		delete(e.jobs, e.finished[n].ID)
+		n++
+	}
+	e.finished = e.finished[n:]
+}
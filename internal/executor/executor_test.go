@@ -0,0 +1,289 @@
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/executor"
+
+	"go.uber.org/zap"
+)
+
+// waitTimeout ограничение ожидания в тестах, чтобы зависшая очередь не блокировала прогон
+const waitTimeout = 5 * time.Second
+
+// blocker задача, которая занимает рабочий стол, пока не закроют release
+type blocker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlocker() *blocker {
+	return &blocker{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (b *blocker) task(ctx context.Context) (any, error) {
+	close(b.started)
+	select {
+	case <-b.release:
+		return "released", nil
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// submitBlocker ставит blocker в очередь и дожидается его запуска
+func submitBlocker(t *testing.T, e *executor.Executor) (*executor.Job, *blocker) {
+	t.Helper()
+	b := newBlocker()
+	job := e.Submit("blocker", b.task)
+	select {
+	case <-b.started:
+	case <-time.After(waitTimeout):
+		t.Fatal("blocker не запустился")
+	}
+	return job, b
+}
+
+func waitDone(t *testing.T, job *executor.Job) {
+	t.Helper()
+	select {
+	case <-job.Done():
+	case <-time.After(waitTimeout):
+		t.Fatalf("задание %s не завершилось", job.Name)
+	}
+}
+
+func TestFIFOOrder(t *testing.T) {
+	e := executor.New(zap.NewNop(), 0)
+	first, b := submitBlocker(t, e)
+
+	const n = 20
+	var (
+		mu      sync.Mutex
+		order   []int
+		running atomic.Int32
+		overlap atomic.Bool
+	)
+	jobs := make([]*executor.Job, n)
+	positions := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jobs[i] = e.Submit("job", func(ctx context.Context) (any, error) {
+				if running.Add(1) > 1 {
+					overlap.Store(true)
+				}
+				defer running.Add(-1)
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return nil, nil
+			})
+			// Пока blocker выполняется, очередь не двигается и позиция при постановке сохраняется
+			positions[i] = jobs[i].Info().QueuePosition
+		}()
+	}
+	wg.Wait()
+
+	// Задания выполняются в порядке постановки в очередь, то есть по возрастанию позиции
+	want := make([]int, n)
+	for i := range want {
+		want[i] = i
+	}
+	slices.SortFunc(want, func(a, b int) int { return positions[a] - positions[b] })
+	if sorted := slices.Sorted(slices.Values(positions)); sorted[0] != 1 || sorted[n-1] != n {
+		t.Fatalf("позиции в очереди %v", positions)
+	}
+
+	close(b.release)
+	waitDone(t, first)
+	for _, job := range jobs {
+		waitDone(t, job)
+	}
+	if !slices.Equal(order, want) {
+		t.Errorf("порядок выполнения %v, ожидался %v", order, want)
+	}
+	if overlap.Load() {
+		t.Error("задания выполнялись одновременно")
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	e := executor.New(zap.NewNop(), 20*time.Millisecond)
+	first, b := submitBlocker(t, e)
+
+	started := false
+	result, err := e.Do(context.Background(), "late", func(ctx context.Context) (any, error) {
+		started = true
+		return nil, nil
+	})
+	if !errors.Is(err, executor.ErrQueueTimeout) || started {
+		t.Fatalf("ошибка %v, задача запущена: %v", err, started)
+	}
+	job, err := e.Job(result.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State() != executor.StateFailed || result.QueuePosition != 1 {
+		t.Errorf("состояние %s, позиция %d", job.State(), result.QueuePosition)
+	}
+
+	// Снятое по таймауту задание не мешает выполняющемуся и следующим
+	close(b.release)
+	waitDone(t, first)
+	if first.State() != executor.StateSucceeded {
+		t.Errorf("blocker: %s", first.State())
+	}
+	if _, err := e.Do(context.Background(), "next", func(ctx context.Context) (any, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPanicBecomesError(t *testing.T) {
+	e := executor.New(zap.NewNop(), 0)
+	cleanups := 0
+	e.SetCleanup(func() { cleanups++ })
+
+	_, err := e.Do(context.Background(), "panic", func(ctx context.Context) (any, error) {
+		panic("сбой")
+	})
+	if err == nil || err.Error() != "паника при выполнении действия: сбой" {
+		t.Fatalf("ошибка %v", err)
+	}
+	if cleanups != 1 {
+		t.Errorf("cleanup вызван %d раз", cleanups)
+	}
+
+	// Очередь продолжает работать после паники
+	result, err := e.Do(context.Background(), "next", func(ctx context.Context) (any, error) { return 42, nil })
+	if err != nil || result.Value != 42 {
+		t.Fatalf("результат %+v, ошибка %v", result, err)
+	}
+}
+
+func TestCleanupOnlyAfterFailure(t *testing.T) {
+	errTask := errors.New("ошибка действия")
+	tests := []struct {
+		name string
+		task executor.Task
+		// cancel отменить ctx вызова до завершения задачи
+		cancel  bool
+		cleanup bool
+		state   executor.State
+	}{
+		{
+			name:  "успех",
+			task:  func(ctx context.Context) (any, error) { return nil, nil },
+			state: executor.StateSucceeded,
+		},
+		{
+			name:    "ошибка",
+			task:    func(ctx context.Context) (any, error) { return nil, errTask },
+			cleanup: true,
+			state:   executor.StateFailed,
+		},
+		{
+			name: "отмена",
+			task: func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			cancel:  true,
+			cleanup: true,
+			state:   executor.StateCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := executor.New(zap.NewNop(), 0)
+			cleaned := make(chan struct{}, 1)
+			e.SetCleanup(func() { cleaned <- struct{}{} })
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+			result, _ := e.Do(ctx, tt.name, tt.task)
+			job, err := e.Job(result.JobID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.State() != tt.state {
+				t.Errorf("состояние %s, ожидалось %s", job.State(), tt.state)
+			}
+			// cleanup выполняется до завершения задания, поэтому уже известен
+			if got := len(cleaned) == 1; got != tt.cleanup {
+				t.Errorf("cleanup вызван: %v, ожидалось %v", got, tt.cleanup)
+			}
+		})
+	}
+}
+
+func TestShutdownWaitsForRunningJob(t *testing.T) {
+	e := executor.New(zap.NewNop(), 0)
+
+	// Задание после отмены еще 50 мс возвращает приложение в исходное состояние
+	started := make(chan struct{})
+	var finished atomic.Bool
+	job := e.Submit("slow", func(ctx context.Context) (any, error) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil, ctx.Err()
+	})
+	<-started
+	queued := e.Submit("queued", func(ctx context.Context) (any, error) {
+		t.Error("ожидающее задание запущено после остановки")
+		return nil, nil
+	})
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !finished.Load() || job.State() != executor.StateCancelled {
+		t.Errorf("Shutdown вернулся до остановки задания: состояние %s", job.State())
+	}
+	waitDone(t, queued)
+	if queued.State() != executor.StateCancelled {
+		t.Errorf("ожидающее задание: %s", queued.State())
+	}
+
+	// После остановки новые задания не принимаются
+	_, err := e.Do(context.Background(), "late", func(ctx context.Context) (any, error) { return nil, nil })
+	if !errors.Is(err, executor.ErrShuttingDown) {
+		t.Errorf("ошибка %v, ожидалась ErrShuttingDown", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	e := executor.New(zap.NewNop(), 0)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	e.Submit("stuck", func(ctx context.Context) (any, error) {
+		// Задание не проверяет отмену
+		close(started)
+		<-release
+		return nil, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := e.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || err.Error() != "действие stuck не остановилось: context deadline exceeded" {
+		t.Errorf("ошибка %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// State состояние задания
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Finished сообщает, что задание завершено (успешно, с ошибкой или отменено)
+func (s State) Finished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCancelled
+}
+
+// Job действие, поставленное в очередь. Все изменяемые поля защищены мьютексом Executor.
+type Job struct {
+	ID   string
+	Name string
+
+	exec   *Executor
+	task   Task
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	state         State
+	queuePosition int
+	enqueuedAt    time.Time
+	startedAt     time.Time
+	finishedAt    time.Time
+	result        any
+	err           error
+
+	// granted закрывается, когда подходит очередь задания
+	granted chan struct{}
+	// done закрывается после завершения задания
+	done chan struct{}
+}
+
+// JobInfo снимок состояния задания для API
+type JobInfo struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	// QueuePosition текущая позиция в очереди (только для queued)
+	QueuePosition int        `json:"queue_position,omitempty"`
+	EnqueuedAt    time.Time  `json:"enqueued_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	WaitMs        int64      `json:"wait_ms"`
+	DurationMs    int64      `json:"duration_ms"`
+	Result        any        `json:"result,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// Done возвращает канал, закрывающийся после завершения задания
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait дожидается завершения задания. Если ctx отменен раньше, задание отменяется.
+func (j *Job) Wait(ctx context.Context) (any, error) {
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.Cancel()
+		<-j.done
+	}
+
+	j.exec.mu.Lock()
+	defer j.exec.mu.Unlock()
+	return j.result, j.err
+}
+
+// Cancel отменяет задание: ожидающее снимается с очереди, выполняемое получает отмену контекста
+func (j *Job) Cancel() {
+	j.cancel(nil)
+}
+
+// State возвращает текущее состояние задания
+func (j *Job) State() State {
+	j.exec.mu.Lock()
+	defer j.exec.mu.Unlock()
+	return j.state
+}
+
+// Info возвращает снимок состояния задания
+func (j *Job) Info() JobInfo {
+	e := j.exec
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info := JobInfo{
+		ID:         j.ID,
+		Name:       j.Name,
+		State:      j.state,
+		EnqueuedAt: j.enqueuedAt,
+		Result:     j.result,
+	}
+	if j.err != nil {
+		info.Error = j.err.Error()
+	}
+
+	now := time.Now()
+	switch {
+	case j.state == StateQueued:
+		info.QueuePosition = e.positionLocked(j)
+		info.WaitMs = now.Sub(j.enqueuedAt).Milliseconds()
+	case !j.startedAt.IsZero():
+		started := j.startedAt
+		info.StartedAt = &started
+		info.WaitMs = started.Sub(j.enqueuedAt).Milliseconds()
+		end := now
+		if !j.finishedAt.IsZero() {
+			end = j.finishedAt
+		}
+		info.DurationMs = end.Sub(started).Milliseconds()
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		info.FinishedAt = &finished
+	}
+
+	return info
+}
+
+func newJobID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,191 @@
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/executor"
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+func newService() (*input.Service, *fake.Backend) {
+	b := fake.New(fake.Options{Platform: "linux"})
+	return input.NewService(zap.NewNop(), b), b
+}
+
+func eventStrings(b *fake.Backend) []string {
+	var events []string
+	for _, e := range b.Events() {
+		events = append(events, e.String())
+	}
+	return events
+}
+
+func TestJobStates(t *testing.T) {
+	service, backend := newService()
+	e := executor.New(zap.NewNop(), 0)
+	first, b := submitBlocker(t, e)
+	if first.State() != executor.StateRunning {
+		t.Fatalf("выполняемое задание: %s", first.State())
+	}
+
+	errClick := errors.New("кнопка недоступна")
+	ok := e.Submit("move", func(ctx context.Context) (any, error) {
+		return "moved", service.MoveMouse(ctx, 10, 20)
+	})
+	failed := e.Submit("click", func(ctx context.Context) (any, error) {
+		return nil, errClick
+	})
+
+	info := failed.Info()
+	if info.State != executor.StateQueued || info.QueuePosition != 2 || info.StartedAt != nil {
+		t.Errorf("ожидающее задание: %+v", info)
+	}
+	if status := e.Status(); status.Running == nil || status.Running.ID != first.ID || len(status.Queued) != 2 {
+		t.Errorf("состояние очереди: %+v", status)
+	}
+
+	close(b.release)
+	waitDone(t, ok)
+	waitDone(t, failed)
+
+	info = ok.Info()
+	if info.State != executor.StateSucceeded || info.Result != "moved" || info.StartedAt == nil || info.FinishedAt == nil {
+		t.Errorf("успешное задание: %+v", info)
+	}
+	info = failed.Info()
+	if info.State != executor.StateFailed || info.Error != errClick.Error() {
+		t.Errorf("задание с ошибкой: %+v", info)
+	}
+	if events := eventStrings(backend); len(events) != 1 || events[0] != "move(10,20)" {
+		t.Errorf("события %v", events)
+	}
+	if status := e.Status(); status.Running != nil || len(status.Queued) != 0 {
+		t.Errorf("очередь не освободилась: %+v", status)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	service, backend := newService()
+	e := executor.New(zap.NewNop(), 0)
+	first, b := submitBlocker(t, e)
+
+	started := false
+	job := e.Submit("move", func(ctx context.Context) (any, error) {
+		started = true
+		return nil, service.MoveMouse(ctx, 10, 20)
+	})
+	job.Cancel()
+	waitDone(t, job)
+
+	// Отмененное в очереди задание снимается с очереди, не дожидаясь рабочего стола
+	if first.State() != executor.StateRunning {
+		t.Errorf("blocker: %s", first.State())
+	}
+	info := job.Info()
+	if info.State != executor.StateCancelled || !strings.Contains(info.Error, "задание отменено до начала выполнения") {
+		t.Errorf("задание %+v", info)
+	}
+
+	close(b.release)
+	waitDone(t, first)
+	if started || len(backend.Events()) != 0 {
+		t.Errorf("отмененное задание запущено: события %v", eventStrings(backend))
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	service, backend := newService()
+	e := executor.New(zap.NewNop(), 0)
+
+	started := make(chan struct{})
+	var cause error
+	job := e.Submit("move-click", func(ctx context.Context) (any, error) {
+		if err := service.MoveMouse(ctx, 10, 20); err != nil {
+			return nil, err
+		}
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		// Действия после отмены не выполняются
+		return nil, service.Click(ctx, "left")
+	})
+	<-started
+	job.Cancel()
+	waitDone(t, job)
+
+	if !errors.Is(cause, context.Canceled) {
+		t.Errorf("причина отмены %v", cause)
+	}
+	info := job.Info()
+	if info.State != executor.StateCancelled || !strings.Contains(info.Error, context.Canceled.Error()) {
+		t.Errorf("задание %+v", info)
+	}
+	if events := eventStrings(backend); len(events) != 1 || events[0] != "move(10,20)" {
+		t.Errorf("события %v", events)
+	}
+}
+
+func TestWaitCancelsJob(t *testing.T) {
+	e := executor.New(zap.NewNop(), 0)
+	first, b := submitBlocker(t, e)
+	defer close(b.release)
+
+	// Отмена ctx вызывающего (закрытое соединение) отменяет задание
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := e.Do(ctx, "move", func(ctx context.Context) (any, error) {
+		t.Error("задание запущено после отмены")
+		return nil, nil
+	})
+	if !errors.Is(err, context.Canceled) || result.QueuePosition != 1 {
+		t.Fatalf("результат %+v, ошибка %v", result, err)
+	}
+	if first.State() != executor.StateRunning {
+		t.Errorf("blocker: %s", first.State())
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	service, backend := newService()
+	e := executor.New(zap.NewNop(), 0)
+	first, _ := submitBlocker(t, e)
+
+	queued := make([]*executor.Job, 2)
+	for i := range queued {
+		queued[i] = e.Submit("move", func(ctx context.Context) (any, error) {
+			return nil, service.MoveMouse(ctx, 10, 20)
+		})
+	}
+
+	running, cancelled := e.CancelAll()
+	if running != first || cancelled != 3 {
+		t.Fatalf("выполняемое %v, отменено %d", running, cancelled)
+	}
+	waitDone(t, first)
+	for _, job := range append(queued, first) {
+		waitDone(t, job)
+		if job.State() != executor.StateCancelled {
+			t.Errorf("задание %s: %s", job.Name, job.State())
+		}
+	}
+	// Причина отмены выполняемого задания (context.Cause) — аварийная остановка
+	if _, err := first.Wait(context.Background()); !errors.Is(err, input.ErrStopped) {
+		t.Errorf("ошибка blocker %v, ожидалась ErrStopped", err)
+	}
+	if len(backend.Events()) != 0 {
+		t.Errorf("ожидающие задания запущены: %v", eventStrings(backend))
+	}
+
+	// После аварийной остановки очередь принимает новые задания
+	if _, err := e.Do(context.Background(), "move", func(ctx context.Context) (any, error) {
+		return nil, service.MoveMouse(ctx, 1, 2)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+package input
+
+import (
+	"image"
+	"time"
+)
+
+// Backend низкоуровневый драйвер ввода, через который Service управляет мышью и клавиатурой.
+// Реализации находятся в internal/backend/... (robotgo по умолчанию).
+type Backend interface {
+	// Name возвращает имя бэкенда (например, "robotgo")
+	Name() string
+
+	// MoveMouse перемещает курсор в абсолютные координаты экрана
+	MoveMouse(x, y int) error
+	// Click выполняет клик кнопкой мыши (left, right, center) на текущей позиции
+	Click(button string, double bool) error
+	// MouseToggle нажимает (down=true) или отпускает кнопку мыши
+	MouseToggle(button string, down bool) error
+	// Scroll прокручивает колесико мыши по горизонтали (x) и вертикали (y)
+	Scroll(x, y int) error
+	// MousePosition возвращает текущую позицию курсора
+	MousePosition() (int, int, error)
+
+	// KeyTap нажимает и отпускает клавишу с необязательными модификаторами
+	KeyTap(key string, modifiers ...string) error
+	// KeyToggle нажимает (down=true) или отпускает клавишу
+	KeyToggle(key string, down bool) error
+	// TypeRune вводит один символ
+	TypeRune(r rune) error
+
+	// ReadClipboard читает текст из буфера обмена
+	ReadClipboard() (string, error)
+	// WriteClipboard записывает текст в буфер обмена
+	WriteClipboard(text string) error
+
+	// ScreenSize возвращает размер основного экрана
+	ScreenSize() (int, int, error)
+}
+
+// ScreenCapturer необязательный интерфейс бэкенда для снятия скриншотов
+type ScreenCapturer interface {
+	// CaptureScreen снимает область экрана. Пустой прямоугольник означает весь экран.
+	CaptureScreen(rect image.Rectangle) (image.Image, error)
+}
+
+// DisplayLister необязательный интерфейс бэкенда, различающего мониторы
+type DisplayLister interface {
+	// Displays возвращает границы мониторов в координатах экрана, первым — основной
+	Displays() ([]image.Rectangle, error)
+}
+
+// Sleeper необязательный интерфейс бэкенда, управляющего временем.
+// Service выполняет все задержки через него (например, виртуальные часы в тестах).
+type Sleeper interface {
+	Sleep(d time.Duration)
+}
+
+// Platformer необязательный интерфейс бэкенда, сообщающего платформу (windows, darwin, linux),
+// под которую Service подбирает задержки и способ ввода. По умолчанию используется runtime.GOOS.
+type Platformer interface {
+	Platform() string
+}
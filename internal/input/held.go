@@ -0,0 +1,125 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// HeldInputs клавиши и кнопки мыши, зажатые сервисом и еще не отпущенные
+type HeldInputs struct {
+	Keys    []string `json:"keys"`
+	Buttons []string `json:"buttons"`
+}
+
+// heldState учет зажатых клавиш и кнопок мыши
+type heldState struct {
+	mu      sync.Mutex
+	keys    map[string]struct{}
+	buttons map[string]struct{}
+}
+
+func (h *heldState) set(m *map[string]struct{}, name string, down bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if down {
+		if *m == nil {
+			*m = make(map[string]struct{})
+		}
+		(*m)[name] = struct{}{}
+		return
+	}
+	delete(*m, name)
+}
+
+func (h *heldState) snapshot() HeldInputs {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HeldInputs{
+		Keys:    sortedNames(h.keys),
+		Buttons: sortedNames(h.buttons),
+	}
+}
+
+func sortedNames(m map[string]struct{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// toggleKey нажимает или отпускает клавишу через бэкенд и учитывает ее состояние.
+// Клавиша, которую не удалось отпустить, остается в списке зажатых.
+func (s *Service) toggleKey(key string, down bool) error {
+	if err := s.backend.KeyToggle(key, down); err != nil {
+		return err
+	}
+	s.held.set(&s.held.keys, key, down)
+	return nil
+}
+
+// toggleButton нажимает или отпускает кнопку мыши через бэкенд и учитывает ее состояние
+func (s *Service) toggleButton(button string, down bool) error {
+	if err := s.backend.MouseToggle(button, down); err != nil {
+		return err
+	}
+	s.held.set(&s.held.buttons, button, down)
+	return nil
+}
+
+// MouseToggle удерживает или отпускает кнопку мыши
+func (s *Service) MouseToggle(ctx context.Context, button string, down bool) error {
+	s.logger.Info("Изменение состояния кнопки мыши",
+		zap.String("button", button),
+		zap.Bool("down", down))
+
+	// Отпускание выполняем даже при отмененном ctx, чтобы кнопка не осталась зажатой
+	if down {
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.toggleButton(button, down); err != nil {
+		return fmt.Errorf("ошибка изменения состояния кнопки мыши %s: %w", button, err)
+	}
+	return nil
+}
+
+// Held возвращает клавиши и кнопки мыши, которые сервис зажал и еще не отпустил
+func (s *Service) Held() HeldInputs {
+	return s.held.snapshot()
+}
+
+// ReleaseAll отпускает все клавиши и кнопки мыши, зажатые сервисом.
+// Выполняется без проверки ctx и guard: отпускание нужно именно после ошибки или отмены.
+func (s *Service) ReleaseAll() error {
+	held := s.held.snapshot()
+	if len(held.Keys) == 0 && len(held.Buttons) == 0 {
+		return nil
+	}
+
+	s.logger.Info("Отпускание зажатых клавиш и кнопок мыши",
+		zap.Strings("keys", held.Keys),
+		zap.Strings("buttons", held.Buttons))
+
+	var errs []error
+	for _, key := range held.Keys {
+		if err := s.toggleKey(key, false); err != nil {
+			errs = append(errs, fmt.Errorf("ошибка отпускания %s: %w", key, err))
+		}
+	}
+	for _, button := range held.Buttons {
+		if err := s.toggleButton(button, false); err != nil {
+			errs = append(errs, fmt.Errorf("ошибка отпускания кнопки мыши %s: %w", button, err))
+		}
+	}
+	return errors.Join(errs...)
+}
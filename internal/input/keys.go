@@ -0,0 +1,45 @@
+package input
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyNames имена клавиш, которые поддерживают все бэкенды (имена robotgo)
+var keyNames = map[string]bool{
+	"enter": true, "return": true, "tab": true, "space": true,
+	"backspace": true, "delete": true, "escape": true, "esc": true,
+	"up": true, "down": true, "left": true, "right": true,
+	"home": true, "end": true, "pageup": true, "pagedown": true,
+	"insert": true, "capslock": true, "printscreen": true, "menu": true,
+}
+
+// modifierNames имена клавиш-модификаторов
+var modifierNames = map[string]bool{
+	"ctrl": true, "control": true, "lctrl": true, "rctrl": true,
+	"shift": true, "lshift": true, "rshift": true,
+	"alt": true, "lalt": true, "ralt": true,
+	"cmd": true, "command": true, "lcmd": true, "rcmd": true,
+}
+
+// IsKnownKey сообщает, что имя клавиши поддерживается: именованная клавиша, модификатор,
+// функциональная клавиша f1-f24 или одиночный символ
+func IsKnownKey(key string) bool {
+	if utf8.RuneCountInString(key) == 1 {
+		return true
+	}
+	lower := strings.ToLower(key)
+	if keyNames[lower] || modifierNames[lower] {
+		return true
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "f")); err == nil && strings.HasPrefix(lower, "f") {
+		return n >= 1 && n <= 24
+	}
+	return false
+}
+
+// IsModifier сообщает, что клавиша является модификатором (ctrl, shift, alt, cmd)
+func IsModifier(key string) bool {
+	return modifierNames[strings.ToLower(key)]
+}
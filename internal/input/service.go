@@ -1,68 +1,197 @@
 package input
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image"
 	"runtime"
 	"time"
 
-	"github.com/go-vgo/robotgo"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrCaptureNotSupported возвращается, если бэкенд не умеет снимать скриншоты
+	ErrCaptureNotSupported = errors.New("бэкенд ввода не поддерживает снятие скриншотов")
+	// ErrStopped причина отмены действий аварийной остановкой (context.Cause контекста действия).
+	// Объявлена здесь, чтобы ее проверяли и очередь заданий, и выполнение последовательностей.
+	ErrStopped = errors.New("аварийная остановка")
+)
+
+// TypingInterruptedError возвращается, если ввод текста прерван до завершения
+type TypingInterruptedError struct {
+	Typed int
+	Total int
+	Err   error
+}
+
+func (e *TypingInterruptedError) Error() string {
+	return fmt.Sprintf("введено %d из %d символов: %v", e.Typed, e.Total, e.Err)
+}
+
+func (e *TypingInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// StepError возвращается многошаговыми операциями и указывает, на каком шаге они остановились
+type StepError struct {
+	Step  int
+	Total int
+	Name  string
+	Err   error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("шаг %d из %d (%s): %v", e.Step, e.Total, e.Name, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Guard блокирует ввод, например после срабатывания аварийного останова
+type Guard interface {
+	// Check возвращает ошибку, если ввод запрещен
+	Check() error
+}
+
 type Service struct {
-	logger *zap.Logger
+	logger  *zap.Logger
+	backend Backend
+	// os платформа, под которую подбираются задержки и способы ввода
+	os string
+	// guard дополнительная проверка перед каждым действием (может быть nil)
+	guard Guard
+	// held клавиши и кнопки мыши, зажатые сервисом
+	held heldState
 }
 
-func NewService(logger *zap.Logger) *Service {
+func NewService(logger *zap.Logger, backend Backend) *Service {
+	goos := runtime.GOOS
+	if p, ok := backend.(Platformer); ok && p.Platform() != "" {
+		goos = p.Platform()
+	}
+
 	return &Service{
-		logger: logger,
+		logger:  logger,
+		backend: backend,
+		os:      goos,
+	}
+}
+
+// sleep выполняет задержку через бэкенд, если он управляет временем (виртуальные часы),
+// иначе через таймер. Возвращает ошибку, если ctx отменен до или во время задержки.
+func (s *Service) sleep(ctx context.Context, d time.Duration) error {
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+
+	if sleeper, ok := s.backend.(Sleeper); ok {
+		sleeper.Sleep(d)
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("действие прервано: %w", ctx.Err())
+	}
+}
+
+// Sleep выполняет паузу между действиями с учетом отмены ctx и guard
+func (s *Service) Sleep(ctx context.Context, d time.Duration) error {
+	return s.sleep(ctx, d)
+}
+
+// checkContext возвращает ошибку, если ctx отменен или ввод заблокирован guard
+func (s *Service) checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("действие прервано: %w", err)
+	}
+	if s.guard != nil {
+		if err := s.guard.Check(); err != nil {
+			return fmt.Errorf("действие прервано: %w", err)
+		}
 	}
+	return nil
+}
+
+// SetGuard устанавливает проверку, которая выполняется перед каждым нажатием и задержкой.
+// Вызывается до начала обработки запросов.
+func (s *Service) SetGuard(guard Guard) {
+	s.guard = guard
+}
+
+// Backend возвращает используемый бэкенд ввода
+func (s *Service) Backend() Backend {
+	return s.backend
+}
+
+// Platform возвращает платформу, под которую подбираются задержки и способы ввода
+func (s *Service) Platform() string {
+	return s.os
 }
 
 // MoveMouse перемещает мышь на указанные координаты
-func (s *Service) MoveMouse(x, y int) error {
+func (s *Service) MoveMouse(ctx context.Context, x, y int) error {
 	s.logger.Info("Перемещение мыши", zap.Int("x", x), zap.Int("y", y))
-	robotgo.MoveMouse(x, y)
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.MoveMouse(x, y); err != nil {
+		return fmt.Errorf("ошибка перемещения мыши: %w", err)
+	}
 	return nil
 }
 
 // Click выполняет клик мышью на текущей позиции
-func (s *Service) Click(button string) error {
+func (s *Service) Click(ctx context.Context, button string) error {
 	s.logger.Info("Клик мышью", zap.String("button", button))
 	
 	switch button {
-	case "left":
-		robotgo.Click("left")
-	case "right":
-		robotgo.Click("right")
+	case "left", "right":
 	case "center", "middle":
-		robotgo.Click("center")
+		button = "center"
 	default:
-		robotgo.Click("left")
+		button = "left"
 	}
 	
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.Click(button, false); err != nil {
+		return fmt.Errorf("ошибка клика: %w", err)
+	}
 	return nil
 }
 
 // ClickAt выполняет клик мышью на указанных координатах
-func (s *Service) ClickAt(x, y int, button string) error {
+func (s *Service) ClickAt(ctx context.Context, x, y int, button string) error {
 	s.logger.Info("Клик мышью по координатам", 
 		zap.Int("x", x), 
 		zap.Int("y", y), 
 		zap.String("button", button))
 	
-	robotgo.MoveMouse(x, y)
-	time.Sleep(50 * time.Millisecond) // Небольшая задержка перед кликом
+	if err := s.MoveMouse(ctx, x, y); err != nil {
+		return err
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil { // Небольшая задержка перед кликом
+		return err
+	}
 	
-	return s.Click(button)
+	return s.Click(ctx, button)
 }
 
 // TypeText вводит текст
-func (s *Service) TypeText(text string, delayMs int) error {
+func (s *Service) TypeText(ctx context.Context, text string, delayMs int) error {
 	s.logger.Info("Ввод текста", 
 		zap.String("text", text), 
 		zap.Int("delay_ms", delayMs),
-		zap.String("os", runtime.GOOS),
+		zap.String("os", s.os),
 		zap.Int("text_length", len(text)))
 	
 	if text == "" {
@@ -72,7 +201,7 @@ func (s *Service) TypeText(text string, delayMs int) error {
 	
 	// На Windows рекомендуется использовать задержку между символами
 	if delayMs <= 0 {
-		if runtime.GOOS == "windows" {
+		if s.os == "windows" {
 			delayMs = 30 // Увеличена задержка для Windows
 		} else {
 			delayMs = 10 // Минимальная задержка для других ОС
@@ -80,7 +209,7 @@ func (s *Service) TypeText(text string, delayMs int) error {
 	}
 	
 	// На Windows используем посимвольный ввод через клавиатуру (для модальных окон)
-	if runtime.GOOS == "windows" {
+	if s.os == "windows" {
 		s.logger.Info("Windows: начинаем посимвольный ввод текста через клавиатуру", 
 			zap.String("method", "UnicodeType"),
 			zap.String("text", text),
@@ -88,7 +217,7 @@ func (s *Service) TypeText(text string, delayMs int) error {
 			zap.Int("delay_ms", delayMs))
 		
 		// Используем посимвольный ввод для Windows (работает в модальных окнах)
-		if err := s.typeTextCharByChar(text, delayMs); err != nil {
+		if err := s.typeTextCharByChar(ctx, text, delayMs); err != nil {
 			s.logger.Error("Ошибка при посимвольном вводе на Windows", zap.Error(err))
 			return err
 		}
@@ -97,7 +226,7 @@ func (s *Service) TypeText(text string, delayMs int) error {
 	}
 	
 	// На macOS используем посимвольный ввод (без буфера обмена)
-	if runtime.GOOS == "darwin" {
+	if s.os == "darwin" {
 		s.logger.Info("macOS: начинаем посимвольный ввод текста", 
 			zap.String("method", "CharByChar"),
 			zap.String("text", text),
@@ -105,7 +234,7 @@ func (s *Service) TypeText(text string, delayMs int) error {
 			zap.Int("delay_ms", delayMs))
 		
 		// Используем посимвольный ввод для macOS
-		if err := s.typeTextCharByChar(text, delayMs); err != nil {
+		if err := s.typeTextCharByChar(ctx, text, delayMs); err != nil {
 			s.logger.Error("Ошибка при посимвольном вводе на macOS", zap.Error(err))
 			return err
 		}
@@ -113,19 +242,32 @@ func (s *Service) TypeText(text string, delayMs int) error {
 		return nil
 	}
 	
-	// Для Linux используем стандартный метод
-	robotgo.TypeStr(text, delayMs)
-	s.logger.Info("Текст введен через TypeStr", zap.String("text", text))
+	// На Linux вводим посимвольно, чтобы отмена и fail-safe останавливали ввод между символами
+	runes := []rune(text)
+	for i, char := range runes {
+		if err := s.checkContext(ctx); err != nil {
+			return &TypingInterruptedError{Typed: i, Total: len(runes), Err: err}
+		}
+		if err := s.backend.TypeRune(char); err != nil {
+			return fmt.Errorf("ошибка ввода символа %q: %w", char, err)
+		}
+		if i < len(runes)-1 {
+			if err := s.sleep(ctx, time.Duration(delayMs)*time.Millisecond); err != nil {
+				return &TypingInterruptedError{Typed: i + 1, Total: len(runes), Err: err}
+			}
+		}
+	}
+	s.logger.Info("Текст введен через TypeRune", zap.String("text", text))
 	
 	return nil
 }
 
 // typeTextViaClipboard вводит текст через буфер обмена (Ctrl+V)
-func (s *Service) typeTextViaClipboard(text string) error {
+func (s *Service) typeTextViaClipboard(ctx context.Context, text string) error {
 	s.logger.Debug("Начало ввода через буфер обмена", zap.String("text", text))
 	
 	// Сохраняем текущий буфер обмена
-	oldClip, err := robotgo.ReadAll()
+	oldClip, err := s.backend.ReadClipboard()
 	if err != nil {
 		s.logger.Debug("Не удалось прочитать буфер обмена (не критично)", zap.Error(err))
 	} else {
@@ -134,28 +276,37 @@ func (s *Service) typeTextViaClipboard(text string) error {
 	
 	// Копируем текст в буфер обмена
 	s.logger.Debug("Копирование текста в буфер обмена")
-	if err := robotgo.WriteAll(text); err != nil {
+	if err := s.backend.WriteClipboard(text); err != nil {
 		s.logger.Error("Ошибка записи в буфер обмена", zap.Error(err))
 		return fmt.Errorf("ошибка записи в буфер обмена: %w", err)
 	}
 	s.logger.Debug("Текст скопирован в буфер обмена")
 	
-	time.Sleep(50 * time.Millisecond)
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
 	
 	// Вставляем через Ctrl+V
 	s.logger.Debug("Вставка через Ctrl+V")
-	robotgo.KeyToggle("ctrl", "down")
-	time.Sleep(20 * time.Millisecond)
-	robotgo.KeyTap("v")
-	time.Sleep(20 * time.Millisecond)
-	robotgo.KeyToggle("ctrl", "up")
-	time.Sleep(50 * time.Millisecond)
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.KeyTap("v", "ctrl"); err != nil {
+		return fmt.Errorf("ошибка вставки из буфера обмена: %w", err)
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
 	s.logger.Debug("Вставка выполнена")
 	
 	// Восстанавливаем старый буфер обмена (если был)
 	if oldClip != "" {
-		time.Sleep(100 * time.Millisecond)
-		robotgo.WriteAll(oldClip)
+		if err := s.sleep(ctx, 100*time.Millisecond); err != nil {
+			return err
+		}
+		if err := s.backend.WriteClipboard(oldClip); err != nil {
+			s.logger.Debug("Не удалось восстановить буфер обмена (не критично)", zap.Error(err))
+		}
 		s.logger.Debug("Буфер обмена восстановлен")
 	}
 	
@@ -163,125 +314,187 @@ func (s *Service) typeTextViaClipboard(text string) error {
 }
 
 // typeTextCharByChar вводит текст посимвольно (более надежно на Windows и macOS)
-func (s *Service) typeTextCharByChar(text string, delayMs int) error {
+func (s *Service) typeTextCharByChar(ctx context.Context, text string, delayMs int) error {
 	s.logger.Debug("Ввод текста посимвольно", 
 		zap.Int("length", len(text)), 
 		zap.Int("delay_ms", delayMs),
-		zap.String("os", runtime.GOOS))
+		zap.String("os", s.os))
 	
 	// Убеждаемся, что есть достаточная задержка для стабильного ввода
 	if delayMs <= 0 {
 		delayMs = 30
 	}
-	if runtime.GOOS == "windows" && delayMs < 50 {
+	if s.os == "windows" && delayMs < 50 {
 		delayMs = 50 // Минимум 50мс для Windows (модальные окна требуют больше времени)
 	}
 	
-	for i, char := range text {
+	runes := []rune(text)
+	for i, char := range runes {
+		// Проверяем отмену перед каждым символом, чтобы остановиться между нажатиями
+		if err := s.checkContext(ctx); err != nil {
+			return &TypingInterruptedError{Typed: i, Total: len(runes), Err: err}
+		}
+
 		charStr := string(char)
 		
 		// Специальная обработка для некоторых символов
+		var err error
 		if char == '\n' {
-			robotgo.KeyTap("enter")
+			err = s.backend.KeyTap("enter")
 			s.logger.Debug("Введен символ: Enter")
 		} else if char == '\t' {
-			robotgo.KeyTap("tab")
+			err = s.backend.KeyTap("tab")
 			s.logger.Debug("Введен символ: Tab")
 		} else if char == ' ' {
-			robotgo.KeyTap("space")
+			err = s.backend.KeyTap("space")
 			s.logger.Debug("Введен символ: Space")
 		} else {
-			// Для Windows используем Unicode события напрямую (работает в модальных окнах)
-			if runtime.GOOS == "windows" {
-				// Используем UnicodeType для прямого ввода символов через события клавиатуры
-				// Это работает в модальных окнах, где TypeStr может не работать
-				// Преобразуем rune в uint32 для UnicodeType
-				robotgo.UnicodeType(uint32(char))
-				time.Sleep(30 * time.Millisecond) // Задержка после каждого символа для стабильности
-				s.logger.Debug("Введен символ через UnicodeType", zap.String("char", charStr), zap.Int("unicode", int(char)), zap.Int("position", i+1), zap.Int("total", len(text)))
-			} else if runtime.GOOS == "darwin" {
-				// Используем TypeStr с небольшой задержкой между символами
-				robotgo.TypeStr(charStr, 10) // Задержка 10мс для каждого символа
-				s.logger.Debug("Введен символ", zap.String("char", charStr), zap.Int("position", i+1), zap.Int("total", len(text)))
-			} else {
-				// Для других ОС используем TypeStr
-				robotgo.TypeStr(charStr, 0)
-				s.logger.Debug("Введен символ", zap.String("char", charStr), zap.Int("position", i+1), zap.Int("total", len(text)))
+			// Бэкенд сам выбирает способ ввода символа (на Windows robotgo использует
+			// Unicode события напрямую, что работает в модальных окнах)
+			err = s.backend.TypeRune(char)
+			if err == nil && s.os == "windows" {
+				// Задержка после каждого символа для стабильности
+				if sleepErr := s.sleep(ctx, 30*time.Millisecond); sleepErr != nil {
+					return &TypingInterruptedError{Typed: i + 1, Total: len(runes), Err: sleepErr}
+				}
 			}
+			s.logger.Debug("Введен символ", zap.String("char", charStr), zap.Int("unicode", int(char)), zap.Int("position", i+1), zap.Int("total", len(runes)))
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка ввода символа %q: %w", char, err)
 		}
 		
 		// Задержка между символами
 		// Для Windows и macOS делаем задержку даже после последнего символа для надежности
-		if i < len(text)-1 {
-			time.Sleep(time.Duration(delayMs) * time.Millisecond)
+		delay := time.Duration(0)
+		if i < len(runes)-1 {
+			delay = time.Duration(delayMs) * time.Millisecond
 		} else {
 			// Дополнительная задержка после последнего символа
-			if runtime.GOOS == "darwin" {
-				time.Sleep(100 * time.Millisecond)
-			} else if runtime.GOOS == "windows" {
-				time.Sleep(150 * time.Millisecond) // Задержка для Windows после последнего символа
+			if s.os == "darwin" {
+				delay = 100 * time.Millisecond
+			} else if s.os == "windows" {
+				delay = 150 * time.Millisecond // Задержка для Windows после последнего символа
+			}
+		}
+		if delay > 0 {
+			if err := s.sleep(ctx, delay); err != nil {
+				return &TypingInterruptedError{Typed: i + 1, Total: len(runes), Err: err}
 			}
 		}
 	}
 	
-	s.logger.Info("Текст введен посимвольно", zap.String("text", text), zap.Int("chars_count", len(text)))
+	s.logger.Info("Текст введен посимвольно", zap.String("text", text), zap.Int("chars_count", len(runes)))
 	return nil
 }
 
 // TypeTextAt вводит текст после клика на указанных координатах
-func (s *Service) TypeTextAt(x, y int, text string, delayMs int) error {
+func (s *Service) TypeTextAt(ctx context.Context, x, y int, text string, delayMs int) error {
 	s.logger.Info("Ввод текста по координатам", 
 		zap.Int("x", x), 
 		zap.Int("y", y), 
 		zap.String("text", text), 
 		zap.Int("delay_ms", delayMs),
-		zap.String("os", runtime.GOOS))
+		zap.String("os", s.os))
 	
 	// Перемещаем мышь на координаты
-	robotgo.MoveMouse(x, y)
-	time.Sleep(50 * time.Millisecond)
+	if err := s.MoveMouse(ctx, x, y); err != nil {
+		return err
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
 	
-	if runtime.GOOS == "windows" {
+	if s.os == "windows" {
 		// На Windows используем тройной клик для гарантии фокуса
 		s.logger.Debug("Тройной клик для установки фокуса на Windows")
-		robotgo.MouseClick("left", false) // первый клик
-		// time.Sleep(100 * time.Millisecond)
-		// robotgo.MouseClick("left", false) // второй клик
-		// time.Sleep(100 * time.Millisecond)
-		// robotgo.MouseClick("left", false) // третий клик (выделяет весь текст)
-		// time.Sleep(200 * time.Millisecond)
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.Click("left", false); err != nil { // первый клик
+			return fmt.Errorf("ошибка клика: %w", err)
+		}
+		// s.sleep(100 * time.Millisecond)
+		// s.backend.Click("left", false) // второй клик
+		// s.sleep(100 * time.Millisecond)
+		// s.backend.Click("left", false) // третий клик (выделяет весь текст)
+		// s.sleep(200 * time.Millisecond)
 		
 		// Очищаем выделенный текст
-		robotgo.KeyTap("delete")
-		time.Sleep(20 * time.Millisecond)
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.KeyTap("delete"); err != nil {
+			return fmt.Errorf("ошибка нажатия клавиши: %w", err)
+		}
+		if err := s.sleep(ctx, 20*time.Millisecond); err != nil {
+			return err
+		}
 		
 		// Дополнительная задержка для гарантии фокуса
-		time.Sleep(30 * time.Millisecond)
+		if err := s.sleep(ctx, 30*time.Millisecond); err != nil {
+			return err
+		}
 		s.logger.Debug("Фокус установлен, готовы к вводу")
-	} else if runtime.GOOS == "darwin" {
+	} else if s.os == "darwin" {
 		// На macOS используем двойной клик и задержку
 		s.logger.Debug("Двойной клик для установки фокуса на macOS")
-		robotgo.MouseClick("left", false) // первый клик
-		time.Sleep(15 * time.Millisecond)
-		robotgo.MouseClick("left", false) // второй клик (выделяет текст в поле)
-		time.Sleep(20 * time.Millisecond)
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.Click("left", false); err != nil { // первый клик
+			return fmt.Errorf("ошибка клика: %w", err)
+		}
+		if err := s.sleep(ctx, 15*time.Millisecond); err != nil {
+			return err
+		}
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.Click("left", false); err != nil { // второй клик (выделяет текст в поле)
+			return fmt.Errorf("ошибка клика: %w", err)
+		}
+		if err := s.sleep(ctx, 20*time.Millisecond); err != nil {
+			return err
+		}
 		
 		// Очищаем выделенный текст (если был выделен)
-		robotgo.KeyTap("delete")
-		time.Sleep(150 * time.Millisecond)
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.KeyTap("delete"); err != nil {
+			return fmt.Errorf("ошибка нажатия клавиши: %w", err)
+		}
+		if err := s.sleep(ctx, 150*time.Millisecond); err != nil {
+			return err
+		}
 		
 		s.logger.Debug("Фокус установлен на macOS, готовы к вводу")
 	} else {
 		// Для Linux используем двойной клик
 		s.logger.Debug("Двойной клик для установки фокуса")
-		robotgo.MouseClick("left", false) // первый клик
-		time.Sleep(100 * time.Millisecond)
-		robotgo.MouseClick("left", false) // второй клик
-		time.Sleep(100 * time.Millisecond)
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.Click("left", false); err != nil { // первый клик
+			return fmt.Errorf("ошибка клика: %w", err)
+		}
+		if err := s.sleep(ctx, 100*time.Millisecond); err != nil {
+			return err
+		}
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+		if err := s.backend.Click("left", false); err != nil { // второй клик
+			return fmt.Errorf("ошибка клика: %w", err)
+		}
+		if err := s.sleep(ctx, 100*time.Millisecond); err != nil {
+			return err
+		}
 	}
 	
 	// Вводим текст
-	if err := s.TypeText(text, delayMs); err != nil {
+	if err := s.TypeText(ctx, text, delayMs); err != nil {
 		return fmt.Errorf("ошибка ввода текста: %w", err)
 	}
 	
@@ -289,25 +502,97 @@ func (s *Service) TypeTextAt(x, y int, text string, delayMs int) error {
 }
 
 // GetMousePosition возвращает текущую позицию мыши
-func (s *Service) GetMousePosition() (int, int) {
+func (s *Service) GetMousePosition(ctx context.Context) (int, int, error) {
 	// Задержка 3 секунды перед определением позиции мыши
 	s.logger.Debug("Ожидание 3 секунды перед определением позиции мыши")
-	time.Sleep(3 * time.Second)
+	if err := s.sleep(ctx, 3*time.Second); err != nil {
+		return 0, 0, err
+	}
 	
-	x, y := robotgo.GetMousePos()
+	x, y, err := s.backend.MousePosition()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка определения позиции мыши: %w", err)
+	}
 	s.logger.Debug("Текущая позиция мыши", zap.Int("x", x), zap.Int("y", y))
-	return x, y
+	return x, y, nil
+}
+
+// MousePosition возвращает текущую позицию курсора без задержки (в отличие от GetMousePosition)
+func (s *Service) MousePosition(ctx context.Context) (int, int, error) {
+	if err := s.checkContext(ctx); err != nil {
+		return 0, 0, err
+	}
+	x, y, err := s.backend.MousePosition()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка определения позиции мыши: %w", err)
+	}
+	return x, y, nil
 }
 
-// KeyTap нажимает клавишу
-func (s *Service) KeyTap(key string) error {
-	s.logger.Info("Нажатие клавиши", zap.String("key", key))
-	robotgo.KeyTap(key)
+// ReadClipboard возвращает текст из буфера обмена
+func (s *Service) ReadClipboard(ctx context.Context) (string, error) {
+	if err := s.checkContext(ctx); err != nil {
+		return "", err
+	}
+	text, err := s.backend.ReadClipboard()
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения буфера обмена: %w", err)
+	}
+	return text, nil
+}
+
+// CaptureScreen снимает скриншот области экрана (пустой прямоугольник — весь экран)
+func (s *Service) CaptureScreen(ctx context.Context, rect image.Rectangle) (image.Image, error) {
+	capturer, ok := s.backend.(ScreenCapturer)
+	if !ok {
+		return nil, ErrCaptureNotSupported
+	}
+
+	s.logger.Debug("Снятие скриншота", zap.String("rect", rect.String()))
+	if err := s.checkContext(ctx); err != nil {
+		return nil, err
+	}
+	img, err := capturer.CaptureScreen(rect)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка снятия скриншота: %w", err)
+	}
+	return img, nil
+}
+
+// Displays возвращает границы мониторов. Если бэкенд не различает мониторы,
+// весь экран считается одним монитором.
+func (s *Service) Displays() ([]image.Rectangle, error) {
+	if lister, ok := s.backend.(DisplayLister); ok {
+		displays, err := lister.Displays()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка мониторов: %w", err)
+		}
+		if len(displays) > 0 {
+			return displays, nil
+		}
+	}
+
+	width, height, err := s.backend.ScreenSize()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения размера экрана: %w", err)
+	}
+	return []image.Rectangle{image.Rect(0, 0, width, height)}, nil
+}
+
+// KeyTap нажимает клавишу, при необходимости вместе с модификаторами (ctrl, shift, alt, cmd)
+func (s *Service) KeyTap(ctx context.Context, key string, modifiers ...string) error {
+	s.logger.Info("Нажатие клавиши", zap.String("key", key), zap.Strings("modifiers", modifiers))
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.KeyTap(key, modifiers...); err != nil {
+		return fmt.Errorf("ошибка нажатия клавиши %s: %w", key, err)
+	}
 	return nil
 }
 
 // KeyToggle удерживает или отпускает клавишу
-func (s *Service) KeyToggle(key string, down bool) error {
+func (s *Service) KeyToggle(ctx context.Context, key string, down bool) error {
 	action := "отпускание"
 	if down {
 		action = "удержание"
@@ -316,66 +601,97 @@ func (s *Service) KeyToggle(key string, down bool) error {
 		zap.String("key", key), 
 		zap.String("action", action))
 	
+	// Отпускание выполняем даже при отмененном ctx, чтобы клавиша не осталась зажатой
 	if down {
-		robotgo.KeyToggle(key, "down", []string{})
-	} else {
-		robotgo.KeyToggle(key, "up", []string{})
+		if err := s.checkContext(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.toggleKey(key, down); err != nil {
+		return fmt.Errorf("ошибка изменения состояния клавиши %s: %w", key, err)
 	}
 	
 	return nil
 }
 
 // Scroll прокручивает колесико мыши
-func (s *Service) Scroll(x, y int) error {
+func (s *Service) Scroll(ctx context.Context, x, y int) error {
 	s.logger.Info("Прокрутка мыши", zap.Int("x", x), zap.Int("y", y))
-	robotgo.Scroll(x, y)
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.Scroll(x, y); err != nil {
+		return fmt.Errorf("ошибка прокрутки: %w", err)
+	}
 	return nil
 }
 
 // ClearInput очищает поле ввода (выделяет все и удаляет)
-func (s *Service) ClearInput() error {
-	s.logger.Info("Очистка поля ввода", zap.String("os", runtime.GOOS))
-	
-	// Используем кроссплатформенный подход - всегда используем комбинацию клавиш для выделения всего
-	if runtime.GOOS == "windows" {
-		// На Windows используем Ctrl+A для выделения всего текста
-		s.logger.Debug("Выделение всего текста: Ctrl+A")
-		robotgo.KeyToggle("ctrl", "down")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyTap("a")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyToggle("ctrl", "up")
-		time.Sleep(50 * time.Millisecond)
-	} else if runtime.GOOS == "darwin" {
-		// На macOS используем Cmd+A для выделения всего текста
-		s.logger.Debug("Выделение всего текста: Cmd+A")
-		robotgo.KeyToggle("command", "down")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyTap("a")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyToggle("command", "up")
-		time.Sleep(50 * time.Millisecond)
-	} else {
-		// Linux и другие ОС - используем Ctrl+A
-		s.logger.Debug("Выделение всего текста: Ctrl+A")
-		robotgo.KeyToggle("ctrl", "down")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyTap("a")
-		time.Sleep(30 * time.Millisecond)
-		robotgo.KeyToggle("ctrl", "up")
-		time.Sleep(50 * time.Millisecond)
+func (s *Service) ClearInput(ctx context.Context) error {
+	s.logger.Info("Очистка поля ввода", zap.String("os", s.os))
+	
+	// Используем кроссплатформенный подход - всегда используем комбинацию клавиш для выделения всего:
+	// Cmd+A на macOS, Ctrl+A на Windows, Linux и других ОС
+	modifier := "ctrl"
+	if s.os == "darwin" {
+		modifier = "command"
+	}
+	s.logger.Debug("Выделение всего текста", zap.String("modifier", modifier))
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.toggleKey(modifier, true); err != nil {
+		return fmt.Errorf("ошибка нажатия %s: %w", modifier, err)
+	}
+	// Модификатор отпускаем при любом исходе: ошибке, отмене или панике,
+	// иначе все последующие нажатия превратятся в сочетания клавиш
+	released := false
+	release := func() error {
+		if released {
+			return nil
+		}
+		released = true
+		return s.toggleKey(modifier, false)
+	}
+	defer release()
+
+	if err := s.sleep(ctx, 30*time.Millisecond); err != nil {
+		return err
+	}
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	tapErr := s.backend.KeyTap("a")
+	if err := s.sleep(ctx, 30*time.Millisecond); err != nil {
+		return err
+	}
+	if err := release(); err != nil {
+		return fmt.Errorf("ошибка отпускания %s: %w", modifier, err)
+	}
+	if tapErr != nil {
+		return fmt.Errorf("ошибка выделения текста: %w", tapErr)
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return err
 	}
 	
 	// Удаляем выделенный текст
 	s.logger.Debug("Удаление выделенного текста")
-	robotgo.KeyTap("delete")
-	time.Sleep(50 * time.Millisecond)
+	if err := s.checkContext(ctx); err != nil {
+		return err
+	}
+	if err := s.backend.KeyTap("delete"); err != nil {
+		return fmt.Errorf("ошибка удаления текста: %w", err)
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return err
+	}
 	
 	return nil
 }
 
 // InputAtCoordinates полный цикл: клик по координатам и ввод текста
-func (s *Service) InputAtCoordinates(x, y int, text string, options *InputOptions) error {
+func (s *Service) InputAtCoordinates(ctx context.Context, x, y int, text string, options *InputOptions) error {
 	if options == nil {
 		options = &InputOptions{
 			ClearBeforeInput: true,
@@ -390,61 +706,79 @@ func (s *Service) InputAtCoordinates(x, y int, text string, options *InputOption
 		zap.String("text", text),
 		zap.Bool("clear_before", options.ClearBeforeInput))
 	
-	// Перемещаем мышь на координаты
-	robotgo.MoveMouse(x, y)
-	time.Sleep(50 * time.Millisecond)
+	// Шаг 1: Перемещаем мышь на координаты
+	if err := s.MoveMouse(ctx, x, y); err != nil {
+		return &StepError{Step: 1, Total: 4, Name: "перемещение мыши", Err: err}
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return &StepError{Step: 1, Total: 4, Name: "перемещение мыши", Err: err}
+	}
 	
-	// Устанавливаем фокус на поле ввода (один клик)
-	s.logger.Debug("Клик для установки фокуса", zap.String("os", runtime.GOOS))
-	robotgo.MouseClick("left", false)
+	// Шаг 2: Устанавливаем фокус на поле ввода (один клик)
+	s.logger.Debug("Клик для установки фокуса", zap.String("os", s.os))
+	if err := s.checkContext(ctx); err != nil {
+		return &StepError{Step: 2, Total: 4, Name: "установка фокуса", Err: err}
+	}
+	if err := s.backend.Click("left", false); err != nil {
+		err = fmt.Errorf("ошибка клика для установки фокуса: %w", err)
+		return &StepError{Step: 2, Total: 4, Name: "установка фокуса", Err: err}
+	}
 	
 	// Задержка для установки фокуса (увеличена для macOS)
 	focusDelay := 300 * time.Millisecond // Увеличена базовая задержка
-	if runtime.GOOS == "windows" {
+	if s.os == "windows" {
 		focusDelay = 300 * time.Millisecond
-	} else if runtime.GOOS == "darwin" {
+	} else if s.os == "darwin" {
 		focusDelay = 400 * time.Millisecond // Больше задержка на macOS
 	}
-	time.Sleep(focusDelay)
+	if err := s.sleep(ctx, focusDelay); err != nil {
+		return &StepError{Step: 2, Total: 4, Name: "установка фокуса", Err: err}
+	}
 	s.logger.Debug("Фокус установлен")
 	
-	// Очищаем поле если нужно (использует Cmd+A/Ctrl+A для выделения всего)
+	// Шаг 3: Очищаем поле если нужно (использует Cmd+A/Ctrl+A для выделения всего)
 	if options.ClearBeforeInput {
 		s.logger.Debug("Очистка поля перед вводом")
-		if err := s.ClearInput(); err != nil {
-			return fmt.Errorf("ошибка очистки: %w", err)
+		if err := s.ClearInput(ctx); err != nil {
+			err = fmt.Errorf("ошибка очистки: %w", err)
+			return &StepError{Step: 3, Total: 4, Name: "очистка поля", Err: err}
 		}
 		// Задержка после очистки (увеличена для надежности, особенно для macOS)
 		clearDelay := 200 * time.Millisecond
-		if runtime.GOOS == "windows" {
+		if s.os == "windows" {
 			clearDelay = 300 * time.Millisecond // Больше задержка на Windows
-		} else if runtime.GOOS == "darwin" {
+		} else if s.os == "darwin" {
 			clearDelay = 400 * time.Millisecond // Еще больше задержка на macOS
 		}
-		time.Sleep(clearDelay)
+		if err := s.sleep(ctx, clearDelay); err != nil {
+			return &StepError{Step: 3, Total: 4, Name: "очистка поля", Err: err}
+		}
 		s.logger.Debug("Поле очищено, готовы к вводу")
 	}
 	
 	// Дополнительная задержка перед вводом текста для гарантии фокуса (увеличена для macOS)
 	preTypeDelay := 100 * time.Millisecond
-	if runtime.GOOS == "windows" {
+	if s.os == "windows" {
 		preTypeDelay = 200 * time.Millisecond
-	} else if runtime.GOOS == "darwin" {
+	} else if s.os == "darwin" {
 		preTypeDelay = 300 * time.Millisecond // Больше задержка на macOS перед вводом
 	}
-	time.Sleep(preTypeDelay)
+	if err := s.sleep(ctx, preTypeDelay); err != nil {
+		return &StepError{Step: 4, Total: 4, Name: "ввод текста", Err: err}
+	}
 	s.logger.Debug("Начинаем ввод текста")
 	
-	// Вводим текст
-	if err := s.TypeText(text, options.TypeDelay); err != nil {
-		return fmt.Errorf("ошибка ввода текста: %w", err)
+	// Шаг 4: Вводим текст
+	if err := s.TypeText(ctx, text, options.TypeDelay); err != nil {
+		err = fmt.Errorf("ошибка ввода текста: %w", err)
+		return &StepError{Step: 4, Total: 4, Name: "ввод текста", Err: err}
 	}
 	
 	return nil
 }
 
 // FillInputAndClickButton выполняет полный цикл: наведение на инпут, очистка, ввод текста, клик по кнопке
-func (s *Service) FillInputAndClickButton(inputX, inputY int, text string, buttonX, buttonY int, button string, options *InputOptions) error {
+func (s *Service) FillInputAndClickButton(ctx context.Context, inputX, inputY int, text string, buttonX, buttonY int, button string, options *InputOptions) error {
 	if options == nil {
 		options = &InputOptions{
 			ClearBeforeInput: true,
@@ -466,52 +800,77 @@ func (s *Service) FillInputAndClickButton(inputX, inputY int, text string, butto
 		zap.String("button", button))
 
 	// Шаг 1: Наводим мышь на инпут
-	robotgo.MoveMouse(inputX, inputY)
-	time.Sleep(50 * time.Millisecond)
+	if err := s.MoveMouse(ctx, inputX, inputY); err != nil {
+		return &StepError{Step: 1, Total: 6, Name: "наведение на инпут", Err: err}
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return &StepError{Step: 1, Total: 6, Name: "наведение на инпут", Err: err}
+	}
 
 	// Шаг 2: Устанавливаем фокус на поле ввода (клик)
-	s.logger.Debug("Клик для установки фокуса на инпут", zap.String("os", runtime.GOOS))
-	robotgo.MouseClick("left", false)
+	s.logger.Debug("Клик для установки фокуса на инпут", zap.String("os", s.os))
+	if err := s.checkContext(ctx); err != nil {
+		return &StepError{Step: 2, Total: 6, Name: "установка фокуса", Err: err}
+	}
+	if err := s.backend.Click("left", false); err != nil {
+		err = fmt.Errorf("ошибка клика для установки фокуса: %w", err)
+		return &StepError{Step: 2, Total: 6, Name: "установка фокуса", Err: err}
+	}
 
 	// Задержка для установки фокуса (увеличена для стабильности)
 	focusDelay := 150 * time.Millisecond
-	if runtime.GOOS == "windows" {
+	if s.os == "windows" {
 		focusDelay = 200 * time.Millisecond // Модальные окна требуют больше времени
 	}
-	time.Sleep(focusDelay)
+	if err := s.sleep(ctx, focusDelay); err != nil {
+		return &StepError{Step: 2, Total: 6, Name: "установка фокуса", Err: err}
+	}
 	s.logger.Debug("Фокус установлен на инпут")
 
 	// Шаг 3: Очищаем поле если нужно
 	if options.ClearBeforeInput {
 		s.logger.Debug("Очистка поля перед вводом")
-		if err := s.ClearInput(); err != nil {
-			return fmt.Errorf("ошибка очистки: %w", err)
+		if err := s.ClearInput(ctx); err != nil {
+			err = fmt.Errorf("ошибка очистки: %w", err)
+			return &StepError{Step: 3, Total: 6, Name: "очистка поля", Err: err}
+		}
+		if err := s.sleep(ctx, 100*time.Millisecond); err != nil { // Даем время на обработку
+			return &StepError{Step: 3, Total: 6, Name: "очистка поля", Err: err}
 		}
-		time.Sleep(100 * time.Millisecond) // Даем время на обработку
 		s.logger.Debug("Поле очищено, готовы к вводу")
 	}
 
 	// Задержка перед вводом текста (увеличена для стабильности)
-	time.Sleep(150 * time.Millisecond)
+	if err := s.sleep(ctx, 150*time.Millisecond); err != nil {
+		return &StepError{Step: 4, Total: 6, Name: "ввод текста", Err: err}
+	}
 	s.logger.Debug("Начинаем ввод текста")
 
 	// Шаг 4: Вводим текст
-	if err := s.TypeText(text, options.TypeDelay); err != nil {
-		return fmt.Errorf("ошибка ввода текста: %w", err)
+	if err := s.TypeText(ctx, text, options.TypeDelay); err != nil {
+		err = fmt.Errorf("ошибка ввода текста: %w", err)
+		return &StepError{Step: 4, Total: 6, Name: "ввод текста", Err: err}
 	}
 
 	// Задержка после ввода текста перед переходом к кнопке
-	time.Sleep(100 * time.Millisecond)
+	if err := s.sleep(ctx, 100*time.Millisecond); err != nil {
+		return &StepError{Step: 4, Total: 6, Name: "ввод текста", Err: err}
+	}
 
 	// Шаг 5: Наводим мышь на кнопку
 	s.logger.Debug("Перемещение мыши на кнопку")
-	robotgo.MoveMouse(buttonX, buttonY)
-	time.Sleep(50 * time.Millisecond)
+	if err := s.MoveMouse(ctx, buttonX, buttonY); err != nil {
+		return &StepError{Step: 5, Total: 6, Name: "наведение на кнопку", Err: err}
+	}
+	if err := s.sleep(ctx, 50*time.Millisecond); err != nil {
+		return &StepError{Step: 5, Total: 6, Name: "наведение на кнопку", Err: err}
+	}
 
 	// Шаг 6: Кликаем по кнопке
 	s.logger.Debug("Клик по кнопке", zap.String("button", button))
-	if err := s.Click(button); err != nil {
-		return fmt.Errorf("ошибка клика по кнопке: %w", err)
+	if err := s.Click(ctx, button); err != nil {
+		err = fmt.Errorf("ошибка клика по кнопке: %w", err)
+		return &StepError{Step: 6, Total: 6, Name: "клик по кнопке", Err: err}
 	}
 
 	s.logger.Info("✅ Заполнение инпута и клик по кнопке выполнены успешно")
@@ -0,0 +1,371 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+// newService создает сервис на fake бэкенде с платформой platform
+func newService(platform string) (*input.Service, *fake.Backend) {
+	b := fake.New(fake.Options{Platform: platform})
+	return input.NewService(zap.NewNop(), b), b
+}
+
+// timeline возвращает события в виде "время_мс событие"
+func timeline(events []fake.Event) []string {
+	lines := make([]string, len(events))
+	for i, e := range events {
+		lines[i] = fmt.Sprintf("%d %s", e.AtMs, e)
+	}
+	return lines
+}
+
+func assertTimeline(t *testing.T, b *fake.Backend, want []string) {
+	t.Helper()
+	got := timeline(b.Events())
+	if !slices.Equal(got, want) {
+		t.Errorf("события:\n%q\nожидались:\n%q", got, want)
+	}
+}
+
+func TestInputAtCoordinates(t *testing.T) {
+	tests := []struct {
+		platform string
+		options  *input.InputOptions
+		want     []string
+	}{
+		{
+			platform: "linux",
+			options:  &input.InputOptions{ClearBeforeInput: true, TypeDelay: 30},
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"350 key_down(ctrl)",
+				"380 key_tap(a)",
+				"410 key_up(ctrl)",
+				"460 key_tap(delete)",
+				"810 type(\"a\")",
+				"840 type(\"b\")",
+			},
+		},
+		{
+			platform: "linux",
+			options:  &input.InputOptions{TypeDelay: 30},
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"450 type(\"a\")",
+				"480 type(\"b\")",
+			},
+		},
+		{
+			platform: "windows",
+			options:  &input.InputOptions{ClearBeforeInput: true, TypeDelay: 30},
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"350 key_down(ctrl)",
+				"380 key_tap(a)",
+				"410 key_up(ctrl)",
+				"460 key_tap(delete)",
+				// Очистка 300 мс, перед вводом 200 мс; после символа 30 мс и между символами не меньше 50 мс
+				"1010 type(\"a\")",
+				"1090 type(\"b\")",
+			},
+		},
+		{
+			platform: "darwin",
+			options:  &input.InputOptions{ClearBeforeInput: true, TypeDelay: 30},
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"450 key_down(command)",
+				"480 key_tap(a)",
+				"510 key_up(command)",
+				"560 key_tap(delete)",
+				"1310 type(\"a\")",
+				"1340 type(\"b\")",
+			},
+		},
+		{
+			platform: "darwin",
+			options:  &input.InputOptions{TypeDelay: 30},
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"750 type(\"a\")",
+				"780 type(\"b\")",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/clear=%v", tt.platform, tt.options.ClearBeforeInput), func(t *testing.T) {
+			s, b := newService(tt.platform)
+			if err := s.InputAtCoordinates(context.Background(), 100, 200, "ab", tt.options); err != nil {
+				t.Fatal(err)
+			}
+			assertTimeline(t, b, tt.want)
+		})
+	}
+}
+
+func TestFillInputAndClickButton(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     []string
+		// total общее виртуальное время действия
+		total int64
+	}{
+		{
+			platform: "linux",
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"200 key_down(ctrl)",
+				"230 key_tap(a)",
+				"260 key_up(ctrl)",
+				"310 key_tap(delete)",
+				"610 type(\"a\")",
+				"640 type(\"b\")",
+				"740 move(300,400)",
+				"790 click(right)",
+			},
+			total: 790,
+		},
+		{
+			platform: "windows",
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"250 key_down(ctrl)",
+				"280 key_tap(a)",
+				"310 key_up(ctrl)",
+				"360 key_tap(delete)",
+				"660 type(\"a\")",
+				"740 type(\"b\")",
+				"1020 move(300,400)",
+				"1070 click(right)",
+			},
+			total: 1070,
+		},
+		{
+			platform: "darwin",
+			want: []string{
+				"0 move(100,200)",
+				"50 click(left)",
+				"200 key_down(command)",
+				"230 key_tap(a)",
+				"260 key_up(command)",
+				"310 key_tap(delete)",
+				"610 type(\"a\")",
+				"640 type(\"b\")",
+				"840 move(300,400)",
+				"890 click(right)",
+			},
+			total: 890,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			s, b := newService(tt.platform)
+			options := &input.InputOptions{ClearBeforeInput: true, TypeDelay: 30}
+			if err := s.FillInputAndClickButton(context.Background(), 100, 200, "ab", 300, 400, "right", options); err != nil {
+				t.Fatal(err)
+			}
+			assertTimeline(t, b, tt.want)
+			if got := b.Now().Milliseconds(); got != tt.total {
+				t.Errorf("виртуальное время %d мс, ожидалось %d мс", got, tt.total)
+			}
+		})
+	}
+}
+
+func TestTypeTextSpecialCharacters(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     []string
+	}{
+		{
+			platform: "linux",
+			want:     []string{"0 type(\"a\")", "10 type(\" \")", "20 type(\"\\n\")"},
+		},
+		{
+			platform: "darwin",
+			// Пробел и перевод строки на macOS нажимаются клавишами
+			want: []string{"0 type(\"a\")", "10 key_tap(space)", "20 key_tap(enter)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			s, b := newService(tt.platform)
+			if err := s.TypeText(context.Background(), "a \n", 0); err != nil {
+				t.Fatal(err)
+			}
+			assertTimeline(t, b, tt.want)
+		})
+	}
+}
+
+// cancelOnSleep fake бэкенд, который отменяет ctx на n-й задержке: отмена приходит
+// после проверки ctx в sleep, но до следующего нажатия
+type cancelOnSleep struct {
+	*fake.Backend
+	n      int
+	sleeps int
+	cancel context.CancelFunc
+	// recorded число событий в момент отмены
+	recorded int
+}
+
+func (b *cancelOnSleep) Sleep(d time.Duration) {
+	b.Backend.Sleep(d)
+	b.sleeps++
+	if b.sleeps == b.n {
+		b.recorded = len(b.Events())
+		b.cancel()
+	}
+}
+
+func TestCancelBetweenSleepAndAction(t *testing.T) {
+	actions := map[string]func(ctx context.Context, s *input.Service) error{
+		"input": func(ctx context.Context, s *input.Service) error {
+			return s.InputAtCoordinates(ctx, 100, 200, "ab", &input.InputOptions{ClearBeforeInput: true})
+		},
+		"fill_and_click": func(ctx context.Context, s *input.Service) error {
+			return s.FillInputAndClickButton(ctx, 100, 200, "ab", 300, 400, "left", &input.InputOptions{ClearBeforeInput: true})
+		},
+		"type_at": func(ctx context.Context, s *input.Service) error {
+			return s.TypeTextAt(ctx, 100, 200, "ab", 0)
+		},
+	}
+
+	for name, action := range actions {
+		for _, platform := range []string{"linux", "windows", "darwin"} {
+			t.Run(name+"/"+platform, func(t *testing.T) {
+				for n := 1; ; n++ {
+					ctx, cancel := context.WithCancel(context.Background())
+					b := &cancelOnSleep{Backend: fake.New(fake.Options{Platform: platform}), n: n, cancel: cancel}
+					err := action(ctx, input.NewService(zap.NewNop(), b))
+					cancel()
+					if b.sleeps < n {
+						// Действие завершилось раньше n-й задержки: все точки отмены проверены
+						if err != nil {
+							t.Fatal(err)
+						}
+						return
+					}
+					// Отмена на последней задержке (после последнего нажатия) не прерывает действие
+					if err != nil && !errors.Is(err, context.Canceled) {
+						t.Fatalf("отмена на задержке %d: ошибка %v, ожидалась context.Canceled", n, err)
+					}
+					// Отпускание зажатого модификатора допускается и после отмены
+					var extra []string
+					for _, e := range b.Events()[b.recorded:] {
+						if e.Type != fake.EventKeyUp {
+							extra = append(extra, e.String())
+						}
+					}
+					if len(extra) > 0 {
+						t.Fatalf("отмена на задержке %d: после отмены выполнены события %v", n, extra)
+					}
+				}
+			})
+		}
+	}
+}
+
+// errBlocked ошибка guard в тестах
+var errBlocked = errors.New("ввод заблокирован")
+
+// tripAfter guard, блокирующий ввод после n событий бэкенда (как fail-safe,
+// сработавший посреди ввода)
+type tripAfter struct {
+	b *fake.Backend
+	n int
+}
+
+func (g tripAfter) Check() error {
+	if len(g.b.Events()) >= g.n {
+		return errBlocked
+	}
+	return nil
+}
+
+func TestTypeTextLinuxCancellable(t *testing.T) {
+	tests := []struct {
+		name string
+		// tripAt число событий, после которого guard блокирует ввод (0 — не блокирует)
+		tripAt int
+		want   []string
+		// typed сколько символов введено до остановки (-1 — ввод не прерван)
+		typed int
+	}{
+		{
+			name:  "без остановки",
+			want:  []string{"0 type(\"a\")", "30 type(\"b\")", "60 type(\"c\")"},
+			typed: -1,
+		},
+		{
+			name:   "fail-safe посреди ввода",
+			tripAt: 2,
+			want:   []string{"0 type(\"a\")", "30 type(\"b\")"},
+			typed:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Как в рабочем режиме: ctx задания отменяемый, fail-safe подключен
+			s, b := newService("linux")
+			guard := tripAfter{b: b, n: tt.tripAt}
+			if tt.tripAt == 0 {
+				guard.n = 1 << 30
+			}
+			s.SetGuard(guard)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err := s.TypeText(ctx, "abc", 30)
+			assertTimeline(t, b, tt.want)
+			if tt.typed < 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var interrupted *input.TypingInterruptedError
+			if !errors.As(err, &interrupted) || interrupted.Typed != tt.typed || interrupted.Total != 3 || !errors.Is(err, errBlocked) {
+				t.Fatalf("ошибка %v, ожидалась остановка после %d символов", err, tt.typed)
+			}
+		})
+	}
+}
+
+func TestTypeTextLinuxCancel(t *testing.T) {
+	b := &cancelOnSleep{Backend: fake.New(fake.Options{Platform: "linux"}), n: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.cancel = cancel
+	s := input.NewService(zap.NewNop(), b)
+	s.SetGuard(tripAfter{b: b.Backend, n: 1 << 30})
+
+	// Отмена во время паузы после первого символа останавливает ввод
+	err := s.TypeText(ctx, "abc", 30)
+	var interrupted *input.TypingInterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Typed != 1 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("ошибка %v", err)
+	}
+	assertTimeline(t, b.Backend, []string{"0 type(\"a\")"})
+}
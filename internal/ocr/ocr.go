@@ -0,0 +1,133 @@
+// Package ocr распознает текст в области экрана. Движок распознавания подключается
+// пакетом ocr/tesseract (требует cgo и библиотеки tesseract); без него распознавание
+// возвращает ErrNotSupported.
+package ocr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrNotSupported возвращается, если в сборке нет движка распознавания текста
+var ErrNotSupported = errors.New("распознавание текста не поддерживается в этой сборке (нужны cgo и tesseract)")
+
+// languagePattern имя языковой модели tesseract (rus, kaz, eng, chi_sim)
+var languagePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+const (
+	// psmAutoOnly режим сегментации tesseract без распознавания текста (0 тоже, но 0 означает значение по умолчанию)
+	psmAutoOnly = 2
+	psmMax      = 13
+)
+
+// Options параметры распознавания. Нулевые значения заменяются значениями по умолчанию (WithDefaults).
+type Options struct {
+	// Languages языки через "+", например rus+kaz+eng
+	Languages string `json:"languages,omitempty" yaml:"languages,omitempty" form:"languages"`
+	// PSM режим сегментации страницы tesseract: 3 — автоматически, 6 — блок текста,
+	// 7 — одна строка, 8 — одно слово, 11 — разрозненный текст
+	PSM int `json:"psm,omitempty" yaml:"psm,omitempty" form:"psm"`
+}
+
+// WithDefaults возвращает параметры, в которых незаданные поля взяты из defaults
+func (o Options) WithDefaults(defaults Options) Options {
+	if o.Languages == "" {
+		o.Languages = defaults.Languages
+	}
+	if o.PSM == 0 {
+		o.PSM = defaults.PSM
+	}
+	return o
+}
+
+// Validate проверяет параметры распознавания
+func (o Options) Validate() error {
+	if o.Languages != "" {
+		for _, lang := range languageList(o.Languages) {
+			if !languagePattern.MatchString(lang) {
+				return fmt.Errorf("недопустимый язык %q", lang)
+			}
+		}
+	}
+	if o.PSM < 0 || o.PSM > psmMax || o.PSM == psmAutoOnly {
+		return fmt.Errorf("psm должен быть от 1 до %d, кроме %d (без распознавания)", psmMax, psmAutoOnly)
+	}
+	return nil
+}
+
+// LanguageList возвращает языки по отдельности. Кроме "+" допускаются запятые и пробелы:
+// в query-параметре "+" превращается в пробел.
+func (o Options) LanguageList() []string {
+	return languageList(o.Languages)
+}
+
+func languageList(languages string) []string {
+	return strings.FieldsFunc(languages, func(r rune) bool {
+		return r == '+' || r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// Word распознанное слово
+type Word struct {
+	Text string `json:"text"`
+	// Confidence уверенность распознавания от 0 до 1
+	Confidence float64 `json:"confidence"`
+	// X, Y, Width, Height область слова в координатах экрана
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Result результат распознавания
+type Result struct {
+	// Text распознанный текст с переводами строк
+	Text string `json:"text"`
+	// Confidence средняя уверенность распознавания слов от 0 до 1
+	Confidence float64 `json:"confidence"`
+	Words      []Word  `json:"words"`
+}
+
+// Engine движок распознавания текста на изображении. Координаты слов возвращаются
+// относительно левого верхнего угла изображения.
+type Engine interface {
+	Recognize(img image.Image, opts Options) (*Result, error)
+}
+
+var (
+	mu     sync.RWMutex
+	engine Engine
+)
+
+// RegisterEngine подключает движок распознавания. Вызывается из init() пакета с реализацией.
+func RegisterEngine(e Engine) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if engine != nil {
+		panic("ocr: движок распознавания уже зарегистрирован")
+	}
+	engine = e
+}
+
+// currentEngine возвращает подключенный движок или nil
+func currentEngine() Engine {
+	mu.RLock()
+	defer mu.RUnlock()
+	return engine
+}
+
+// ContainsText сообщает, содержится ли substr в распознанном тексте. Регистр и пробелы
+// (в том числе переводы строк) не учитываются: OCR часто разбивает строки иначе, чем на экране.
+func ContainsText(text, substr string) bool {
+	return strings.Contains(normalize(text), normalize(substr))
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
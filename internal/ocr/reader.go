@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"context"
+	"image"
+	"math"
+
+	"goszakup-automation/internal/input"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// upscale во сколько раз увеличивается небольшая область перед распознаванием:
+	// tesseract рассчитан на текст высотой 20-30 пикселей, а экранный шрифт мельче
+	upscale = 2
+	// upscaleMaxPixels площадь области, больше которой увеличение не выполняется
+	upscaleMaxPixels = 1 << 20
+)
+
+// Reader распознает текст в областях экрана service
+type Reader struct {
+	service *input.Service
+	// defaults параметры, не указанные в запросе или шаге
+	defaults Options
+}
+
+// NewReader создает распознавание текста на экране service с параметрами по умолчанию defaults
+func NewReader(service *input.Service, defaults Options) *Reader {
+	return &Reader{service: service, defaults: defaults}
+}
+
+// Defaults возвращает параметры распознавания по умолчанию
+func (r *Reader) Defaults() Options {
+	return r.defaults
+}
+
+// ReadText распознает текст в области region (пустая — весь экран). Незаданные параметры opts
+// берутся по умолчанию. Координаты слов возвращаются в координатах экрана.
+func (r *Reader) ReadText(ctx context.Context, region image.Rectangle, opts Options) (*Result, error) {
+	e := currentEngine()
+	if e == nil {
+		return nil, ErrNotSupported
+	}
+	opts = opts.WithDefaults(r.defaults)
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	img, err := r.service.CaptureScreen(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scale := 1
+	if b := img.Bounds(); b.Dx()*b.Dy() <= upscaleMaxPixels {
+		scale = upscale
+		out := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+		draw.CatmullRom.Scale(out, out.Bounds(), img, b, draw.Src, nil)
+		img = out
+	}
+
+	result, err := e.Recognize(img, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := region.Min
+	if region.Empty() {
+		origin = image.Point{}
+	}
+	var sum float64
+	for i := range result.Words {
+		w := &result.Words[i]
+		w.X = origin.X + w.X/scale
+		w.Y = origin.Y + w.Y/scale
+		w.Width = int(math.Ceil(float64(w.Width) / float64(scale)))
+		w.Height = int(math.Ceil(float64(w.Height) / float64(scale)))
+		sum += w.Confidence
+	}
+	if len(result.Words) > 0 {
+		result.Confidence = math.Round(sum/float64(len(result.Words))*1000) / 1000
+	}
+	return result, nil
+}
@@ -0,0 +1,4 @@
+// Package tesseract подключает распознавание текста через github.com/otiai10/gosseract/v2.
+// Требует cgo и библиотеки tesseract и leptonica; при CGO_ENABLED=0 или теге notesseract
+// пакет пуст и распознавание текста недоступно.
+package tesseract
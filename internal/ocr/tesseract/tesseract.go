@@ -0,0 +1,73 @@
+//go:build cgo && !notesseract
+
+package tesseract
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/otiai10/gosseract/v2"
+
+	"goszakup-automation/internal/ocr"
+)
+
+func init() {
+	ocr.RegisterEngine(engine{})
+}
+
+// engine распознавание через tesseract. Клиент создается на каждый вызов:
+// клиент gosseract нельзя использовать из нескольких горутин.
+type engine struct{}
+
+func (engine) Recognize(img image.Image, opts ocr.Options) (*ocr.Result, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("ошибка кодирования изображения для OCR: %w", err)
+	}
+
+	client := gosseract.NewClient()
+	defer client.Close()
+
+	if langs := opts.LanguageList(); len(langs) > 0 {
+		if err := client.SetLanguage(langs...); err != nil {
+			return nil, fmt.Errorf("ошибка выбора языков OCR: %w", err)
+		}
+	}
+	if opts.PSM != 0 {
+		if err := client.SetPageSegMode(gosseract.PageSegMode(opts.PSM)); err != nil {
+			return nil, fmt.Errorf("ошибка выбора режима сегментации: %w", err)
+		}
+	}
+	if err := client.SetImageFromBytes(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("ошибка передачи изображения в tesseract: %w", err)
+	}
+
+	text, err := client.Text()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распознавания текста: %w", err)
+	}
+	boxes, err := client.GetBoundingBoxes(gosseract.RIL_WORD)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения слов: %w", err)
+	}
+
+	words := make([]ocr.Word, 0, len(boxes))
+	for _, box := range boxes {
+		if strings.TrimSpace(box.Word) == "" {
+			continue
+		}
+		words = append(words, ocr.Word{
+			Text:       box.Word,
+			Confidence: math.Round(box.Confidence*10) / 1000,
+			X:          box.Box.Min.X,
+			Y:          box.Box.Min.Y,
+			Width:      box.Box.Dx(),
+			Height:     box.Box.Dy(),
+		})
+	}
+	return &ocr.Result{Text: strings.TrimSpace(text), Words: words}, nil
+}
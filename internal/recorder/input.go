@@ -0,0 +1,304 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+)
+
+// SourceAPI источник записи по умолчанию: действия, выполненные через API
+const SourceAPI = "api"
+
+// EventKind тип события физического ввода
+type EventKind string
+
+const (
+	EventKeyDown    EventKind = "key_down"
+	EventKeyUp      EventKind = "key_up"
+	EventButtonDown EventKind = "button_down"
+	EventButtonUp   EventKind = "button_up"
+	EventMotion     EventKind = "motion"
+	EventScroll     EventKind = "scroll"
+)
+
+// InputEvent событие мыши или клавиатуры, перехваченное источником ввода
+type InputEvent struct {
+	Kind EventKind
+	At   time.Time
+	// Key имя клавиши в нотации robotgo без учета Shift и раскладки ("a", "enter", "ctrl")
+	Key string
+	// Rune введенный символ с учетом Shift, CapsLock и раскладки (0 — клавиша не вводит символ)
+	Rune rune
+	// Button кнопка мыши: left, right, center
+	Button string
+	// X, Y позиция курсора
+	X, Y int
+	// DX, DY шаг прокрутки (положительный DY — вверх, положительный DX — вправо)
+	DX, DY int
+}
+
+// Source источник событий физических мыши и клавиатуры
+type Source interface {
+	// Capture передает события в emit, пока не отменен ctx, и освобождает ресурсы источника
+	Capture(ctx context.Context, emit func(InputEvent)) error
+}
+
+// ErrSourceUnavailable возвращается, если не удалось подключиться к источнику ввода
+var ErrSourceUnavailable = errors.New("источник записи недоступен")
+
+// SourceFactory подключается к источнику ввода. display — адрес X-сервера (пусто — DISPLAY).
+type SourceFactory func(display string) (Source, error)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]SourceFactory{}
+)
+
+// RegisterSource регистрирует источник ввода под указанным именем.
+// Вызывается из init() пакетов с реализациями.
+func RegisterSource(name string, factory SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	if _, exists := sources[name]; exists || name == SourceAPI {
+		panic(fmt.Sprintf("recorder: источник %q уже зарегистрирован", name))
+	}
+	sources[name] = factory
+}
+
+// NewSource подключается к источнику ввода по имени
+func NewSource(name, display string) (Source, error) {
+	sourcesMu.RLock()
+	factory, ok := sources[name]
+	sourcesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("неизвестный источник записи %q (доступны: %v)", name, Sources())
+	}
+	source, err := factory(display)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSourceUnavailable, name, err)
+	}
+	return source, nil
+}
+
+// Sources возвращает имена источников записи, включая api
+func Sources() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	names := []string{SourceAPI}
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+const (
+	// textBreak пауза при вводе, после которой текст разбивается на отдельные шаги
+	textBreak = time.Second
+	// scrollMerge прокрутки с меньшим интервалом объединяются в один шаг
+	scrollMerge = 500 * time.Millisecond
+	// dragThreshold смещение между нажатием и отпусканием кнопки, после которого клик считается перетаскиванием
+	dragThreshold = 5
+)
+
+// modifierOrder порядок модификаторов в шаге hotkey
+var modifierOrder = []string{"ctrl", "alt", "shift", "cmd"}
+
+// Merge объединяет события ввода в шаги последовательности: нажатие и отпускание кнопки —
+// в click по координатам нажатия, символы — в type, сочетания с ctrl, alt или cmd — в hotkey,
+// остальные клавиши — в key_tap, серию прокруток — в scroll. Backspace сразу после ввода
+// удаляет последний символ из текста шага.
+func Merge(events []InputEvent) []Action {
+	m := &merger{mods: make(map[string]bool)}
+	for _, ev := range events {
+		m.add(ev)
+	}
+	m.flushText()
+	return m.actions
+}
+
+type merger struct {
+	actions []Action
+	lastEnd time.Time
+	mods    map[string]bool
+
+	// x, y позиция курсора по событиям, cursorX, cursorY — позиция после уже записанных шагов
+	x, y             int
+	cursorX, cursorY int
+	cursorKnown      bool
+
+	press *InputEvent
+
+	text      []rune
+	textStart time.Time
+	textEnd   time.Time
+}
+
+func (m *merger) add(ev InputEvent) {
+	switch ev.Kind {
+	case EventMotion:
+		m.x, m.y = ev.X, ev.Y
+
+	case EventButtonDown:
+		m.flushText()
+		m.x, m.y = ev.X, ev.Y
+		press := ev
+		m.press = &press
+
+	case EventButtonUp:
+		press := m.press
+		if press == nil || press.Button != ev.Button {
+			return
+		}
+		m.press = nil
+		step := sequence.Step{
+			Type:   sequence.StepClick,
+			X:      sequence.IntOf(press.X),
+			Y:      sequence.IntOf(press.Y),
+			Button: press.Button,
+		}
+		if abs(ev.X-press.X) > dragThreshold || abs(ev.Y-press.Y) > dragThreshold {
+			step.Name = fmt.Sprintf("Перетаскивание в (%d, %d) записано как клик", ev.X, ev.Y)
+		}
+		m.emit("click", press.At, ev.At, step)
+		m.cursorX, m.cursorY, m.cursorKnown = press.X, press.Y, true
+
+	case EventScroll:
+		m.flushText()
+		m.x, m.y = ev.X, ev.Y
+		if m.mergeScroll(ev) {
+			return
+		}
+		var steps []sequence.Step
+		if !m.cursorKnown || m.cursorX != ev.X || m.cursorY != ev.Y {
+			steps = append(steps, sequence.Step{Type: sequence.StepMove, X: sequence.IntOf(ev.X), Y: sequence.IntOf(ev.Y)})
+		}
+		steps = append(steps, sequence.Step{Type: sequence.StepScroll, DX: sequence.Int{Value: ev.DX}, DY: sequence.Int{Value: ev.DY}})
+		m.emit("scroll", ev.At, ev.At, steps...)
+		m.cursorX, m.cursorY, m.cursorKnown = ev.X, ev.Y, true
+
+	case EventKeyDown:
+		m.keyDown(ev)
+
+	case EventKeyUp:
+		if input.IsModifier(ev.Key) {
+			m.mods[modifierName(ev.Key)] = false
+		}
+	}
+}
+
+func (m *merger) keyDown(ev InputEvent) {
+	if input.IsModifier(ev.Key) {
+		m.mods[modifierName(ev.Key)] = true
+		return
+	}
+
+	switch {
+	case m.mods["ctrl"] || m.mods["alt"] || m.mods["cmd"]:
+		if ev.Key == "" {
+			return
+		}
+		var keys []string
+		for _, mod := range modifierOrder {
+			if m.mods[mod] {
+				keys = append(keys, mod)
+			}
+		}
+		m.emit("hotkey", ev.At, ev.At, sequence.Step{Type: sequence.StepHotkey, Keys: append(keys, ev.Key)})
+
+	case ev.Rune != 0 && unicode.IsPrint(ev.Rune):
+		if len(m.text) > 0 && ev.At.Sub(m.textEnd) > textBreak {
+			m.flushText()
+		}
+		if len(m.text) == 0 {
+			m.textStart = ev.At
+		}
+		m.text = append(m.text, ev.Rune)
+		m.textEnd = ev.At
+
+	case ev.Key == "backspace" && len(m.text) > 0:
+		m.text = m.text[:len(m.text)-1]
+		m.textEnd = ev.At
+
+	case ev.Key != "":
+		step := sequence.Step{Type: sequence.StepKeyTap, Key: ev.Key}
+		if m.mods["shift"] {
+			step.Modifiers = []string{"shift"}
+		}
+		m.emit("key_tap", ev.At, ev.At, step)
+	}
+}
+
+// mergeScroll добавляет прокрутку к предыдущему шагу scroll в той же точке
+func (m *merger) mergeScroll(ev InputEvent) bool {
+	if len(m.actions) == 0 || !m.cursorKnown || m.cursorX != ev.X || m.cursorY != ev.Y {
+		return false
+	}
+	last := &m.actions[len(m.actions)-1]
+	step := &last.Steps[len(last.Steps)-1]
+	if step.Type != sequence.StepScroll || ev.At.Sub(m.lastEnd) > scrollMerge {
+		return false
+	}
+	step.DX.Value += ev.DX
+	step.DY.Value += ev.DY
+	m.lastEnd = ev.At
+	return true
+}
+
+func (m *merger) flushText() {
+	if len(m.text) == 0 {
+		return
+	}
+	text := string(m.text)
+	m.text = nil
+	m.emit("type", m.textStart, m.textEnd, sequence.Step{Type: sequence.StepText, Text: Literal(text)})
+}
+
+func (m *merger) emit(name string, started, finished time.Time, steps ...sequence.Step) {
+	if name != "type" {
+		m.flushText()
+	}
+	pause := started.Sub(m.lastEnd).Milliseconds()
+	if len(m.actions) == 0 || pause < 0 {
+		pause = 0
+	}
+	m.actions = append(m.actions, Action{
+		Name:      name,
+		StartedAt: started,
+		Steps:     steps,
+		PauseMs:   pause,
+	})
+	m.lastEnd = finished
+}
+
+// modifierName приводит имя модификатора к виду, принятому в hotkey
+func modifierName(key string) string {
+	switch key = strings.ToLower(key); {
+	case strings.HasSuffix(key, "ctrl"), key == "control":
+		return "ctrl"
+	case strings.HasSuffix(key, "shift"):
+		return "shift"
+	case strings.HasSuffix(key, "alt"):
+		return "alt"
+	default:
+		return "cmd"
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
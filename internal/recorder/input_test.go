@@ -0,0 +1,268 @@
+package recorder_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/sequence"
+)
+
+// events строит события с временем в миллисекундах от начала записи
+type events struct {
+	start time.Time
+	list  []recorder.InputEvent
+}
+
+func newEvents() *events {
+	return &events{start: time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)}
+}
+
+func (e *events) add(ms int, ev recorder.InputEvent) *events {
+	ev.At = at(e.start, ms)
+	e.list = append(e.list, ev)
+	return e
+}
+
+func (e *events) click(ms int, button string, x, y, upX, upY int) *events {
+	e.add(ms, recorder.InputEvent{Kind: recorder.EventButtonDown, Button: button, X: x, Y: y})
+	return e.add(ms+50, recorder.InputEvent{Kind: recorder.EventButtonUp, Button: button, X: upX, Y: upY})
+}
+
+// key нажатие клавиши; r — введенный символ (0 — клавиша не вводит символ)
+func (e *events) key(ms int, key string, r rune) *events {
+	e.add(ms, recorder.InputEvent{Kind: recorder.EventKeyDown, Key: key, Rune: r})
+	return e.add(ms+10, recorder.InputEvent{Kind: recorder.EventKeyUp, Key: key, Rune: r})
+}
+
+func (e *events) text(ms int, text string) *events {
+	for _, r := range text {
+		e.key(ms, string(r), r)
+		ms += 100
+	}
+	return e
+}
+
+func (e *events) down(ms int, key string) *events {
+	return e.add(ms, recorder.InputEvent{Kind: recorder.EventKeyDown, Key: key})
+}
+
+func (e *events) up(ms int, key string) *events {
+	return e.add(ms, recorder.InputEvent{Kind: recorder.EventKeyUp, Key: key})
+}
+
+func (e *events) scroll(ms, x, y, dy int) *events {
+	return e.add(ms, recorder.InputEvent{Kind: recorder.EventScroll, X: x, Y: y, DY: dy})
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		events *events
+		want   []string
+	}{
+		{
+			name:   "клик",
+			events: newEvents().click(0, "left", 10, 20, 12, 21).click(1000, "right", 30, 40, 30, 40),
+			want:   []string{"0 click(10,20,left)", "950 click(30,40,right)"},
+		},
+		{
+			name: "отпускание другой кнопки",
+			events: newEvents().
+				add(0, recorder.InputEvent{Kind: recorder.EventButtonUp, Button: "left", X: 1, Y: 1}).
+				add(10, recorder.InputEvent{Kind: recorder.EventButtonDown, Button: "left", X: 5, Y: 5}).
+				add(20, recorder.InputEvent{Kind: recorder.EventButtonUp, Button: "right", X: 5, Y: 5}),
+			want: nil,
+		},
+		{
+			name:   "ввод текста",
+			events: newEvents().text(0, "Ромашка"),
+			want:   []string{`0 type("Ромашка")`},
+		},
+		{
+			name:   "backspace исправляет текст",
+			events: newEvents().text(0, "abx").key(300, "backspace", 0).text(400, "c"),
+			want:   []string{`0 type("abc")`},
+		},
+		{
+			name:   "backspace без текста",
+			events: newEvents().key(0, "backspace", 0),
+			want:   []string{"0 key_tap(backspace)"},
+		},
+		{
+			name:   "пауза в вводе разбивает текст",
+			events: newEvents().text(0, "ab").text(1200, "cd"),
+			want:   []string{`0 type("ab")`, `1100 type("cd")`},
+		},
+		{
+			name:   "шаблон в тексте экранируется",
+			events: newEvents().text(0, "{{x"),
+			want:   []string{`0 type("{{ \"{{\" }}x")`},
+		},
+		{
+			name:   "клик завершает ввод",
+			events: newEvents().text(0, "ab").click(500, "left", 1, 2, 1, 2),
+			want:   []string{`0 type("ab")`, "400 click(1,2,left)"},
+		},
+		{
+			name:   "сочетание клавиш",
+			events: newEvents().down(0, "ctrl").key(50, "c", 'c').up(100, "ctrl").text(200, "v"),
+			want:   []string{"0 hotkey(ctrl+c)", `150 type("v")`},
+		},
+		{
+			name: "порядок модификаторов",
+			events: newEvents().down(0, "shift").down(10, "rctrl").key(20, "s", 'S').
+				up(40, "rctrl").up(50, "shift"),
+			want: []string{"0 hotkey(ctrl+shift+s)"},
+		},
+		{
+			name:   "shift с клавишей без символа",
+			events: newEvents().down(0, "shift").key(10, "tab", 0).up(30, "shift").key(100, "enter", 0),
+			want:   []string{"0 key_tap(shift+tab)", "90 key_tap(enter)"},
+		},
+		{
+			name:   "shift с символом — ввод текста",
+			events: newEvents().down(0, "shift").key(10, "a", 'A').up(30, "shift"),
+			want:   []string{`0 type("A")`},
+		},
+		{
+			name: "прокрутка",
+			events: newEvents().scroll(0, 100, 200, -1).scroll(200, 100, 200, -2).
+				scroll(1000, 100, 200, -1).scroll(1100, 300, 200, 3),
+			want: []string{
+				// Серия прокруток в одной точке объединяется, курсор перемещается один раз
+				"0 move(100,200) scroll(0,-3)",
+				"800 scroll(0,-1)",
+				"100 move(300,200) scroll(0,3)",
+			},
+		},
+		{
+			name:   "прокрутка после клика в той же точке",
+			events: newEvents().click(0, "left", 100, 200, 100, 200).scroll(100, 100, 200, 2),
+			want:   []string{"0 click(100,200,left)", "50 scroll(0,2)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeActions(recorder.Merge(tt.events.list))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("действия %q, ожидались %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDrag(t *testing.T) {
+	actions := recorder.Merge(newEvents().click(0, "left", 10, 20, 200, 20).list)
+	if len(actions) != 1 || actions[0].Steps[0].Name != "Перетаскивание в (200, 20) записано как клик" {
+		t.Fatalf("действия %+v", actions)
+	}
+	if got := describe(actions[0].Steps); got != "click(10,20,left)" {
+		t.Errorf("шаги %s", got)
+	}
+}
+
+// testSource источник ввода, который передает заданные события и ждет отмены
+type testSource struct {
+	events []recorder.InputEvent
+	err    error
+}
+
+func (s *testSource) Capture(ctx context.Context, emit func(recorder.InputEvent)) error {
+	for _, ev := range s.events {
+		emit(ev)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// source текущий источник для зарегистрированного в тестах имени "test"
+var source = &testSource{}
+
+func init() {
+	recorder.RegisterSource("test", func(display string) (recorder.Source, error) {
+		return source, nil
+	})
+}
+
+func waitStopped(t *testing.T, rec *recorder.Recording) {
+	t.Helper()
+	select {
+	case <-rec.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("запись не остановилась")
+	}
+}
+
+func TestStopKey(t *testing.T) {
+	source = &testSource{events: newEvents().
+		text(0, "ab").
+		// Клавиша остановки не записывается, события после нее тоже
+		key(300, "F12", 0).
+		text(400, "c").list}
+
+	r, _ := newRecorder()
+	rec, err := r.Start(recorder.Options{Source: "test", StopKey: "f12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStopped(t, rec)
+
+	info := rec.Info()
+	if info.State != recorder.StateStopped || info.Error != "" {
+		t.Errorf("запись %+v", info)
+	}
+	if got := describeActions(info.Actions); !slices.Equal(got, []string{`0 type("ab")`}) {
+		t.Errorf("действия %q", got)
+	}
+}
+
+func TestSourceIgnoresAPIActions(t *testing.T) {
+	source = &testSource{}
+
+	r, c := newRecorder()
+	rec, err := r.Start(recorder.Options{Source: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Запись физического ввода получает действия API от источника как обычные события
+	r.Record("mouse/click", at(c.t, 100), at(c.t, 200), nil, step(sequence.StepClick))
+	if _, err := r.Stop(rec.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if actions := rec.Info().Actions; len(actions) != 0 {
+		t.Errorf("действия %v", describeActions(actions))
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	source = &testSource{
+		events: newEvents().text(0, "a").list,
+		err:    errors.New("соединение с X-сервером потеряно"),
+	}
+
+	r, _ := newRecorder()
+	rec, err := r.Start(recorder.Options{Source: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStopped(t, rec)
+
+	info := rec.Info()
+	if info.State != recorder.StateStopped || info.Error != "соединение с X-сервером потеряно" {
+		t.Errorf("запись %+v", info)
+	}
+	if got := describeActions(info.Actions); !slices.Equal(got, []string{`0 type("a")`}) {
+		t.Errorf("действия %q", got)
+	}
+	if _, err := r.Start(recorder.Options{Source: "test"}); err != nil {
+		t.Errorf("новая запись после ошибки источника: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+// Package recorder записывает действия, выполненные через API (клики, ввод текста,
+// последовательности), или физический ввод оператора вместе с паузами между действиями
+// и превращает запись в сценарий.
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+)
+
+var (
+	// ErrNotFound возвращается, если записи с указанным ID нет
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrActive возвращается при попытке начать запись, пока идет другая
+	ErrActive = errors.New("запись уже идет")
+)
+
+// State состояние записи
+type State string
+
+const (
+	StateRecording State = "recording"
+	StateStopped   State = "stopped"
+)
+
+// recordingRetention время хранения остановленных записей
+const recordingRetention = time.Hour
+
+// Options настройки записи
+type Options struct {
+	// Source источник: api (действия API, по умолчанию) или источник физического ввода, например x11
+	Source string `json:"source"`
+	// StopKey клавиша, нажатие которой останавливает запись физического ввода (сама не записывается)
+	StopKey string `json:"stop_key,omitempty"`
+	// MinPauseMs паузы короче не записываются (по умолчанию 200 мс)
+	MinPauseMs int `json:"min_pause_ms"`
+	// MaxPauseMs ограничение записанной паузы (0 — без ограничения)
+	MaxPauseMs int `json:"max_pause_ms"`
+}
+
+// Action записанное действие: шаги, которыми его можно повторить, и параметры шаблонов
+type Action struct {
+	// Name действие API (mouse/click, keyboard/type, sequence, scenario/<имя>)
+	// или вид действия физического ввода (click, type, hotkey, key_tap, scroll)
+	Name      string          `json:"name"`
+	StartedAt time.Time       `json:"started_at"`
+	Steps     []sequence.Step `json:"steps"`
+	// Params значения {{ .params.* }} для шагов последовательностей и сценариев
+	Params map[string]any `json:"params,omitempty"`
+	// PauseMs пауза между окончанием предыдущего действия и началом этого
+	PauseMs int64 `json:"pause_ms"`
+}
+
+// Recording запись действий
+type Recording struct {
+	mu        sync.Mutex
+	id        string
+	options   Options
+	state     State
+	startedAt time.Time
+	stoppedAt time.Time
+	lastEnd   time.Time
+	actions   []Action
+
+	// events события физического ввода; шаги строятся из них функцией Merge
+	events []InputEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Info состояние записи для API
+type Info struct {
+	ID        string     `json:"id"`
+	State     State      `json:"state"`
+	Options   Options    `json:"options"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Actions   []Action   `json:"actions"`
+	// Error ошибка источника ввода, остановившая запись
+	Error string `json:"error,omitempty"`
+}
+
+// Recorder хранит записи. Одновременно идет не больше одной записи: в нее попадают
+// все действия, выполненные через API.
+type Recorder struct {
+	mu         sync.Mutex
+	seq        int
+	active     *Recording
+	recordings map[string]*Recording
+	now        func() time.Time
+	// display адрес X-сервера для источников физического ввода
+	display string
+}
+
+// New создает хранилище записей. display — адрес X-сервера для записи физического ввода.
+func New(display string) *Recorder {
+	return &Recorder{
+		recordings: make(map[string]*Recording),
+		now:        time.Now,
+		display:    display,
+	}
+}
+
+// SetClock подменяет источник времени начала и остановки записей
+func (r *Recorder) SetClock(now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = now
+}
+
+// Start начинает новую запись и удаляет давно остановленные
+func (r *Recorder) Start(opts Options) (*Recording, error) {
+	if opts.MinPauseMs < 0 || opts.MaxPauseMs < 0 {
+		return nil, errors.New("паузы не могут быть отрицательными")
+	}
+	if opts.MinPauseMs == 0 {
+		opts.MinPauseMs = 200
+	}
+	if opts.Source == "" {
+		opts.Source = SourceAPI
+	}
+	if opts.StopKey != "" && (opts.Source == SourceAPI || !input.IsKnownKey(opts.StopKey)) {
+		return nil, fmt.Errorf("stop_key %q: поддерживается только известная клавиша для записи физического ввода", opts.StopKey)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active != nil {
+		return nil, fmt.Errorf("%w: %s", ErrActive, r.active.id)
+	}
+
+	var source Source
+	if opts.Source != SourceAPI {
+		var err error
+		if source, err = NewSource(opts.Source, r.display); err != nil {
+			return nil, err
+		}
+	}
+
+	for id, old := range r.recordings {
+		old.mu.Lock()
+		expired := old.state == StateStopped && r.now().Sub(old.stoppedAt) > recordingRetention
+		old.mu.Unlock()
+		if expired {
+			delete(r.recordings, id)
+		}
+	}
+
+	r.seq++
+	now := r.now()
+	rec := &Recording{
+		id:        "rec-" + strconv.Itoa(r.seq),
+		options:   opts,
+		state:     StateRecording,
+		startedAt: now,
+		lastEnd:   now,
+		done:      make(chan struct{}),
+	}
+	r.recordings[rec.id] = rec
+	r.active = rec
+
+	if source != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		rec.cancel = cancel
+		go r.capture(ctx, rec, source)
+	}
+	return rec, nil
+}
+
+// capture сохраняет события источника ввода, пока запись не остановлена
+func (r *Recorder) capture(ctx context.Context, rec *Recording, source Source) {
+	err := source.Capture(ctx, func(ev InputEvent) {
+		if ev.Kind == EventKeyDown && rec.options.StopKey != "" && strings.EqualFold(ev.Key, rec.options.StopKey) {
+			r.Stop(rec.id)
+			return
+		}
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.state == StateRecording {
+			rec.events = append(rec.events, ev)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		rec.mu.Lock()
+		rec.err = err
+		rec.mu.Unlock()
+	}
+	r.Stop(rec.id)
+}
+
+// Record добавляет выполненное действие в активную запись. Без активной записи ничего не делает.
+// started и finished — время начала и окончания действия (без ожидания в очереди).
+func (r *Recorder) Record(name string, started, finished time.Time, params map[string]any, steps ...sequence.Step) {
+	r.mu.Lock()
+	rec := r.active
+	r.mu.Unlock()
+	if rec == nil || len(steps) == 0 {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// Действие могло завершиться уже после остановки записи; запись физического ввода
+	// получает действия API от источника как обычные события
+	if rec.state != StateRecording || rec.options.Source != SourceAPI || started.Before(rec.startedAt) {
+		return
+	}
+
+	pause := started.Sub(rec.lastEnd).Milliseconds()
+	if len(rec.actions) == 0 || pause < 0 {
+		pause = 0
+	}
+	rec.actions = append(rec.actions, Action{
+		Name:      name,
+		StartedAt: started,
+		Steps:     steps,
+		Params:    params,
+		PauseMs:   pause,
+	})
+	rec.lastEnd = finished
+}
+
+// Get возвращает запись по ID
+func (r *Recorder) Get(id string) (*Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.recordings[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return rec, nil
+}
+
+// List возвращает все записи
+func (r *Recorder) List() []Info {
+	r.mu.Lock()
+	recordings := make([]*Recording, 0, len(r.recordings))
+	for _, rec := range r.recordings {
+		recordings = append(recordings, rec)
+	}
+	r.mu.Unlock()
+
+	infos := make([]Info, 0, len(recordings))
+	for _, rec := range recordings {
+		infos = append(infos, rec.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// Stop останавливает запись. Повторная остановка не считается ошибкой.
+func (r *Recorder) Stop(id string) (*Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.recordings[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if r.active == rec {
+		r.active = nil
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.state == StateRecording {
+		rec.state = StateStopped
+		rec.stoppedAt = r.now()
+		if rec.cancel != nil {
+			rec.cancel()
+		}
+		close(rec.done)
+	}
+	return rec, nil
+}
+
+// Delete останавливает и удаляет запись
+func (r *Recorder) Delete(id string) error {
+	if _, err := r.Stop(id); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.recordings, id)
+	return nil
+}
+
+// ID возвращает идентификатор записи
+func (rec *Recording) ID() string {
+	return rec.id
+}
+
+// Done закрывается при остановке записи (в том числе клавишей stop_key или из-за ошибки источника)
+func (rec *Recording) Done() <-chan struct{} {
+	return rec.done
+}
+
+// Info возвращает состояние записи
+func (rec *Recording) Info() Info {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	info := Info{
+		ID:        rec.id,
+		State:     rec.state,
+		Options:   rec.options,
+		StartedAt: rec.startedAt,
+		Actions:   append([]Action{}, rec.actions...),
+	}
+	if rec.options.Source != SourceAPI {
+		info.Actions = Merge(rec.events)
+	}
+	if rec.state == StateStopped {
+		stoppedAt := rec.stoppedAt
+		info.StoppedAt = &stoppedAt
+	}
+	if rec.err != nil {
+		info.Error = rec.err.Error()
+	}
+	return info
+}
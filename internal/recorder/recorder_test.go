@@ -0,0 +1,262 @@
+package recorder_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/recorder"
+	"goszakup-automation/internal/sequence"
+)
+
+// describe возвращает компактное представление шагов для сравнения в тестах
+func describe(steps []sequence.Step) string {
+	var out []string
+	for _, step := range steps {
+		switch step.Type {
+		case sequence.StepClick:
+			out = append(out, fmt.Sprintf("click(%d,%d,%s)", step.X.Value, step.Y.Value, step.Button))
+		case sequence.StepMove:
+			out = append(out, fmt.Sprintf("move(%d,%d)", step.X.Value, step.Y.Value))
+		case sequence.StepScroll:
+			out = append(out, fmt.Sprintf("scroll(%d,%d)", step.DX.Value, step.DY.Value))
+		case sequence.StepText:
+			out = append(out, fmt.Sprintf("type(%q)", step.Text))
+		case sequence.StepKeyTap:
+			out = append(out, fmt.Sprintf("key_tap(%s)", strings.Join(append(slices.Clone(step.Modifiers), step.Key), "+")))
+		case sequence.StepHotkey:
+			out = append(out, fmt.Sprintf("hotkey(%s)", strings.Join(step.Keys, "+")))
+		case sequence.StepWait:
+			out = append(out, fmt.Sprintf("wait(%d)", step.DurationMs.Value))
+		default:
+			out = append(out, string(step.Type))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// describeActions возвращает действия в виде "<пауза> <шаги>"
+func describeActions(actions []recorder.Action) []string {
+	var out []string
+	for _, action := range actions {
+		out = append(out, fmt.Sprintf("%d %s", action.PauseMs, describe(action.Steps)))
+	}
+	return out
+}
+
+// clock управляемые часы записи
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+// at возвращает момент через ms миллисекунд после начала часов
+func at(start time.Time, ms int) time.Time {
+	return start.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func newRecorder() (*recorder.Recorder, *clock) {
+	c := &clock{t: time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)}
+	r := recorder.New("")
+	r.SetClock(c.now)
+	return r, c
+}
+
+func step(t sequence.StepType) sequence.Step {
+	switch t {
+	case sequence.StepClick:
+		return sequence.Step{Type: t, X: sequence.IntOf(10), Y: sequence.IntOf(20), Button: "left"}
+	case sequence.StepText:
+		return sequence.Step{Type: t, Text: "ООО Ромашка"}
+	case sequence.StepKeyTap:
+		return sequence.Step{Type: t, Key: "enter"}
+	}
+	return sequence.Step{Type: t}
+}
+
+func TestRecordPauses(t *testing.T) {
+	r, c := newRecorder()
+	start := c.t
+	rec, err := r.Start(recorder.Options{MinPauseMs: 200, MaxPauseMs: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Действие, начатое до записи (ждало в очереди или выполнялось), не записывается
+	r.Record("mouse/click", at(start, -100), at(start, 50), nil, step(sequence.StepClick))
+	r.Record("mouse/click", at(start, 300), at(start, 350), nil, step(sequence.StepClick))
+	r.Record("keyboard/type", at(start, 850), at(start, 1900), nil, step(sequence.StepText))
+	r.Record("keyboard/key", at(start, 2000), at(start, 2010), nil, step(sequence.StepKeyTap))
+	r.Record("mouse/click", at(start, 6010), at(start, 6020), nil, step(sequence.StepClick))
+	// Действие без шагов не записывается
+	r.Record("mouse/position", at(start, 6100), at(start, 6110), nil)
+
+	info := rec.Info()
+	want := []string{
+		// Пауза первого действия не записывается
+		`0 click(10,20,left)`,
+		`500 type("ООО Ромашка")`,
+		`100 key_tap(enter)`,
+		`4000 click(10,20,left)`,
+	}
+	if got := describeActions(info.Actions); !slices.Equal(got, want) {
+		t.Errorf("действия %v, ожидались %v", got, want)
+	}
+
+	c.t = at(start, 7000)
+	if _, err := r.Stop(rec.ID()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-rec.Done():
+	default:
+		t.Error("Done не закрыт после остановки")
+	}
+	// После остановки действия не записываются, повторная остановка не ошибка
+	r.Record("mouse/click", at(start, 7100), at(start, 7200), nil, step(sequence.StepClick))
+	if _, err := r.Stop(rec.ID()); err != nil {
+		t.Errorf("повторная остановка: %v", err)
+	}
+
+	info = rec.Info()
+	if info.State != recorder.StateStopped || info.StoppedAt == nil || !info.StoppedAt.Equal(at(start, 7000)) || len(info.Actions) != 4 {
+		t.Errorf("запись после остановки: %+v", info)
+	}
+
+	// Паузы короче min_pause_ms пропускаются, длинные ограничиваются max_pause_ms
+	sc := rec.Scenario("record", recorder.ScenarioOptions{})
+	wantSteps := `click(10,20,left) wait(500) type("ООО Ромашка") key_tap(enter) wait(1000) click(10,20,left)`
+	if got := describe(sc.Steps); got != wantSteps {
+		t.Errorf("шаги сценария %s, ожидались %s", got, wantSteps)
+	}
+	if sc.Description != "Запись rec-1 от 15.01.2026 10:00" {
+		t.Errorf("описание %q", sc.Description)
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	r, _ := newRecorder()
+
+	tests := []struct {
+		name string
+		opts recorder.Options
+		err  string
+	}{
+		{name: "отрицательная пауза", opts: recorder.Options{MinPauseMs: -1}, err: "паузы не могут быть отрицательными"},
+		{name: "stop_key для api", opts: recorder.Options{StopKey: "f12"}, err: `stop_key "f12"`},
+		{name: "неизвестный источник", opts: recorder.Options{Source: "unknown"}, err: `неизвестный источник записи "unknown"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Start(tt.opts); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+		})
+	}
+
+	rec, err := r.Start(recorder.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := rec.Info(); info.Options.MinPauseMs != 200 || info.Options.Source != recorder.SourceAPI {
+		t.Errorf("настройки по умолчанию %+v", info.Options)
+	}
+	if _, err := r.Start(recorder.Options{}); !errors.Is(err, recorder.ErrActive) {
+		t.Errorf("вторая запись: %v", err)
+	}
+	if _, err := r.Stop("rec-100"); !errors.Is(err, recorder.ErrNotFound) {
+		t.Errorf("остановка неизвестной записи: %v", err)
+	}
+
+	// Без активной записи действия не записываются
+	if _, err := r.Stop(rec.ID()); err != nil {
+		t.Fatal(err)
+	}
+	r.Record("mouse/click", time.Now(), time.Now(), nil, step(sequence.StepClick))
+	if _, err := r.Start(recorder.Options{}); err != nil {
+		t.Fatalf("новая запись после остановки: %v", err)
+	}
+	if len(rec.Info().Actions) != 0 {
+		t.Errorf("действия %v", describeActions(rec.Info().Actions))
+	}
+}
+
+func TestScenarioParameterize(t *testing.T) {
+	r, c := newRecorder()
+	start := c.t
+	rec, err := r.Start(recorder.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typeStep := func(text string) sequence.Step {
+		return sequence.Step{Type: sequence.StepText, Text: text}
+	}
+	r.Record("keyboard/type", at(start, 0), at(start, 10), nil, typeStep("ООО Ромашка"))
+	r.Record("keyboard/type", at(start, 20), at(start, 30), nil, typeStep(recorder.Literal("{{ .params.x }}")))
+	// Параметры последовательности объявляются со значениями из записи; text1 уже занят
+	r.Record("sequence", at(start, 40), at(start, 50), map[string]any{
+		"text1":  "занято",
+		"amount": 1500,
+		"urgent": true,
+		"lots":   []any{"1", "2"},
+		"extra":  map[string]any{"a": 1},
+	}, sequence.Step{
+		Type:      sequence.StepIf,
+		Condition: &sequence.Condition{Previous: sequence.StatusOK},
+		Then:      []sequence.Step{typeStep("{{ .params.text1 }}"), typeStep("БИН 123")},
+	})
+	r.Record("keyboard/type", at(start, 60), at(start, 70), nil, typeStep("ООО Ромашка"))
+
+	sc := rec.Scenario("parameterized", recorder.ScenarioOptions{Description: "Тест", Parameterize: true})
+
+	var texts []string
+	for _, s := range append(slices.Clone(sc.Steps), sc.Steps[2].Then...) {
+		if s.Type == sequence.StepText {
+			texts = append(texts, s.Text)
+		}
+	}
+	want := []string{
+		// Одинаковые тексты получают один параметр
+		"{{ .params.text2 }}",
+		// Экранированный литерал остается как есть
+		`{{ "{{" }} .params.x }}`,
+		"{{ .params.text2 }}",
+		"{{ .params.text1 }}",
+		"{{ .params.text3 }}",
+	}
+	if !slices.Equal(texts, want) {
+		t.Errorf("тексты %q, ожидались %q", texts, want)
+	}
+
+	var params []string
+	for _, p := range sc.Params {
+		params = append(params, fmt.Sprintf("%s:%s=%v", p.Name, p.Type, p.Default))
+	}
+	wantParams := []string{
+		"amount:number=1500",
+		"lots:list=[1 2]",
+		"text1:=занято",
+		"urgent:bool=true",
+		"text2:=ООО Ромашка",
+		"text3:=БИН 123",
+	}
+	if !slices.Equal(params, wantParams) {
+		t.Errorf("параметры %v, ожидались %v", params, wantParams)
+	}
+
+	// Изменения сценария не затрагивают запись
+	if got := rec.Info().Actions[2].Steps[0].Then[1].Text; got != "БИН 123" {
+		t.Errorf("текст в записи изменен: %q", got)
+	}
+	// Построенный сценарий проходит проверку
+	if err := sc.Validate(); err != nil {
+		t.Errorf("сценарий не прошел проверку: %v", err)
+	}
+}
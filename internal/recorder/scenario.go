@@ -0,0 +1,160 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"goszakup-automation/internal/scenario"
+	"goszakup-automation/internal/sequence"
+)
+
+// ScenarioOptions настройки построения сценария из записи
+type ScenarioOptions struct {
+	Description string
+	// Parameterize заменить введенные тексты параметрами text1, text2, ...
+	// (значения из записи становятся значениями по умолчанию)
+	Parameterize bool
+}
+
+// Literal экранирует текст, введенный через API, чтобы в сценарии он не был разобран как шаблон
+func Literal(text string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return strings.ReplaceAll(text, "{{", `{{ "{{" }}`)
+}
+
+// Scenario строит сценарий из записанных действий. Паузы короче MinPauseMs пропускаются,
+// длинные ограничиваются MaxPauseMs. Параметры последовательностей и сценариев объявляются
+// со значениями из записи; если параметр встречался несколько раз, берется первое значение.
+func (rec *Recording) Scenario(name string, opts ScenarioOptions) *scenario.Scenario {
+	info := rec.Info()
+
+	sc := &scenario.Scenario{
+		Name:        name,
+		Description: opts.Description,
+		Steps:       []sequence.Step{},
+	}
+	if sc.Description == "" {
+		sc.Description = fmt.Sprintf("Запись %s от %s", info.ID, info.StartedAt.Format("02.01.2006 15:04"))
+	}
+
+	declared := make(map[string]bool)
+	for i, action := range info.Actions {
+		pause := int(action.PauseMs)
+		if info.Options.MaxPauseMs > 0 && pause > info.Options.MaxPauseMs {
+			pause = info.Options.MaxPauseMs
+		}
+		if i > 0 && pause >= info.Options.MinPauseMs {
+			sc.Steps = append(sc.Steps, sequence.Step{Type: sequence.StepWait, DurationMs: sequence.Int{Value: pause}})
+		}
+		sc.Steps = append(sc.Steps, cloneSteps(action.Steps)...)
+
+		names := make([]string, 0, len(action.Params))
+		for name := range action.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if declared[name] {
+				continue
+			}
+			param, ok := paramOf(name, action.Params[name])
+			if !ok {
+				continue
+			}
+			param.Description = "Значение из записи (" + action.Name + ")"
+			sc.Params = append(sc.Params, param)
+			declared[name] = true
+		}
+	}
+
+	if opts.Parameterize {
+		p := &parameterizer{declared: declared, byText: make(map[string]string)}
+		p.walk(sc.Steps)
+		sc.Params = append(sc.Params, p.params...)
+	}
+	return sc
+}
+
+// parameterizer заменяет введенные тексты ссылками на параметры
+type parameterizer struct {
+	declared map[string]bool
+	byText   map[string]string
+	params   []scenario.Param
+	next     int
+}
+
+func (p *parameterizer) walk(steps []sequence.Step) {
+	for i := range steps {
+		step := &steps[i]
+		switch step.Type {
+		case sequence.StepText, sequence.StepInput, sequence.StepFillAndClick:
+			// Шаблоны и экранированные тексты оставляем как есть
+			if step.Text != "" && !strings.Contains(step.Text, "{{") {
+				step.Text = "{{ .params." + p.param(step.Text) + " }}"
+			}
+		}
+		p.walk(step.Then)
+		p.walk(step.Else)
+		p.walk(step.Steps)
+	}
+}
+
+// param возвращает имя параметра для текста; одинаковые тексты получают один параметр
+func (p *parameterizer) param(text string) string {
+	if name, ok := p.byText[text]; ok {
+		return name
+	}
+	var name string
+	for {
+		p.next++
+		name = fmt.Sprintf("text%d", p.next)
+		if !p.declared[name] {
+			break
+		}
+	}
+	p.declared[name] = true
+	p.byText[text] = name
+	p.params = append(p.params, scenario.Param{
+		Name:        name,
+		Description: "Введенный текст",
+		Default:     text,
+	})
+	return name
+}
+
+// paramOf объявляет параметр по значению из записи
+func paramOf(name string, value any) (scenario.Param, bool) {
+	param := scenario.Param{Name: name, Default: value}
+	switch value.(type) {
+	case string:
+	case bool:
+		param.Type = scenario.ParamBool
+	case int, int64, float64, json.Number:
+		param.Type = scenario.ParamNumber
+	case []any:
+		param.Type = scenario.ParamList
+	default:
+		// Значения других типов (объекты, null) в сценарии объявить нельзя
+		return param, false
+	}
+	return param, true
+}
+
+// cloneSteps копирует шаги вместе с вложенными, чтобы изменения сценария не затронули запись
+func cloneSteps(steps []sequence.Step) []sequence.Step {
+	if steps == nil {
+		return nil
+	}
+	cloned := make([]sequence.Step, len(steps))
+	for i, step := range steps {
+		step.Then = cloneSteps(step.Then)
+		step.Else = cloneSteps(step.Else)
+		step.Steps = cloneSteps(step.Steps)
+		cloned[i] = step
+	}
+	return cloned
+}
@@ -0,0 +1,254 @@
+// Package safety реализует аварийный останов: fail-safe по углу экрана,
+// как в pyautogui. Если оператор уводит физический курсор в заданный угол,
+// все действия отменяются, а ввод блокируется до повторного взведения.
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+// ErrTripped возвращается для любого ввода после срабатывания fail-safe
+var ErrTripped = errors.New("ввод заблокирован аварийным остановом")
+
+// Corner угол экрана, в котором срабатывает fail-safe
+type Corner string
+
+const (
+	TopLeft     Corner = "top-left"
+	TopRight    Corner = "top-right"
+	BottomLeft  Corner = "bottom-left"
+	BottomRight Corner = "bottom-right"
+)
+
+// ParseCorners разбирает список углов через запятую. "all" означает все четыре угла,
+// пустая строка или "off" — fail-safe выключен.
+func ParseCorners(value string) ([]Corner, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	switch value {
+	case "", "off", "none":
+		return nil, nil
+	case "all":
+		return []Corner{TopLeft, TopRight, BottomLeft, BottomRight}, nil
+	}
+
+	var corners []Corner
+	for _, part := range strings.Split(value, ",") {
+		corner := Corner(strings.TrimSpace(part))
+		switch corner {
+		case TopLeft, TopRight, BottomLeft, BottomRight:
+			corners = append(corners, corner)
+		default:
+			return nil, fmt.Errorf("неизвестный угол экрана %q", part)
+		}
+	}
+	return corners, nil
+}
+
+// Options параметры fail-safe
+type Options struct {
+	// Corners углы, в которых срабатывает fail-safe (пусто — выключен)
+	Corners []Corner
+	// Margin расстояние от угла в пикселях, считающееся попаданием в угол
+	Margin int
+	// PollInterval период опроса позиции курсора
+	PollInterval time.Duration
+}
+
+// Status состояние fail-safe для API
+type Status struct {
+	Enabled   bool       `json:"enabled"`
+	Corners   []Corner   `json:"corners"`
+	Tripped   bool       `json:"tripped"`
+	Reason    string     `json:"reason,omitempty"`
+	TrippedAt *time.Time `json:"tripped_at,omitempty"`
+}
+
+// FailSafe следит за позицией курсора и блокирует ввод после срабатывания.
+// Реализует input.Guard.
+type FailSafe struct {
+	logger  *zap.Logger
+	backend input.Backend
+	opts    Options
+	// onTrip вызывается один раз при каждом срабатывании
+	onTrip func(reason string)
+
+	mu        sync.Mutex
+	tripped   bool
+	reason    string
+	trippedAt time.Time
+}
+
+// NewFailSafe создает fail-safe. onTrip вызывается при срабатывании (в том числе
+// через Trip) и должен отменить действия и отпустить клавиши.
+func NewFailSafe(logger *zap.Logger, backend input.Backend, opts Options, onTrip func(reason string)) *FailSafe {
+	if opts.Margin < 0 {
+		opts.Margin = 0
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 100 * time.Millisecond
+	}
+	return &FailSafe{
+		logger:  logger,
+		backend: backend,
+		opts:    opts,
+		onTrip:  onTrip,
+	}
+}
+
+// Enabled сообщает, включено ли слежение за углами экрана
+func (f *FailSafe) Enabled() bool {
+	return len(f.opts.Corners) > 0
+}
+
+// Run опрашивает позицию курсора до отмены ctx. Если fail-safe выключен, сразу возвращается.
+func (f *FailSafe) Run(ctx context.Context) {
+	if !f.Enabled() {
+		return
+	}
+
+	f.logger.Info("Fail-safe включен",
+		zap.Any("corners", f.opts.Corners),
+		zap.Int("margin", f.opts.Margin))
+
+	ticker := time.NewTicker(f.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if f.isTripped() {
+			continue
+		}
+
+		corner, err := f.cursorCorner()
+		if err != nil {
+			f.logger.Debug("Fail-safe: ошибка определения позиции курсора", zap.Error(err))
+			continue
+		}
+		if corner != "" {
+			f.Trip(fmt.Sprintf("курсор в углу экрана (%s)", corner))
+		}
+	}
+}
+
+// Check возвращает ErrTripped, если fail-safe сработал
+func (f *FailSafe) Check() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.tripped {
+		return fmt.Errorf("%w: %s", ErrTripped, f.reason)
+	}
+	return nil
+}
+
+// Trip переводит fail-safe в сработавшее состояние и вызывает onTrip.
+// Повторный вызов до взведения ничего не делает.
+func (f *FailSafe) Trip(reason string) {
+	f.mu.Lock()
+	if f.tripped {
+		f.mu.Unlock()
+		return
+	}
+	f.tripped = true
+	f.reason = reason
+	f.trippedAt = time.Now()
+	f.mu.Unlock()
+
+	f.logger.Warn("Сработал аварийный останов", zap.String("reason", reason))
+	if f.onTrip != nil {
+		f.onTrip(reason)
+	}
+}
+
+// Arm повторно взводит fail-safe и снимает блокировку ввода. Пока курсор
+// остается в углу экрана, взведение невозможно.
+func (f *FailSafe) Arm() error {
+	if f.Enabled() {
+		corner, err := f.cursorCorner()
+		if err != nil {
+			return fmt.Errorf("ошибка определения позиции курсора: %w", err)
+		}
+		if corner != "" {
+			return fmt.Errorf("курсор все еще в углу экрана (%s), уведите его перед взведением", corner)
+		}
+	}
+
+	f.mu.Lock()
+	f.tripped = false
+	f.reason = ""
+	f.trippedAt = time.Time{}
+	f.mu.Unlock()
+
+	f.logger.Info("Fail-safe взведен, ввод разблокирован")
+	return nil
+}
+
+// Status возвращает текущее состояние fail-safe
+func (f *FailSafe) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := Status{
+		Enabled: f.Enabled(),
+		Corners: f.opts.Corners,
+		Tripped: f.tripped,
+		Reason:  f.reason,
+	}
+	if status.Corners == nil {
+		status.Corners = []Corner{}
+	}
+	if f.tripped {
+		trippedAt := f.trippedAt
+		status.TrippedAt = &trippedAt
+	}
+	return status
+}
+
+func (f *FailSafe) isTripped() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tripped
+}
+
+// cursorCorner возвращает угол, в котором находится курсор, или пустую строку
+func (f *FailSafe) cursorCorner() (Corner, error) {
+	x, y, err := f.backend.MousePosition()
+	if err != nil {
+		return "", err
+	}
+	width, height, err := f.backend.ScreenSize()
+	if err != nil {
+		return "", err
+	}
+
+	m := f.opts.Margin
+	left := x <= m
+	right := x >= width-1-m
+	top := y <= m
+	bottom := y >= height-1-m
+
+	for _, corner := range f.opts.Corners {
+		switch {
+		case corner == TopLeft && top && left,
+			corner == TopRight && top && right,
+			corner == BottomLeft && bottom && left,
+			corner == BottomRight && bottom && right:
+			return corner, nil
+		}
+	}
+	return "", nil
+}
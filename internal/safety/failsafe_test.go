@@ -0,0 +1,182 @@
+package safety_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/safety"
+
+	"go.uber.org/zap"
+)
+
+func TestParseCorners(t *testing.T) {
+	tests := []struct {
+		value   string
+		corners []safety.Corner
+		err     bool
+	}{
+		{value: "", corners: nil},
+		{value: "off", corners: nil},
+		{value: " None ", corners: nil},
+		{value: "all", corners: []safety.Corner{safety.TopLeft, safety.TopRight, safety.BottomLeft, safety.BottomRight}},
+		{value: "top-left", corners: []safety.Corner{safety.TopLeft}},
+		{value: "Top-Left, bottom-right", corners: []safety.Corner{safety.TopLeft, safety.BottomRight}},
+		{value: "top-left,center", err: true},
+		{value: "top-left,", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			corners, err := safety.ParseCorners(tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("ошибка %v", err)
+			}
+			if !slices.Equal(corners, tt.corners) {
+				t.Errorf("углы %v, ожидались %v", corners, tt.corners)
+			}
+		})
+	}
+}
+
+func TestCornerDetection(t *testing.T) {
+	two := []safety.Corner{safety.TopLeft, safety.BottomRight}
+	all, _ := safety.ParseCorners("all")
+
+	tests := []struct {
+		name    string
+		corners []safety.Corner
+		margin  int
+		x, y    int
+		// corner угол, в котором находится курсор (пусто — не в углу)
+		corner safety.Corner
+	}{
+		{name: "точно в углу", corners: two, x: 0, y: 0, corner: safety.TopLeft},
+		{name: "рядом без margin", corners: two, x: 1, y: 0},
+		{name: "в пределах margin", corners: two, margin: 5, x: 5, y: 5, corner: safety.TopLeft},
+		{name: "за пределами margin", corners: two, margin: 5, x: 6, y: 5},
+		{name: "правый нижний", corners: two, margin: 5, x: 94, y: 44, corner: safety.BottomRight},
+		{name: "последний пиксель", corners: two, x: 99, y: 49, corner: safety.BottomRight},
+		{name: "угол не включен", corners: two, x: 99, y: 0},
+		{name: "all", corners: all, x: 99, y: 0, corner: safety.TopRight},
+		{name: "all левый нижний", corners: all, x: 0, y: 49, corner: safety.BottomLeft},
+		{name: "край, но не угол", corners: all, x: 50, y: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := fake.New(fake.Options{Platform: "linux", Width: 100, Height: 50})
+			if err := backend.MoveMouse(tt.x, tt.y); err != nil {
+				t.Fatal(err)
+			}
+			f := safety.NewFailSafe(zap.NewNop(), backend, safety.Options{Corners: tt.corners, Margin: tt.margin}, nil)
+
+			// Взведение невозможно, пока курсор в углу
+			err := f.Arm()
+			if tt.corner == "" {
+				if err != nil {
+					t.Errorf("курсор не в углу, ошибка взведения: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "("+string(tt.corner)+")") {
+				t.Errorf("ошибка %v, ожидался угол %s", err, tt.corner)
+			}
+		})
+	}
+}
+
+func TestTripByCursor(t *testing.T) {
+	backend := fake.New(fake.Options{Platform: "linux", Width: 100, Height: 50})
+	service := input.NewService(zap.NewNop(), backend)
+
+	reasons := make(chan string, 4)
+	f := safety.NewFailSafe(zap.NewNop(), backend, safety.Options{
+		Corners:      []safety.Corner{safety.TopRight},
+		Margin:       2,
+		PollInterval: time.Millisecond,
+	}, func(reason string) { reasons <- reason })
+	service.SetGuard(f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Run(ctx)
+
+	// Курсор в стороне от угла: ввод разрешен
+	if err := service.MoveMouse(context.Background(), 97, 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if f.Status().Tripped {
+		t.Fatal("сработал вне угла")
+	}
+
+	// Оператор уводит курсор в угол
+	if err := service.MoveMouse(context.Background(), 98, 1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-reasons:
+		if reason != "курсор в углу экрана (top-right)" {
+			t.Errorf("причина %q", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fail-safe не сработал")
+	}
+
+	status := f.Status()
+	if !status.Tripped || status.TrippedAt == nil || status.Reason != "курсор в углу экрана (top-right)" {
+		t.Errorf("состояние %+v", status)
+	}
+	if err := service.Click(context.Background(), "left"); !errors.Is(err, safety.ErrTripped) {
+		t.Errorf("ввод после срабатывания: %v", err)
+	}
+	if err := f.Arm(); err == nil {
+		t.Error("взведен, пока курсор в углу")
+	}
+
+	// Физический курсор уведен из угла: после взведения ввод разблокирован
+	if err := backend.MoveMouse(50, 25); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Arm(); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Click(context.Background(), "left"); err != nil {
+		t.Errorf("ввод после взведения: %v", err)
+	}
+	// onTrip вызывается один раз на срабатывание
+	if len(reasons) != 0 {
+		t.Errorf("лишние срабатывания: %d", len(reasons))
+	}
+}
+
+func TestTripOnce(t *testing.T) {
+	backend := fake.New(fake.Options{Platform: "linux"})
+	trips := 0
+	f := safety.NewFailSafe(zap.NewNop(), backend, safety.Options{}, func(reason string) { trips++ })
+
+	if f.Check() != nil || f.Enabled() {
+		t.Fatalf("выключенный fail-safe: %v", f.Check())
+	}
+	// Run сразу возвращается, если углы не заданы
+	f.Run(context.Background())
+
+	// Trip работает и без слежения за углами (например, из API)
+	f.Trip("вручную")
+	f.Trip("повторно")
+	if trips != 1 {
+		t.Errorf("onTrip вызван %d раз", trips)
+	}
+	if err := f.Check(); !errors.Is(err, safety.ErrTripped) || !strings.Contains(err.Error(), "вручную") {
+		t.Errorf("ошибка %v", err)
+	}
+	if err := f.Arm(); err != nil || f.Check() != nil {
+		t.Errorf("взведение: %v, %v", err, f.Check())
+	}
+}
@@ -0,0 +1,29 @@
+package safety
+
+import (
+	"fmt"
+	"time"
+
+	"goszakup-automation/internal/executor"
+	"goszakup-automation/internal/input"
+)
+
+// stopWait сколько ждать остановки выполняемого действия перед отпусканием клавиш
+const stopWait = 2 * time.Second
+
+// Stop отменяет все ожидающие и выполняемое действия, дожидается остановки текущего
+// и отпускает клавиши-модификаторы и кнопки мыши. Возвращает число отмененных заданий.
+func Stop(exec *executor.Executor, service *input.Service) (int, error) {
+	running, cancelled := exec.CancelAll()
+	if running != nil {
+		select {
+		case <-running.Done():
+		case <-time.After(stopWait):
+		}
+	}
+
+	if err := service.ReleaseAll(); err != nil {
+		return cancelled, fmt.Errorf("ошибка отпускания клавиш: %w", err)
+	}
+	return cancelled, nil
+}
@@ -0,0 +1,133 @@
+package safety_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/executor"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/safety"
+
+	"go.uber.org/zap"
+)
+
+func eventStrings(b *fake.Backend) []string {
+	var events []string
+	for _, e := range b.Events() {
+		events = append(events, e.String())
+	}
+	return events
+}
+
+// holdCtrl ставит в очередь задание, которое зажимает Ctrl и ждет отмены;
+// после отмены задание еще delay не отпускает рабочий стол.
+// Если release не nil, задание не проверяет отмену и ждет закрытия release.
+func holdCtrl(t *testing.T, e *executor.Executor, service *input.Service, delay time.Duration, release chan struct{}) *executor.Job {
+	t.Helper()
+	started := make(chan struct{})
+	job := e.Submit("hotkey", func(ctx context.Context) (any, error) {
+		if err := service.KeyToggle(ctx, "ctrl", true); err != nil {
+			return nil, err
+		}
+		close(started)
+		if release != nil {
+			<-release
+			return nil, nil
+		}
+		<-ctx.Done()
+		time.Sleep(delay)
+		return nil, context.Cause(ctx)
+	})
+	<-started
+	return job
+}
+
+func TestStop(t *testing.T) {
+	backend := fake.New(fake.Options{Platform: "linux"})
+	service := input.NewService(zap.NewNop(), backend)
+	e := executor.New(zap.NewNop(), 0)
+
+	running := holdCtrl(t, e, service, 50*time.Millisecond, nil)
+	queued := e.Submit("click", func(ctx context.Context) (any, error) {
+		return nil, service.Click(ctx, "left")
+	})
+
+	cancelled, err := safety.Stop(e, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled != 2 {
+		t.Errorf("отменено %d заданий", cancelled)
+	}
+
+	// Stop дожидается остановки выполняемого задания и только потом отпускает клавиши
+	select {
+	case <-running.Done():
+	default:
+		t.Fatal("Stop вернулся до остановки задания")
+	}
+	if _, err := running.Wait(context.Background()); !errors.Is(err, input.ErrStopped) {
+		t.Errorf("ошибка задания %v, ожидалась ErrStopped", err)
+	}
+	<-queued.Done()
+	if queued.State() != executor.StateCancelled {
+		t.Errorf("ожидающее задание: %s", queued.State())
+	}
+
+	if got, want := eventStrings(backend), []string{"key_down(ctrl)", "key_up(ctrl)"}; !slices.Equal(got, want) {
+		t.Errorf("события %v, ожидались %v", got, want)
+	}
+	if held := service.Held(); len(held.Keys) != 0 {
+		t.Errorf("зажаты клавиши %v", held.Keys)
+	}
+}
+
+func TestStopStuckJob(t *testing.T) {
+	if testing.Short() {
+		t.Skip("ожидание остановки 2 с")
+	}
+	backend := fake.New(fake.Options{Platform: "linux"})
+	service := input.NewService(zap.NewNop(), backend)
+	e := executor.New(zap.NewNop(), 0)
+
+	release := make(chan struct{})
+	defer close(release)
+	running := holdCtrl(t, e, service, 0, release)
+
+	// Задание не реагирует на отмену: Stop ждет его 2 с и все равно отпускает клавиши
+	started := time.Now()
+	cancelled, err := safety.Stop(e, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < 2*time.Second || elapsed > 4*time.Second {
+		t.Errorf("Stop ждал %v", elapsed)
+	}
+	if cancelled != 1 || running.State() != executor.StateRunning {
+		t.Errorf("отменено %d, задание %s", cancelled, running.State())
+	}
+	if got, want := eventStrings(backend), []string{"key_down(ctrl)", "key_up(ctrl)"}; !slices.Equal(got, want) {
+		t.Errorf("события %v, ожидались %v", got, want)
+	}
+}
+
+func TestStopIdle(t *testing.T) {
+	backend := fake.New(fake.Options{Platform: "linux"})
+	service := input.NewService(zap.NewNop(), backend)
+
+	// Без заданий Stop только отпускает то, что осталось зажатым
+	if err := service.MouseToggle(context.Background(), "left", true); err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := safety.Stop(executor.New(zap.NewNop(), 0), service)
+	if err != nil || cancelled != 0 {
+		t.Fatalf("отменено %d, ошибка %v", cancelled, err)
+	}
+	if got, want := eventStrings(backend), []string{"mouse_down(left)", "mouse_up(left)"}; !slices.Equal(got, want) {
+		t.Errorf("события %v, ожидались %v", got, want)
+	}
+}
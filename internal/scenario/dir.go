@@ -0,0 +1,146 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound возвращается, если сценарий с указанным именем не найден
+var ErrNotFound = errors.New("сценарий не найден")
+
+// namePattern допустимые имена сценариев (имя файла без расширения)
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// extensions поддерживаемые расширения файлов сценариев в порядке поиска
+var extensions = []string{".yaml", ".yml", ".json"}
+
+// Summary краткое описание сценария для списка
+type Summary struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params,omitempty"`
+	File        string  `json:"file"`
+	// Version номер текущей версии в истории (0 — файл без истории или изменен вручную)
+	Version int `json:"version,omitempty"`
+	// Error ошибка загрузки, если файл сценария некорректен
+	Error string `json:"error,omitempty"`
+}
+
+// Dir каталог с файлами сценариев. Файлы читаются при каждом обращении,
+// поэтому изменения применяются без перезапуска сервиса.
+type Dir struct {
+	path string
+}
+
+// NewDir создает загрузчик сценариев из каталога
+func NewDir(path string) *Dir {
+	return &Dir{path: path}
+}
+
+// Path возвращает путь к каталогу сценариев
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// List возвращает все сценарии каталога. Некорректные файлы попадают в список с ошибкой.
+func (d *Dir) List() ([]Summary, error) {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Summary{}, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения каталога сценариев: %w", err)
+	}
+
+	summaries := []Summary{}
+	for _, entry := range entries {
+		if entry.IsDir() || !supported(entry.Name()) {
+			continue
+		}
+
+		summary := Summary{
+			Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			File: entry.Name(),
+		}
+		sc, err := d.loadFile(filepath.Join(d.path, entry.Name()))
+		if err != nil {
+			summary.Error = err.Error()
+		} else {
+			summary.Description = sc.Description
+			summary.Params = sc.Params
+		}
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries, nil
+}
+
+// Get загружает сценарий по имени файла без расширения
+func (d *Dir) Get(name string) (*Scenario, error) {
+	path, err := d.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.loadFile(path)
+}
+
+// Read возвращает путь и исходный текст файла сценария (например, для проверки линтером)
+func (d *Dir) Read(name string) (string, []byte, error) {
+	path, err := d.find(name)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("ошибка чтения сценария: %w", err)
+	}
+	return path, data, nil
+}
+
+// find возвращает путь к файлу сценария по имени
+func (d *Dir) find(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("недопустимое имя сценария %q", name)
+	}
+
+	for _, ext := range extensions {
+		path := filepath.Join(d.path, name+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+func (d *Dir) loadFile(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сценария: %w", err)
+	}
+	sc, err := Parse(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	// Сценарий вызывается по имени файла, поле name в файле на это не влияет
+	sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return sc, nil
+}
+
+func supported(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
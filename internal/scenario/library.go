@@ -0,0 +1,393 @@
+package scenario
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrExists возвращается при создании сценария с занятым именем
+	ErrExists = errors.New("сценарий уже существует")
+	// ErrVersionNotFound возвращается, если у сценария нет указанной версии
+	ErrVersionNotFound = errors.New("версия сценария не найдена")
+)
+
+// versionsDir каталог истории версий внутри каталога сценариев
+const versionsDir = ".versions"
+
+// Version сохраненная неизменяемая версия сценария
+type Version struct {
+	Version   int       `json:"version"`
+	File      string    `json:"file"`
+	CreatedAt time.Time `json:"created_at"`
+	Comment   string    `json:"comment,omitempty"`
+	SHA256    string    `json:"sha256"`
+	Size      int       `json:"size"`
+	// RestoredFrom версия, из которой восстановлена эта (при откате)
+	RestoredFrom int `json:"restored_from,omitempty"`
+}
+
+// Library хранилище сценариев с историей версий. Текущая версия лежит в каталоге сценариев
+// как обычный файл (ее читает Dir), каждое сохранение дополнительно записывается
+// в .versions/<имя>/ и больше не изменяется.
+type Library struct {
+	dir *Dir
+	mu  sync.Mutex
+}
+
+// NewLibrary создает хранилище поверх каталога сценариев
+func NewLibrary(dir *Dir) *Library {
+	return &Library{dir: dir}
+}
+
+// Dir возвращает каталог сценариев
+func (l *Library) Dir() *Dir {
+	return l.dir
+}
+
+// List возвращает сценарии каталога с номером текущей версии
+func (l *Library) List() ([]Summary, error) {
+	summaries, err := l.dir.List()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range summaries {
+		summaries[i].Version = l.currentLocked(summaries[i].Name)
+	}
+	return summaries, nil
+}
+
+// Create сохраняет новый сценарий. ext — расширение файла (.yaml, .yml, .json).
+func (l *Library) Create(name, ext string, data []byte, comment string) (*Version, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.dir.find(name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	return l.saveLocked(name, ext, data, comment, 0)
+}
+
+// Update сохраняет новую версию существующего сценария
+func (l *Library) Update(name, ext string, data []byte, comment string) (*Version, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.dir.find(name); err != nil {
+		return nil, err
+	}
+	if err := l.snapshotLocked(name); err != nil {
+		return nil, err
+	}
+	return l.saveLocked(name, ext, data, comment, 0)
+}
+
+// Delete удаляет текущий файл сценария. История версий сохраняется,
+// поэтому удаленный сценарий можно восстановить через Rollback.
+func (l *Library) Delete(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	path, err := l.dir.find(name)
+	if err != nil {
+		return err
+	}
+	if err := l.snapshotLocked(name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("ошибка удаления сценария: %w", err)
+	}
+	return nil
+}
+
+// Versions возвращает историю версий сценария от первой к последней
+func (l *Library) Versions(name string) ([]Version, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("недопустимое имя сценария %q", name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.versionsLocked(name)
+}
+
+// Current возвращает номер версии, совпадающей с текущим файлом сценария
+// (0 — файл изменен вручную или сохранен без истории)
+func (l *Library) Current(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.currentLocked(name)
+}
+
+// Get загружает сценарий: текущий файл при version == 0 или сохраненную версию
+func (l *Library) Get(name string, version int) (*Scenario, error) {
+	if version == 0 {
+		return l.dir.Get(name)
+	}
+
+	data, v, err := l.ReadVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := Parse(v.File, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s версия %d: %w", name, version, err)
+	}
+	sc.Name = name
+	return sc, nil
+}
+
+// ReadVersion возвращает исходный текст версии
+func (l *Library) ReadVersion(name string, version int) ([]byte, *Version, error) {
+	if !namePattern.MatchString(name) {
+		return nil, nil, fmt.Errorf("недопустимое имя сценария %q", name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readVersionLocked(name, version)
+}
+
+// Rollback сохраняет содержимое старой версии как новую текущую версию
+func (l *Library) Rollback(name string, version int, comment string) (*Version, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, v, err := l.readVersionLocked(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("откат к версии %d", version)
+	}
+	if err := l.snapshotLocked(name); err != nil {
+		return nil, err
+	}
+	return l.saveLocked(name, filepath.Ext(v.File), data, comment, version)
+}
+
+// Diff возвращает построчную разницу двух версий в формате unified diff.
+// Версия 0 означает текущий файл сценария.
+func (l *Library) Diff(name string, from, to int) (string, error) {
+	fromName, fromData, err := l.source(name, from)
+	if err != nil {
+		return "", err
+	}
+	toName, toData, err := l.source(name, to)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(fromName, toName, string(fromData), string(toData)), nil
+}
+
+// source возвращает подпись и текст версии или текущего файла
+func (l *Library) source(name string, version int) (string, []byte, error) {
+	if version == 0 {
+		path, data, err := l.dir.Read(name)
+		if err != nil {
+			return "", nil, err
+		}
+		return filepath.Base(path), data, nil
+	}
+	data, v, err := l.ReadVersion(name, version)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s@%d", v.File, version), data, nil
+}
+
+func (l *Library) saveLocked(name, ext string, data []byte, comment string, restoredFrom int) (*Version, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("недопустимое имя сценария %q", name)
+	}
+	ext = strings.ToLower(ext)
+	if !supported(ext) {
+		return nil, fmt.Errorf("неподдерживаемый формат сценария %q", ext)
+	}
+	if _, err := Parse(name+ext, data); err != nil {
+		return nil, err
+	}
+
+	v, err := l.recordLocked(name, ext, data, comment, restoredFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	// Текущий файл заменяется атомарно; файл с другим расширением (смена формата) удаляется
+	if err := writeFileAtomic(filepath.Join(l.dir.path, name+ext), data); err != nil {
+		return nil, err
+	}
+	for _, other := range extensions {
+		if other != ext {
+			_ = os.Remove(filepath.Join(l.dir.path, name+other))
+		}
+	}
+	return v, nil
+}
+
+// snapshotLocked записывает текущий файл сценария в историю, если его там нет: файл создан
+// до появления истории или изменен вручную. Иначе перезапись или удаление файла потеряли бы
+// его содержимое без возможности сравнения и отката.
+func (l *Library) snapshotLocked(name string) error {
+	path, err := l.dir.find(name)
+	if err != nil || l.currentLocked(name) != 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения сценария: %w", err)
+	}
+	versions, err := l.versionsLocked(name)
+	if err != nil {
+		return err
+	}
+	comment := "исходный файл"
+	if len(versions) > 0 {
+		comment = "изменения вне истории версий"
+	}
+	_, err = l.recordLocked(name, strings.ToLower(filepath.Ext(path)), data, comment, 0)
+	return err
+}
+
+// recordLocked добавляет содержимое в историю версий, не изменяя текущий файл
+func (l *Library) recordLocked(name, ext string, data []byte, comment string, restoredFrom int) (*Version, error) {
+	versions, err := l.versionsLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	number := 1
+	if len(versions) > 0 {
+		number = versions[len(versions)-1].Version + 1
+	}
+
+	sum := sha256.Sum256(data)
+	v := Version{
+		Version:      number,
+		File:         name + ext,
+		CreatedAt:    time.Now(),
+		Comment:      comment,
+		SHA256:       hex.EncodeToString(sum[:]),
+		Size:         len(data),
+		RestoredFrom: restoredFrom,
+	}
+
+	historyDir := l.historyDir(name)
+	if err := os.MkdirAll(historyDir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога версий: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(historyDir, strconv.Itoa(number)+ext), data, 0o444); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения версии: %w", err)
+	}
+	if err := writeJSON(filepath.Join(historyDir, "versions.json"), append(versions, v)); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (l *Library) versionsLocked(name string) ([]Version, error) {
+	data, err := os.ReadFile(filepath.Join(l.historyDir(name), "versions.json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Version{}, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения истории версий: %w", err)
+	}
+	var versions []Version
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("ошибка разбора истории версий: %w", err)
+	}
+	return versions, nil
+}
+
+func (l *Library) readVersionLocked(name string, version int) ([]byte, *Version, error) {
+	versions, err := l.versionsLocked(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range versions {
+		if versions[i].Version != version {
+			continue
+		}
+		v := versions[i]
+		data, err := os.ReadFile(filepath.Join(l.historyDir(name), strconv.Itoa(version)+filepath.Ext(v.File)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка чтения версии %d: %w", version, err)
+		}
+		return data, &v, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s версия %d", ErrVersionNotFound, name, version)
+}
+
+func (l *Library) currentLocked(name string) int {
+	path, err := l.dir.find(name)
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	versions, err := l.versionsLocked(name)
+	if err != nil {
+		return 0
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].SHA256 == hash && versions[i].File == filepath.Base(path) {
+			return versions[i].Version
+		}
+	}
+	return 0
+}
+
+func (l *Library) historyDir(name string) string {
+	return filepath.Join(l.dir.path, versionsDir, name)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ошибка кодирования %s: %w", filepath.Base(path), err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic записывает файл через временный файл и переименование,
+// чтобы параллельное чтение сценария не увидело его наполовину записанным
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package scenario_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goszakup-automation/internal/scenario"
+)
+
+const (
+	original = "steps:\n  - type: click\n    x: 10\n    y: 20\n"
+	edited   = "steps:\n  - type: click\n    x: 30\n    y: 40\n"
+)
+
+func newLibrary(t *testing.T, files map[string]string) *scenario.Library {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return scenario.NewLibrary(scenario.NewDir(dir))
+}
+
+func TestUpdateSnapshotsFileWithoutHistory(t *testing.T) {
+	lib := newLibrary(t, map[string]string{"bid.yaml": original})
+
+	v, err := lib.Update("bid", ".yaml", []byte(edited), "новые координаты")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != 2 {
+		t.Fatalf("новая версия %d, ожидалась 2", v.Version)
+	}
+
+	data, first, err := lib.ReadVersion("bid", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original || first.Comment != "исходный файл" {
+		t.Errorf("версия 1: %q (%q)", data, first.Comment)
+	}
+	if got := lib.Current("bid"); got != 2 {
+		t.Errorf("текущая версия %d, ожидалась 2", got)
+	}
+
+	diff, err := lib.Diff("bid", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "-    x: 10") || !strings.Contains(diff, "+    x: 30") {
+		t.Errorf("diff:\n%s", diff)
+	}
+
+	// Откат к исходному файлу
+	if _, err := lib.Rollback("bid", 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := lib.Dir().Read("bid"); err != nil || string(data) != original {
+		t.Errorf("после отката: %q, %v", data, err)
+	}
+}
+
+func TestSnapshotManualEdit(t *testing.T) {
+	lib := newLibrary(t, nil)
+	if _, err := lib.Create("bid", ".yaml", []byte(original), ""); err != nil {
+		t.Fatal(err)
+	}
+	path, _, err := lib.Dir().Read("bid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ручная правка попадает в историю перед удалением
+	if err := lib.Delete("bid"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := lib.Versions("bid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[1].Comment != "изменения вне истории версий" {
+		t.Fatalf("история: %+v", versions)
+	}
+	if data, _, err := lib.ReadVersion("bid", 2); err != nil || string(data) != edited {
+		t.Errorf("версия 2: %q, %v", data, err)
+	}
+
+	// Файл без изменений повторно не записывается
+	if _, err := lib.Rollback("bid", 2, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Update("bid", ".yaml", []byte(original), ""); err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := lib.Versions("bid"); len(versions) != 4 {
+		t.Errorf("история: %+v", versions)
+	}
+}
@@ -0,0 +1,543 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity уровень замечания линтера
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue замечание к файлу сценария с позицией в исходном тексте
+type Issue struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Path путь к элементу сценария, например steps[2].then[1].key
+	Path     string   `json:"path,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String возвращает замечание в формате строка:колонка: уровень: сообщение
+func (i Issue) String() string {
+	if i.Path != "" {
+		return fmt.Sprintf("%d:%d: %s: %s: %s", i.Line, i.Column, i.Severity, i.Path, i.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Severity, i.Message)
+}
+
+// LintOptions параметры проверки
+type LintOptions struct {
+	// ScreenWidth, ScreenHeight размер экрана для проверки координат (0 — не проверять)
+	ScreenWidth  int
+	ScreenHeight int
+}
+
+// HasErrors сообщает, что среди замечаний есть ошибки (а не только предупреждения)
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	scenarioFields  = yamlFields(Scenario{})
+	paramFields     = yamlFields(Param{})
+	stepFields      = yamlFields(sequence.Step{})
+	conditionFields = yamlFields(sequence.Condition{})
+	pixelFields     = yamlFields(sequence.PixelCondition{})
+	imageFields     = yamlFields(sequence.ImageCondition{})
+	textFields      = yamlFields(sequence.TextCondition{})
+	regionFields    = yamlFields(sequence.Region{})
+
+	paramRefPattern = regexp.MustCompile(`\.params\.([A-Za-z_][A-Za-z0-9_]*)`)
+	varRefPattern   = regexp.MustCompile(`\.vars\.([A-Za-z_][A-Za-z0-9_]*)`)
+	yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+)
+
+// linter состояние проверки одного файла
+type linter struct {
+	opts   LintOptions
+	issues []Issue
+
+	// params объявленные параметры и позиции их объявлений
+	params map[string]*yaml.Node
+	// usedParams параметры, на которые ссылаются шаблоны
+	usedParams map[string]bool
+	// outputs переменные, которые задают шаги (output, as)
+	outputs map[string]bool
+	// varRefs ссылки шаблонов на переменные
+	varRefs []varRef
+}
+
+type varRef struct {
+	name string
+	node *yaml.Node
+	path string
+}
+
+// Lint проверяет файл сценария: синтаксис, схему (типы шагов и имена полей), имена клавиш,
+// координаты относительно экрана и ссылки шаблонов на объявленные параметры.
+// Формат определяется по расширению файла, как в Parse.
+func Lint(filename string, data []byte, opts LintOptions) []Issue {
+	l := &linter{
+		opts:       opts,
+		issues:     []Issue{},
+		params:     make(map[string]*yaml.Node),
+		usedParams: make(map[string]bool),
+		outputs:    make(map[string]bool),
+	}
+	l.lintFile(filename, data)
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Line != l.issues[j].Line {
+			return l.issues[i].Line < l.issues[j].Line
+		}
+		return l.issues[i].Column < l.issues[j].Column
+	})
+	return l.issues
+}
+
+func (l *linter) lintFile(filename string, data []byte) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".json":
+		// Позиция синтаксической ошибки JSON точнее, чем у разборщика YAML
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, column := offsetPosition(data, syntaxErr.Offset)
+				l.issues = append(l.issues, Issue{Line: line, Column: column, Severity: SeverityError,
+					Message: fmt.Sprintf("ошибка разбора JSON: %v", err)})
+				return
+			}
+		}
+	case ".yaml", ".yml":
+	default:
+		l.issues = append(l.issues, Issue{Line: 1, Column: 1, Severity: SeverityError,
+			Message: fmt.Sprintf("неподдерживаемый формат сценария %q, ожидается .yaml, .yml или .json", ext)})
+		return
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line := 1
+		if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		l.issues = append(l.issues, Issue{Line: line, Column: 1, Severity: SeverityError,
+			Message: fmt.Sprintf("ошибка разбора: %v", err)})
+		return
+	}
+	if len(doc.Content) == 0 {
+		l.issues = append(l.issues, Issue{Line: 1, Column: 1, Severity: SeverityError, Message: "файл сценария пуст"})
+		return
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.errorf(root, "", "сценарий должен быть объектом с полями params и steps")
+		return
+	}
+	l.checkFields(root, "", scenarioFields)
+
+	if params := mappingValue(root, "params"); params != nil {
+		l.lintParams(params)
+	}
+	steps := mappingValue(root, "steps")
+	if steps == nil {
+		l.errorf(root, "", "не указаны шаги steps")
+	} else {
+		l.lintSteps(steps, "steps", false)
+	}
+	for _, block := range []string{"on_failure", "finally"} {
+		if steps := mappingValue(root, block); steps != nil {
+			l.lintSteps(steps, block, true)
+		}
+	}
+	l.checkRefs()
+
+	// Итоговая проверка тем же кодом, что и при загрузке, на случай ошибок, не найденных выше
+	if !HasErrors(l.issues) {
+		if _, err := Parse(filename, data); err != nil {
+			l.errorf(root, "", "%v", err)
+		}
+	}
+}
+
+func (l *linter) lintParams(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		l.errorf(node, "params", "params должен быть списком")
+		return
+	}
+	for i, item := range node.Content {
+		path := fmt.Sprintf("params[%d]", i+1)
+		if item.Kind != yaml.MappingNode {
+			l.errorf(item, path, "параметр должен быть объектом")
+			continue
+		}
+		l.checkFields(item, path, paramFields)
+
+		var p Param
+		if err := item.Decode(&p); err != nil {
+			l.errorf(item, path, "%s", decodeMessage(err))
+			continue
+		}
+		switch {
+		case p.Name == "":
+			l.errorf(item, path, "у параметра не указано имя")
+			continue
+		case l.params[p.Name] != nil:
+			l.errorf(item, path, "параметр %q объявлен дважды", p.Name)
+			continue
+		}
+		l.params[p.Name] = item
+
+		switch p.Type {
+		case "", ParamString, ParamNumber, ParamBool, ParamList:
+		default:
+			l.errorf(mappingValue(item, "type"), path+".type", "неизвестный тип %q, ожидается string, number, bool или list", p.Type)
+			continue
+		}
+		if p.Default != nil {
+			if err := checkType(p, p.Default); err != nil {
+				l.errorf(mappingValue(item, "default"), path+".default", "значение по умолчанию: %v", err)
+			}
+		}
+	}
+}
+
+// lintSteps проверяет блок шагов
+func (l *linter) lintSteps(node *yaml.Node, path string, optional bool) {
+	if node.Kind != yaml.SequenceNode {
+		l.errorf(node, path, "%s должен быть списком шагов", path)
+		return
+	}
+	if len(node.Content) == 0 && !optional {
+		l.errorf(node, path, "список шагов пуст")
+		return
+	}
+	for i, item := range node.Content {
+		l.lintStep(item, fmt.Sprintf("%s[%d]", path, i+1))
+	}
+}
+
+func (l *linter) lintStep(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		l.errorf(node, path, "шаг должен быть объектом")
+		return
+	}
+
+	before := len(l.issues)
+	l.checkFields(node, path, stepFields)
+	l.collectRefs(node, path)
+
+	var step sequence.Step
+	if err := node.Decode(&step); err != nil {
+		l.errorf(node, path, "%s", decodeMessage(err))
+		return
+	}
+
+	switch {
+	case step.Type == "":
+		l.errorf(node, path, "не указан тип шага")
+		return
+	case !step.Type.Known():
+		l.errorf(mappingValue(node, "type"), path+".type", "неизвестный тип шага %q", step.Type)
+		return
+	}
+
+	switch step.Type {
+	case sequence.StepKeyTap:
+		l.checkKey(mappingValue(node, "key"), path+".key")
+		l.checkKeys(mappingValue(node, "modifiers"), path+".modifiers")
+	case sequence.StepHotkey:
+		l.checkKeys(mappingValue(node, "keys"), path+".keys")
+	}
+
+	l.checkCoord(node, path, "x", l.opts.ScreenWidth)
+	l.checkCoord(node, path, "y", l.opts.ScreenHeight)
+	l.checkCoord(node, path, "button_x", l.opts.ScreenWidth)
+	l.checkCoord(node, path, "button_y", l.opts.ScreenHeight)
+	if region := mappingValue(node, "region"); region != nil {
+		l.lintRegion(region, path+".region")
+	}
+
+	if step.Output != "" {
+		l.outputs[step.Output] = true
+	}
+	if step.Type == sequence.StepForEach {
+		as := step.As
+		if as == "" {
+			as = "item"
+		}
+		l.outputs[as] = true
+		l.outputs[as+"_index"] = true
+	}
+
+	if cond := mappingValue(node, "condition"); cond != nil {
+		l.lintCondition(cond, path+".condition")
+	}
+	if cond := mappingValue(node, "until"); cond != nil {
+		l.lintCondition(cond, path+".until")
+	}
+	for _, block := range []string{"then", "else", "steps"} {
+		if child := mappingValue(node, block); child != nil {
+			l.lintSteps(child, path+"."+block, block != "steps")
+		}
+	}
+
+	// Общая проверка шага, только если вложенные элементы не дали более точных замечаний
+	if len(l.issues) == before {
+		if err := step.Validate(); err != nil {
+			l.errorf(node, path, "%v", err)
+		}
+	}
+}
+
+func (l *linter) lintCondition(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		l.errorf(node, path, "условие должно быть объектом")
+		return
+	}
+	l.checkFields(node, path, conditionFields)
+
+	if pixel := mappingValue(node, "pixel"); pixel != nil {
+		l.checkFields(pixel, path+".pixel", pixelFields)
+		l.checkCoord(pixel, path+".pixel", "x", l.opts.ScreenWidth)
+		l.checkCoord(pixel, path+".pixel", "y", l.opts.ScreenHeight)
+		if color := mappingValue(pixel, "color"); color != nil && !strings.Contains(color.Value, "{{") {
+			if _, err := screen.ParseColor(color.Value); err != nil {
+				l.errorf(color, path+".pixel.color", "%v", err)
+			}
+		}
+	}
+	if image := mappingValue(node, "image"); image != nil {
+		l.checkFields(image, path+".image", imageFields)
+		if region := mappingValue(image, "region"); region != nil {
+			l.lintRegion(region, path+".image.region")
+		}
+	}
+	if text := mappingValue(node, "text"); text != nil {
+		l.checkFields(text, path+".text", textFields)
+		if region := mappingValue(text, "region"); region != nil {
+			l.lintRegion(region, path+".text.region")
+		}
+	}
+	for _, group := range []string{"all", "any"} {
+		list := mappingValue(node, group)
+		if list == nil {
+			continue
+		}
+		if list.Kind != yaml.SequenceNode {
+			l.errorf(list, path+"."+group, "%s должен быть списком условий", group)
+			continue
+		}
+		for i, item := range list.Content {
+			l.lintCondition(item, fmt.Sprintf("%s.%s[%d]", path, group, i+1))
+		}
+	}
+}
+
+// lintRegion проверяет поля области экрана и ее координаты
+func (l *linter) lintRegion(node *yaml.Node, path string) {
+	l.checkFields(node, path, regionFields)
+	l.checkCoord(node, path, "x", l.opts.ScreenWidth)
+	l.checkCoord(node, path, "y", l.opts.ScreenHeight)
+}
+
+// checkFields сообщает о неизвестных полях объекта (опечатки в именах полей)
+func (l *linter) checkFields(node *yaml.Node, path string, known map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !known[key.Value] {
+			l.errorf(key, joinPath(path, key.Value), "неизвестное поле %q", key.Value)
+		}
+	}
+}
+
+// checkKey проверяет имя клавиши, если оно задано литералом, а не шаблоном
+func (l *linter) checkKey(node *yaml.Node, path string) {
+	if node == nil || node.Kind != yaml.ScalarNode || strings.Contains(node.Value, "{{") {
+		return
+	}
+	if !input.IsKnownKey(node.Value) {
+		l.errorf(node, path, "неизвестная клавиша %q", node.Value)
+	}
+}
+
+func (l *linter) checkKeys(node *yaml.Node, path string) {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range node.Content {
+		l.checkKey(item, fmt.Sprintf("%s[%d]", path, i+1))
+	}
+}
+
+// checkCoord проверяет, что координата-литерал находится в пределах экрана
+func (l *linter) checkCoord(node *yaml.Node, path, field string, limit int) {
+	value := mappingValue(node, field)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return
+	}
+	n, err := strconv.Atoi(value.Value)
+	if err != nil {
+		// Шаблоны проверяются при выполнении, остальное — при разборе шага
+		return
+	}
+	if n < 0 || (limit > 0 && n >= limit) {
+		l.errorf(value, joinPath(path, field), "координата %s=%d вне экрана %dx%d", field, n, l.opts.ScreenWidth, l.opts.ScreenHeight)
+	}
+}
+
+// collectRefs собирает ссылки шаблонов шага на параметры и переменные.
+// Вложенные шаги обрабатываются отдельно при их проверке.
+func (l *linter) collectRefs(node *yaml.Node, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "then", "else", "steps":
+			continue
+		}
+		l.collectScalarRefs(node.Content[i+1], joinPath(path, node.Content[i].Value))
+	}
+}
+
+func (l *linter) collectScalarRefs(node *yaml.Node, path string) {
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			l.collectScalarRefs(child, path)
+		}
+		return
+	}
+	if !strings.Contains(node.Value, "{{") && !strings.HasPrefix(node.Value, ".") {
+		return
+	}
+	for _, m := range paramRefPattern.FindAllStringSubmatch(node.Value, -1) {
+		l.usedParams[m[1]] = true
+		if l.params[m[1]] == nil {
+			l.errorf(node, path, "параметр %q не объявлен в params", m[1])
+		}
+	}
+	for _, m := range varRefPattern.FindAllStringSubmatch(node.Value, -1) {
+		l.varRefs = append(l.varRefs, varRef{name: m[1], node: node, path: path})
+	}
+}
+
+// checkRefs сообщает о переменных, которые не задает ни один шаг, и о неиспользуемых параметрах
+func (l *linter) checkRefs() {
+	for _, ref := range l.varRefs {
+		if !l.outputs[ref.name] {
+			l.warnf(ref.node, ref.path, "переменная %q не задается ни одним шагом (output или as)", ref.name)
+		}
+	}
+	for name, node := range l.params {
+		if !l.usedParams[name] {
+			l.warnf(node, "", "параметр %q не используется в шагах", name)
+		}
+	}
+}
+
+func (l *linter) errorf(node *yaml.Node, path, format string, args ...any) {
+	l.add(node, path, SeverityError, fmt.Sprintf(format, args...))
+}
+
+func (l *linter) warnf(node *yaml.Node, path, format string, args ...any) {
+	l.add(node, path, SeverityWarning, fmt.Sprintf(format, args...))
+}
+
+func (l *linter) add(node *yaml.Node, path string, severity Severity, message string) {
+	issue := Issue{Line: 1, Column: 1, Path: path, Severity: severity, Message: message}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// mappingValue возвращает значение поля объекта или nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlFields возвращает имена полей структуры из тегов yaml
+func yamlFields(v any) map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if opts == "inline" {
+			// Поля встроенной структуры записываются на том же уровне
+			for field := range yamlFields(reflect.New(t.Field(i).Type).Elem().Interface()) {
+				fields[field] = true
+			}
+			continue
+		}
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// decodeMessage убирает из ошибки yaml префиксы, дублирующие позицию замечания
+func decodeMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return strings.Join(typeErr.Errors, "; ")
+	}
+	return err.Error()
+}
+
+// offsetPosition переводит смещение в байтах в строку и колонку (с 1)
+func offsetPosition(data []byte, offset int64) (int, int) {
+	line, column := 1, 1
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
@@ -0,0 +1,202 @@
+// Package scenario загружает декларативные сценарии (YAML/JSON) с именованными
+// параметрами и превращает их в последовательности шагов sequence.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"goszakup-automation/internal/sequence"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParamType тип параметра сценария
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamNumber ParamType = "number"
+	ParamBool   ParamType = "bool"
+	ParamList   ParamType = "list"
+)
+
+// Param объявление параметра сценария
+type Param struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Type тип значения (по умолчанию string)
+	Type     ParamType `json:"type,omitempty" yaml:"type,omitempty"`
+	Required bool      `json:"required,omitempty" yaml:"required,omitempty"`
+	// Default значение, если параметр не передан
+	Default any `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Scenario сценарий: описание параметров и шаги, в текстовых полях и координатах которых
+// можно ссылаться на параметры ({{ .params.query }}) и переменные шагов ({{ .vars.name }})
+type Scenario struct {
+	Name        string          `json:"name" yaml:"name"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Params      []Param         `json:"params,omitempty" yaml:"params,omitempty"`
+	Steps       []sequence.Step `json:"steps" yaml:"steps"`
+	// OnFailure шаги после ошибки или отмены основных шагов, Finally — шаги, выполняемые всегда
+	OnFailure []sequence.Step `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	Finally   []sequence.Step `json:"finally,omitempty" yaml:"finally,omitempty"`
+}
+
+// Parse разбирает сценарий. Формат определяется по расширению файла (.json, .yaml, .yml).
+func Parse(filename string, data []byte) (*Scenario, error) {
+	var sc Scenario
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		if err := json.Unmarshal(data, &sc); err != nil {
+			return nil, fmt.Errorf("ошибка разбора JSON: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &sc); err != nil {
+			return nil, fmt.Errorf("ошибка разбора YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат сценария %q", filepath.Ext(filename))
+	}
+
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// Marshal сериализует сценарий в формат, соответствующий расширению файла (.json, .yaml, .yml)
+func Marshal(filename string, sc *Scenario) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		data, err := json.MarshalIndent(sc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("ошибка кодирования JSON: %w", err)
+		}
+		return append(data, '\n'), nil
+	case ".yaml", ".yml":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(sc); err != nil {
+			return nil, fmt.Errorf("ошибка кодирования YAML: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("ошибка кодирования YAML: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат сценария %q", filepath.Ext(filename))
+	}
+}
+
+// Validate проверяет объявления параметров и шаги сценария
+func (sc *Scenario) Validate() error {
+	seen := make(map[string]bool)
+	for _, p := range sc.Params {
+		if p.Name == "" {
+			return errors.New("у параметра не указано имя")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("параметр %q объявлен дважды", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case "", ParamString, ParamNumber, ParamBool, ParamList:
+		default:
+			return fmt.Errorf("параметр %q: неизвестный тип %q", p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if err := checkType(p, p.Default); err != nil {
+				return fmt.Errorf("параметр %q: значение по умолчанию: %w", p.Name, err)
+			}
+		}
+	}
+
+	// Значения шаблонов проверяются при выполнении шага, здесь — структура шагов
+	if err := sequence.Validate(sc.Steps); err != nil {
+		return err
+	}
+	return sc.Cleanup().Validate()
+}
+
+// Cleanup возвращает блоки завершения сценария для Runner
+func (sc *Scenario) Cleanup() sequence.Cleanup {
+	return sequence.Cleanup{OnFailure: sc.OnFailure, Finally: sc.Finally}
+}
+
+// ResolveParams проверяет переданные значения по объявлениям и подставляет значения по умолчанию
+func (sc *Scenario) ResolveParams(values map[string]any) (map[string]any, error) {
+	declared := make(map[string]Param, len(sc.Params))
+	for _, p := range sc.Params {
+		declared[p.Name] = p
+	}
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("неизвестный параметр %q", name)
+		}
+	}
+
+	resolved := make(map[string]any, len(sc.Params))
+	for _, p := range sc.Params {
+		value, ok := values[p.Name]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, fmt.Errorf("не передан обязательный параметр %q", p.Name)
+			}
+			value = p.Default
+		}
+		if value != nil {
+			if err := checkType(p, value); err != nil {
+				return nil, fmt.Errorf("параметр %q: %w", p.Name, err)
+			}
+		}
+		resolved[p.Name] = value
+	}
+	return resolved, nil
+}
+
+// Prepare проверяет параметры запуска и возвращает область видимости для шаблонов шагов
+func (sc *Scenario) Prepare(values map[string]any) (*sequence.Scope, error) {
+	params, err := sc.ResolveParams(values)
+	if err != nil {
+		return nil, err
+	}
+	return sequence.NewScope(params), nil
+}
+
+// checkType проверяет, что значение соответствует типу параметра
+func checkType(p Param, value any) error {
+	ok := true
+	switch p.Type {
+	case "", ParamString:
+		_, ok = value.(string)
+	case ParamNumber:
+		switch value.(type) {
+		case int, int64, float64, json.Number:
+		default:
+			ok = false
+		}
+	case ParamBool:
+		_, ok = value.(bool)
+	case ParamList:
+		_, ok = value.([]any)
+	}
+	if !ok {
+		paramType := p.Type
+		if paramType == "" {
+			paramType = ParamString
+		}
+		return fmt.Errorf("ожидается значение типа %s, получено %T", paramType, value)
+	}
+	return nil
+}
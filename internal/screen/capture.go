@@ -0,0 +1,159 @@
+package screen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"goszakup-automation/internal/input"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	// ErrDisplayNotFound возвращается, если монитора с указанным номером нет
+	ErrDisplayNotFound = errors.New("монитор не найден")
+	// ErrBadCapture возвращается при неверных параметрах снимка (область, масштаб, формат)
+	ErrBadCapture = errors.New("неверные параметры снимка экрана")
+)
+
+// Форматы снимка экрана
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+)
+
+// defaultJPEGQuality качество JPEG, если не указано
+const defaultJPEGQuality = 80
+
+// CaptureOptions параметры снимка экрана
+type CaptureOptions struct {
+	// Region область снимка; при указанном Display — относительно левого верхнего угла монитора.
+	// Пустая область означает весь экран (или весь монитор).
+	Region image.Rectangle
+	// Display номер монитора (nil — весь экран)
+	Display *int
+	// Scale коэффициент уменьшения от 0 до 1 (0 или 1 — исходный размер)
+	Scale float64
+}
+
+// Capture снимает экран по параметрам opts. Возвращает изображение и снятую область
+// в координатах экрана (до уменьшения).
+func Capture(ctx context.Context, service *input.Service, opts CaptureOptions) (image.Image, image.Rectangle, error) {
+	if opts.Scale < 0 || opts.Scale > 1 {
+		return nil, image.Rectangle{}, fmt.Errorf("%w: масштаб %g вне диапазона (0, 1]", ErrBadCapture, opts.Scale)
+	}
+
+	rect, err := resolveRegion(service, opts)
+	if err != nil {
+		return nil, image.Rectangle{}, err
+	}
+
+	img, err := service.CaptureScreen(ctx, rect)
+	if err != nil {
+		return nil, image.Rectangle{}, err
+	}
+	if rect.Empty() {
+		rect = img.Bounds().Sub(img.Bounds().Min)
+	} else {
+		// Бэкенд обрезает область по границам экрана
+		rect = image.Rectangle{Min: rect.Min, Max: rect.Min.Add(img.Bounds().Size())}
+	}
+
+	if opts.Scale > 0 && opts.Scale < 1 {
+		img = Downscale(img, opts.Scale)
+	}
+	return img, rect, nil
+}
+
+// resolveRegion переводит область и номер монитора в прямоугольник в координатах экрана
+func resolveRegion(service *input.Service, opts CaptureOptions) (image.Rectangle, error) {
+	if opts.Region.Min.X < 0 || opts.Region.Min.Y < 0 || opts.Region.Dx() < 0 || opts.Region.Dy() < 0 {
+		return image.Rectangle{}, fmt.Errorf("%w: область %v", ErrBadCapture, opts.Region)
+	}
+	if opts.Display == nil {
+		return opts.Region, nil
+	}
+
+	displays, err := service.Displays()
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	index := *opts.Display
+	if index < 0 || index >= len(displays) {
+		return image.Rectangle{}, fmt.Errorf("%w: %d (доступно мониторов: %d)", ErrDisplayNotFound, index, len(displays))
+	}
+
+	display := displays[index]
+	if opts.Region.Empty() {
+		return display, nil
+	}
+	rect := opts.Region.Add(display.Min).Intersect(display)
+	if rect.Empty() {
+		return image.Rectangle{}, fmt.Errorf("%w: область %v вне монитора %d", ErrBadCapture, opts.Region, index)
+	}
+	return rect, nil
+}
+
+// Downscale уменьшает изображение в scale раз (билинейная интерполяция)
+func Downscale(img image.Image, scale float64) image.Image {
+	b := img.Bounds()
+	width := max(1, int(float64(b.Dx())*scale+0.5))
+	height := max(1, int(float64(b.Dy())*scale+0.5))
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(out, out.Bounds(), img, b, draw.Src, nil)
+	return out
+}
+
+// NormalizeFormat приводит название формата к png или jpeg (пустое — png)
+func NormalizeFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "png":
+		return FormatPNG, nil
+	case "jpeg", "jpg":
+		return FormatJPEG, nil
+	default:
+		return "", fmt.Errorf("%w: формат %q (доступны png, jpeg)", ErrBadCapture, format)
+	}
+}
+
+// Encode кодирует изображение в формате png или jpeg. quality — качество JPEG от 1 до 100
+// (0 — по умолчанию 80), для PNG не используется.
+func Encode(img image.Image, format string, quality int) ([]byte, error) {
+	format, err := NormalizeFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJPEG:
+		if quality == 0 {
+			quality = defaultJPEGQuality
+		}
+		if quality < 1 || quality > 100 {
+			return nil, fmt.Errorf("%w: качество JPEG %d вне диапазона 1-100", ErrBadCapture, quality)
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка кодирования снимка экрана: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentType возвращает MIME-тип формата снимка
+func ContentType(format string) string {
+	if format == FormatJPEG {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
@@ -0,0 +1,92 @@
+// Package screen содержит снимки и проверки содержимого экрана: цвет пикселя и т.п.
+// Скриншоты снимаются через input.Service (бэкенд ввода).
+package screen
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"goszakup-automation/internal/input"
+)
+
+// ParseColor разбирает цвет в формате #RRGGBB или RRGGBB
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("неверный формат цвета %q, ожидается #RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("неверный формат цвета %q, ожидается #RRGGBB", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// FormatColor возвращает цвет в формате #RRGGBB
+func FormatColor(c color.Color) string {
+	rgba := toRGBA(c)
+	return fmt.Sprintf("#%02X%02X%02X", rgba.R, rgba.G, rgba.B)
+}
+
+// ColorMatches сообщает, что каждая компонента цвета отличается от ожидаемой не больше чем на tolerance
+func ColorMatches(c color.Color, want color.RGBA, tolerance int) bool {
+	got := toRGBA(c)
+	return absDiff(got.R, want.R) <= tolerance &&
+		absDiff(got.G, want.G) <= tolerance &&
+		absDiff(got.B, want.B) <= tolerance
+}
+
+// Pixel возвращает цвет пикселя экрана в точке (x, y)
+func Pixel(ctx context.Context, service *input.Service, x, y int) (color.RGBA, error) {
+	img, err := service.CaptureScreen(ctx, image.Rect(x, y, x+1, y+1))
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	b := img.Bounds()
+	return toRGBA(img.At(b.Min.X, b.Min.Y)), nil
+}
+
+// AverageColor возвращает средний цвет области экрана
+func AverageColor(ctx context.Context, service *input.Service, rect image.Rectangle) (color.RGBA, error) {
+	if rect.Empty() {
+		return color.RGBA{}, fmt.Errorf("%w: пустая область %v", ErrBadCapture, rect)
+	}
+	img, err := service.CaptureScreen(ctx, rect)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	return Average(img), nil
+}
+
+// Average возвращает средний цвет изображения
+func Average(img image.Image) color.RGBA {
+	b := img.Bounds()
+	var r, g, bl, n int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := toRGBA(img.At(x, y))
+			r, g, bl = r+int(c.R), g+int(c.G), bl+int(c.B)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{A: 0xff}
+	}
+	return color.RGBA{R: uint8((r + n/2) / n), G: uint8((g + n/2) / n), B: uint8((bl + n/2) / n), A: 0xff}
+}
+
+func toRGBA(c color.Color) color.RGBA {
+	r, g, b, a := c.RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
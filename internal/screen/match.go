@@ -0,0 +1,602 @@
+package screen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+
+	"goszakup-automation/internal/input"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrImageNotFound возвращается, если изображение-шаблон не найдено на экране с нужной степенью совпадения
+var ErrImageNotFound = errors.New("изображение не найдено на экране")
+
+const (
+	// minPyramidSide минимальная сторона шаблона на грубом уровне пирамиды
+	minPyramidSide = 8
+	// maxPyramidLevels максимальное число уменьшений вдвое
+	maxPyramidLevels = 4
+	// coarseCandidates сколько лучших позиций грубого уровня уточняется на полном разрешении
+	coarseCandidates = 8
+	// refineRadius радиус уточнения позиции на каждом следующем уровне пирамиды
+	refineRadius = 2
+)
+
+// Match найденное изображение
+type Match struct {
+	// X, Y центр совпадения в координатах экрана (точка для клика)
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Left, Top, Width, Height область совпадения
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Confidence степень совпадения от 0 до 1 (нормированная взаимная корреляция)
+	Confidence float64 `json:"confidence"`
+	// Scale масштаб шаблона, при котором найдено совпадение
+	Scale float64 `json:"scale"`
+}
+
+// MatchMode способ сравнения шаблона с экраном
+type MatchMode string
+
+const (
+	// MatchGray сравнение яркости (по умолчанию)
+	MatchGray MatchMode = "gray"
+	// MatchEdge сравнение контуров: не зависит от заливки, темы и оттенка элементов
+	MatchEdge MatchMode = "edge"
+)
+
+// Ограничения диапазона масштабов шаблона
+const (
+	minMatchScale = 0.25
+	maxMatchScale = 4
+	// defaultScaleStep шаг перебора масштабов, если он не указан
+	defaultScaleStep = 0.05
+	// maxScaleSteps максимальное число перебираемых масштабов
+	maxScaleSteps = 64
+)
+
+// MatchOptions параметры поиска шаблона. Нулевые значения заменяются значениями по умолчанию
+// (WithDefaults), без них масштаб шаблона не меняется.
+type MatchOptions struct {
+	// Threshold минимальная степень совпадения (0-1)
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty" form:"threshold"`
+	// Mode gray (яркость) или edge (контуры)
+	Mode MatchMode `json:"match_mode,omitempty" yaml:"match_mode,omitempty" form:"match_mode"`
+	// MinScale, MaxScale диапазон масштабов шаблона, например 0.65-1.55 для шаблона,
+	// снятого при масштабировании экрана 125% и искомого при 100% или 150%
+	MinScale float64 `json:"min_scale,omitempty" yaml:"min_scale,omitempty" form:"min_scale"`
+	MaxScale float64 `json:"max_scale,omitempty" yaml:"max_scale,omitempty" form:"max_scale"`
+	// ScaleStep шаг перебора масштабов (по умолчанию 0.05)
+	ScaleStep float64 `json:"scale_step,omitempty" yaml:"scale_step,omitempty" form:"scale_step"`
+}
+
+// WithDefaults возвращает параметры, в которых незаданные поля взяты из defaults.
+// Диапазон масштабов берется из defaults целиком, если в opts не указана ни одна его граница.
+func (o MatchOptions) WithDefaults(defaults MatchOptions) MatchOptions {
+	if o.Threshold == 0 {
+		o.Threshold = defaults.Threshold
+	}
+	if o.Mode == "" {
+		o.Mode = defaults.Mode
+	}
+	if o.MinScale == 0 && o.MaxScale == 0 {
+		o.MinScale, o.MaxScale = defaults.MinScale, defaults.MaxScale
+	}
+	if o.ScaleStep == 0 {
+		o.ScaleStep = defaults.ScaleStep
+	}
+	return o
+}
+
+// Validate проверяет параметры поиска
+func (o MatchOptions) Validate() error {
+	if o.Threshold < 0 || o.Threshold > 1 {
+		return errors.New("threshold должен быть от 0 до 1")
+	}
+	switch o.Mode {
+	case "", MatchGray, MatchEdge:
+	default:
+		return fmt.Errorf("неизвестный match_mode %q (доступны %s, %s)", o.Mode, MatchGray, MatchEdge)
+	}
+	minScale, maxScale := o.scaleRange()
+	if minScale < minMatchScale || maxScale > maxMatchScale {
+		return fmt.Errorf("min_scale и max_scale должны быть от %g до %g", float64(minMatchScale), float64(maxMatchScale))
+	}
+	if minScale > maxScale {
+		return errors.New("min_scale больше max_scale")
+	}
+	if o.ScaleStep < 0 {
+		return errors.New("scale_step не может быть отрицательным")
+	}
+	if step := o.scaleStep(); (maxScale-minScale)/step > maxScaleSteps {
+		return fmt.Errorf("слишком много масштабов: не больше %d шагов scale_step в диапазоне", maxScaleSteps)
+	}
+	return nil
+}
+
+// scaleRange возвращает диапазон масштабов; незаданная граница равна другой или 1
+func (o MatchOptions) scaleRange() (float64, float64) {
+	minScale, maxScale := o.MinScale, o.MaxScale
+	switch {
+	case minScale == 0 && maxScale == 0:
+		return 1, 1
+	case minScale == 0:
+		minScale = min(1, maxScale)
+	case maxScale == 0:
+		maxScale = max(1, minScale)
+	}
+	return minScale, maxScale
+}
+
+func (o MatchOptions) scaleStep() float64 {
+	if o.ScaleStep > 0 {
+		return o.ScaleStep
+	}
+	return defaultScaleStep
+}
+
+// scales возвращает перебираемые масштабы в порядке удаления от 1, чтобы при равной степени
+// совпадения выигрывал исходный размер
+func (o MatchOptions) scales() []float64 {
+	minScale, maxScale := o.scaleRange()
+	step := o.scaleStep()
+
+	// Сетка масштабов привязана к 1, чтобы в нее попадали частые отношения 0.8, 1.25 и 1.5
+	scales := []float64{minScale, maxScale}
+	for k := math.Ceil((minScale - 1) / step); 1+k*step <= maxScale; k++ {
+		if s := math.Round((1+k*step)*1000) / 1000; s >= minScale && s <= maxScale {
+			scales = append(scales, s)
+		}
+	}
+	slices.Sort(scales)
+	scales = slices.Compact(scales)
+	slices.SortStableFunc(scales, func(a, b float64) int {
+		da, db := math.Abs(a-1), math.Abs(b-1)
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	return scales
+}
+
+// Locate ищет шаблон на экране в области region (пустая — весь экран). Возвращает лучшее
+// совпадение; если его степень ниже opts.Threshold, вместе с ним возвращается ErrImageNotFound.
+func Locate(ctx context.Context, service *input.Service, tmpl image.Image, region image.Rectangle, opts MatchOptions) (Match, error) {
+	img, err := service.CaptureScreen(ctx, region)
+	if err != nil {
+		return Match{}, err
+	}
+	origin := region.Min
+	if region.Empty() {
+		origin = image.Point{}
+	}
+
+	match, err := FindTemplate(img, tmpl, opts)
+	if err != nil {
+		return Match{}, err
+	}
+	match.Left += origin.X
+	match.Top += origin.Y
+	match.X += origin.X
+	match.Y += origin.Y
+	if match.Confidence < opts.Threshold {
+		return match, fmt.Errorf("%w: лучшее совпадение %.2f ниже порога %.2f", ErrImageNotFound, match.Confidence, opts.Threshold)
+	}
+	return match, nil
+}
+
+// FindTemplate находит лучшее совпадение шаблона на изображении (координаты относительно
+// его левого верхнего угла) среди масштабов шаблона opts. Поиск выполняется по пирамиде
+// изображений: полный перебор на грубом уровне и уточнение лучших позиций на каждом следующем.
+// Пирамида изображения строится один раз и используется для всех масштабов.
+func FindTemplate(img, tmpl image.Image, opts MatchOptions) (Match, error) {
+	if tmpl.Bounds().Empty() {
+		return Match{}, fmt.Errorf("%w: пустой шаблон", ErrBadCapture)
+	}
+	if err := opts.Validate(); err != nil {
+		return Match{}, fmt.Errorf("%w: %v", ErrBadCapture, err)
+	}
+
+	src := prepare(toGray(img), opts.Mode)
+
+	// Пирамиды шаблона для каждого масштаба: уровень 0 — полное разрешение
+	var scales []scaledTemplate
+	depth := 1
+	for _, scale := range opts.scales() {
+		t := tmpl
+		if scale != 1 {
+			t = scaleImage(tmpl, scale)
+		}
+		g := prepare(toGray(t), opts.Mode)
+		if g.w == 0 || g.h == 0 || g.w > src.w || g.h > src.h {
+			continue
+		}
+		levels := []*templateStats{newTemplateStats(g)}
+		for len(levels) <= maxPyramidLevels && min(g.w, g.h)/2 >= minPyramidSide {
+			g = g.half()
+			levels = append(levels, newTemplateStats(g))
+		}
+		scales = append(scales, scaledTemplate{scale: scale, levels: levels})
+		depth = max(depth, len(levels))
+	}
+
+	// Пирамида изображения строится один раз для всех масштабов
+	pyramid := []*sourceLevel{newSourceLevel(src)}
+	for len(pyramid) < depth {
+		pyramid = append(pyramid, newSourceLevel(pyramid[len(pyramid)-1].src.half()))
+	}
+
+	// Масштабы перебираются параллельно, лучший выбирается в порядке opts.scales()
+	results := make([]Match, len(scales))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i := range scales {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			results[i] = scales[i].find(pyramid)
+			<-sem
+		}()
+	}
+	wg.Wait()
+
+	var best *Match
+	for i := range results {
+		if best == nil || results[i].Confidence > best.Confidence {
+			best = &results[i]
+		}
+	}
+	if best != nil && len(scales) > 1 {
+		*best = refineScale(pyramid[0], tmpl, opts, *best)
+	}
+
+	if best == nil {
+		b := tmpl.Bounds()
+		return Match{}, fmt.Errorf("%w: шаблон %dx%d больше области поиска %dx%d", ErrImageNotFound, b.Dx(), b.Dy(), src.w, src.h)
+	}
+	best.Confidence = math.Max(0, math.Round(best.Confidence*1000)/1000)
+	return *best, nil
+}
+
+// scaleImage изменяет размер шаблона в scale раз
+func scaleImage(img image.Image, scale float64) image.Image {
+	b := img.Bounds()
+	width := int(math.Round(float64(b.Dx()) * scale))
+	height := int(math.Round(float64(b.Dy()) * scale))
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	if !out.Bounds().Empty() {
+		draw.CatmullRom.Scale(out, out.Bounds(), img, b, draw.Src, nil)
+	}
+	return out
+}
+
+// refineScale уточняет масштаб лучшего совпадения между шагами перебора: проверяет масштабы
+// на полшага и на четверть шага от лучшего на полном разрешении в окрестности найденной позиции
+func refineScale(l *sourceLevel, tmpl image.Image, opts MatchOptions, best Match) Match {
+	minScale, maxScale := opts.scaleRange()
+	step := opts.scaleStep()
+	for _, d := range []float64{step / 2, step / 4} {
+		base := best.Scale
+		for _, scale := range []float64{base - d, base + d} {
+			scale = math.Round(scale*1000) / 1000
+			if scale < minScale || scale > maxScale {
+				continue
+			}
+			g := prepare(toGray(scaleImage(tmpl, scale)), opts.Mode)
+			if g.w == 0 || g.h == 0 || g.w > l.src.w || g.h > l.src.h {
+				continue
+			}
+			// Ошибка масштаба смещает найденную позицию примерно на половину разницы размеров
+			radius := refineRadius + int(math.Ceil(d*float64(max(g.w, g.h))/2))
+			c := refine(l, newTemplateStats(g), best.X-g.w/2, best.Y-g.h/2, radius)
+			if c.score > best.Confidence {
+				best = Match{
+					X:          c.x + g.w/2,
+					Y:          c.y + g.h/2,
+					Left:       c.x,
+					Top:        c.y,
+					Width:      g.w,
+					Height:     g.h,
+					Confidence: c.score,
+					Scale:      scale,
+				}
+			}
+		}
+	}
+	return best
+}
+
+// scaledTemplate пирамида шаблона одного масштаба
+type scaledTemplate struct {
+	scale  float64
+	levels []*templateStats
+}
+
+// find ищет шаблон по пирамиде изображения: полный перебор на грубом уровне
+// и уточнение лучших позиций на каждом следующем
+func (st scaledTemplate) find(pyramid []*sourceLevel) Match {
+	top := len(st.levels) - 1
+	best := scored{score: math.Inf(-1)}
+	for _, c := range searchAll(pyramid[top], st.levels[top]) {
+		for l := top - 1; l >= 0; l-- {
+			c = refine(pyramid[l], st.levels[l], c.x*2, c.y*2, refineRadius)
+		}
+		if c.score > best.score {
+			best = c
+		}
+	}
+
+	w, h := st.levels[0].w, st.levels[0].h
+	return Match{
+		X:          best.x + w/2,
+		Y:          best.y + h/2,
+		Left:       best.x,
+		Top:        best.y,
+		Width:      w,
+		Height:     h,
+		Confidence: best.score,
+		Scale:      st.scale,
+	}
+}
+
+// scored позиция шаблона и степень совпадения в ней
+type scored struct {
+	x, y  int
+	score float64
+}
+
+// sourceLevel уровень пирамиды изображения и его интегральные суммы
+type sourceLevel struct {
+	src *grayImage
+	ii  *integral
+}
+
+func newSourceLevel(src *grayImage) *sourceLevel {
+	return &sourceLevel{src: src, ii: newIntegral(src)}
+}
+
+// searchAll перебирает все позиции шаблона ts на уровне l и возвращает лучшие локальные
+// максимумы, не перекрывающие друг друга
+func searchAll(l *sourceLevel, ts *templateStats) []scored {
+	w, h := l.src.w-ts.w+1, l.src.h-ts.h+1
+	scores := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			scores[y*w+x] = ts.ncc(l.src, l.ii, x, y)
+		}
+	}
+
+	// Кандидаты — позиции, степень совпадения в которых не меньше, чем у соседей
+	var peaks []scored
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := scores[y*w+x]
+			peak := true
+			for dy := -1; dy <= 1 && peak; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx >= 0 && ny >= 0 && nx < w && ny < h && scores[ny*w+nx] > v {
+						peak = false
+						break
+					}
+				}
+			}
+			if peak {
+				peaks = append(peaks, scored{x: x, y: y, score: v})
+			}
+		}
+	}
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].score > peaks[j].score })
+
+	// Подавление соседних позиций: кандидаты должны отстоять друг от друга на полшаблона
+	var picked []scored
+	for _, c := range peaks {
+		if len(picked) == coarseCandidates {
+			break
+		}
+		near := false
+		for _, p := range picked {
+			if abs(p.x-c.x) < max(1, ts.w/2) && abs(p.y-c.y) < max(1, ts.h/2) {
+				near = true
+				break
+			}
+		}
+		if !near {
+			picked = append(picked, c)
+		}
+	}
+	return picked
+}
+
+// refine ищет лучшую позицию шаблона ts в окрестности (cx, cy) радиуса radius на уровне l
+func refine(l *sourceLevel, ts *templateStats, cx, cy, radius int) scored {
+	best := scored{x: min(cx, l.src.w-ts.w), y: min(cy, l.src.h-ts.h), score: math.Inf(-1)}
+	for y := max(0, cy-radius); y <= min(l.src.h-ts.h, cy+radius); y++ {
+		for x := max(0, cx-radius); x <= min(l.src.w-ts.w, cx+radius); x++ {
+			if score := ts.ncc(l.src, l.ii, x, y); score > best.score {
+				best = scored{x: x, y: y, score: score}
+			}
+		}
+	}
+	return best
+}
+
+// grayImage изображение в оттенках серого
+type grayImage struct {
+	w, h int
+	pix  []float32
+}
+
+// toGray переводит изображение в оттенки серого (яркость по BT.601)
+func toGray(img image.Image) *grayImage {
+	b := img.Bounds()
+	g := &grayImage{w: b.Dx(), h: b.Dy(), pix: make([]float32, b.Dx()*b.Dy())}
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := 0; y < g.h; y++ {
+			row := rgba.Pix[(y+b.Min.Y-rgba.Rect.Min.Y)*rgba.Stride+(b.Min.X-rgba.Rect.Min.X)*4:]
+			for x := 0; x < g.w; x++ {
+				p := row[x*4 : x*4+3]
+				g.pix[y*g.w+x] = 0.299*float32(p[0]) + 0.587*float32(p[1]) + 0.114*float32(p[2])
+			}
+		}
+		return g
+	}
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			c := toRGBA(img.At(b.Min.X+x, b.Min.Y+y))
+			g.pix[y*g.w+x] = 0.299*float32(c.R) + 0.587*float32(c.G) + 0.114*float32(c.B)
+		}
+	}
+	return g
+}
+
+// prepare подготавливает изображение к сравнению в режиме mode: для MatchEdge — карта контуров
+func prepare(g *grayImage, mode MatchMode) *grayImage {
+	if mode == MatchEdge {
+		return g.edges()
+	}
+	return g
+}
+
+// edgeSaturation модуль градиента, при котором контур достигает половины максимальной яркости
+const edgeSaturation = 16
+
+// edges возвращает модуль градиента яркости (оператор Собеля). Контуры не зависят от заливки
+// и цветовой темы, поэтому шаблон совпадает при другом фоне или оттенке элемента.
+func (g *grayImage) edges() *grayImage {
+	out := &grayImage{w: g.w, h: g.h, pix: make([]float32, len(g.pix))}
+	at := func(x, y int) float32 {
+		return g.pix[min(max(y, 0), g.h-1)*g.w+min(max(x, 0), g.w-1)]
+	}
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			mag := float32(math.Sqrt(float64(gx*gx + gy*gy)))
+			// Мягкое насыщение: контур виден одинаково при разном контрасте заливки и текста
+			out.pix[y*g.w+x] = 255 * mag / (mag + edgeSaturation)
+		}
+	}
+	return out
+}
+
+// half уменьшает изображение вдвое усреднением блоков 2x2
+func (g *grayImage) half() *grayImage {
+	out := &grayImage{w: g.w / 2, h: g.h / 2}
+	out.pix = make([]float32, out.w*out.h)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			i := 2*y*g.w + 2*x
+			out.pix[y*out.w+x] = (g.pix[i] + g.pix[i+1] + g.pix[i+g.w] + g.pix[i+g.w+1]) / 4
+		}
+	}
+	return out
+}
+
+// integral интегральные изображения суммы и суммы квадратов яркости
+type integral struct {
+	w       int
+	sum, sq []float64
+}
+
+func newIntegral(g *grayImage) *integral {
+	w := g.w + 1
+	ii := &integral{w: w, sum: make([]float64, w*(g.h+1)), sq: make([]float64, w*(g.h+1))}
+	for y := 0; y < g.h; y++ {
+		var rowSum, rowSq float64
+		for x := 0; x < g.w; x++ {
+			v := float64(g.pix[y*g.w+x])
+			rowSum += v
+			rowSq += v * v
+			ii.sum[(y+1)*w+x+1] = ii.sum[y*w+x+1] + rowSum
+			ii.sq[(y+1)*w+x+1] = ii.sq[y*w+x+1] + rowSq
+		}
+	}
+	return ii
+}
+
+// rect возвращает сумму и сумму квадратов в прямоугольнике (x, y, w, h)
+func (ii *integral) rect(x, y, w, h int) (float64, float64) {
+	a, b, c, d := y*ii.w+x, y*ii.w+x+w, (y+h)*ii.w+x, (y+h)*ii.w+x+w
+	return ii.sum[d] - ii.sum[b] - ii.sum[c] + ii.sum[a], ii.sq[d] - ii.sq[b] - ii.sq[c] + ii.sq[a]
+}
+
+// templateStats шаблон с вычтенным средним для нормированной корреляции
+type templateStats struct {
+	w, h int
+	// centered яркость минус средняя яркость шаблона
+	centered []float32
+	mean     float64
+	// energy сумма квадратов centered
+	energy float64
+}
+
+func newTemplateStats(t *grayImage) *templateStats {
+	var sum float64
+	for _, v := range t.pix {
+		sum += float64(v)
+	}
+	ts := &templateStats{w: t.w, h: t.h, centered: make([]float32, len(t.pix)), mean: sum / float64(len(t.pix))}
+	for i, v := range t.pix {
+		d := float64(v) - ts.mean
+		ts.centered[i] = float32(d)
+		ts.energy += d * d
+	}
+	return ts
+}
+
+// flatEpsilon дисперсия, ниже которой область считается однотонной
+const flatEpsilon = 1e-3
+
+// ncc нормированная взаимная корреляция шаблона и области изображения в позиции (x, y).
+// Однотонный шаблон сравнивается по средней яркости.
+func (ts *templateStats) ncc(src *grayImage, ii *integral, x, y int) float64 {
+	n := float64(ts.w * ts.h)
+	sum, sq := ii.rect(x, y, ts.w, ts.h)
+	variance := sq - sum*sum/n
+
+	if ts.energy/n < flatEpsilon {
+		if variance/n >= flatEpsilon {
+			return 0
+		}
+		return 1 - math.Abs(sum/n-ts.mean)/255
+	}
+	if variance/n < flatEpsilon {
+		return 0
+	}
+
+	var dot float64
+	for j := 0; j < ts.h; j++ {
+		row := src.pix[(y+j)*src.w+x : (y+j)*src.w+x+ts.w]
+		trow := ts.centered[j*ts.w : (j+1)*ts.w]
+		var rowDot float32
+		for i, v := range row {
+			rowDot += v * trow[i]
+		}
+		dot += float64(rowDot)
+	}
+	return dot / math.Sqrt(variance*ts.energy)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
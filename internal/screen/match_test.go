@@ -0,0 +1,254 @@
+package screen_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/screen"
+
+	"go.uber.org/zap"
+)
+
+// Размер шаблона при масштабе 1
+const (
+	tmplWidth  = 48
+	tmplHeight = 28
+)
+
+// theme цвета кнопки: фон, рамка, значок и надпись
+type theme struct {
+	fill, border, icon, label uint8
+}
+
+var (
+	light = theme{fill: 225, border: 70, icon: 30, label: 120}
+	// dark та же кнопка в темной теме: другие заливка и оттенки, те же контуры
+	dark = theme{fill: 45, border: 160, icon: 230, label: 95}
+)
+
+// button яркость кнопки в точке (u, v) от 0 до 1: рамка, круглый значок слева и полоса
+// надписи справа. mirrored — похожая кнопка со значком справа.
+func button(th theme, mirrored bool, u, v float64) uint8 {
+	if mirrored {
+		u = 1 - u
+	}
+	switch {
+	case u < 0.06 || u > 0.94 || v < 0.1 || v > 0.9:
+		return th.border
+	case math.Hypot((u-0.27)*tmplWidth, (v-0.5)*tmplHeight) < 7:
+		return th.icon
+	case u > 0.5 && u < 0.85 && v > 0.4 && v < 0.6:
+		return th.label
+	}
+	return th.fill
+}
+
+// drawButton рисует кнопку масштаба scale с левым верхним углом в (x, y)
+func drawButton(img *image.RGBA, x, y int, scale float64, th theme, mirrored bool) image.Rectangle {
+	w := int(math.Round(tmplWidth * scale))
+	h := int(math.Round(tmplHeight * scale))
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			g := button(th, mirrored, (float64(i)+0.5)/float64(w), (float64(j)+0.5)/float64(h))
+			img.Set(x+i, y+j, color.RGBA{R: g, G: g, B: g, A: 255})
+		}
+	}
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// template шаблон кнопки в светлой теме при масштабе 1
+func template() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, tmplWidth, tmplHeight))
+	drawButton(img, 0, 0, 1, light, false)
+	return img
+}
+
+// desktop экран width x height с шумным градиентным фоном
+func desktop(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			g := uint8(150 + 40*x/width + rnd.IntN(16))
+			img.Set(x, y, color.RGBA{R: g, G: g, B: g, A: 255})
+		}
+	}
+	return img
+}
+
+func TestFindTemplateLocation(t *testing.T) {
+	img := desktop(400, 300)
+	// Похожая кнопка со значком справа не должна перехватить совпадение
+	drawButton(img, 20, 30, 1, light, true)
+	want := drawButton(img, 237, 181, 1, light, false)
+
+	match, err := screen.FindTemplate(img, template(), screen.MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Left != want.Min.X || match.Top != want.Min.Y || match.Width != tmplWidth || match.Height != tmplHeight {
+		t.Errorf("совпадение %+v, ожидалась область %v", match, want)
+	}
+	if match.X != want.Min.X+tmplWidth/2 || match.Y != want.Min.Y+tmplHeight/2 {
+		t.Errorf("центр (%d, %d)", match.X, match.Y)
+	}
+	if match.Confidence < 0.99 || match.Scale != 1 {
+		t.Errorf("степень совпадения %.3f, масштаб %g", match.Confidence, match.Scale)
+	}
+}
+
+func TestLocateThreshold(t *testing.T) {
+	backend := fake.New(fake.Options{Platform: "linux", Width: 400, Height: 300})
+	service := input.NewService(zap.NewNop(), backend)
+	img := desktop(400, 300)
+	drawButton(img, 20, 30, 1, light, true)
+	backend.AddFrame(0, img)
+
+	// Только похожая кнопка: лучшее совпадение возвращается вместе с ErrImageNotFound
+	opts := screen.MatchOptions{Threshold: 0.9}
+	match, err := screen.Locate(context.Background(), service, template(), image.Rectangle{}, opts)
+	if !errors.Is(err, screen.ErrImageNotFound) || !strings.Contains(err.Error(), "ниже порога 0.90") {
+		t.Fatalf("ошибка %v", err)
+	}
+	if match.Confidence >= opts.Threshold || match.Confidence < 0.3 {
+		t.Errorf("степень совпадения похожей кнопки %.3f", match.Confidence)
+	}
+
+	// Нужная кнопка появилась: координаты в области переводятся в координаты экрана
+	want := drawButton(img, 301, 211, 1, light, false)
+	backend.AddFrame(0, img)
+	region := image.Rect(200, 150, 400, 300)
+	match, err = screen.Locate(context.Background(), service, template(), region, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Left != want.Min.X || match.Top != want.Min.Y || match.X != want.Min.X+tmplWidth/2 {
+		t.Errorf("совпадение %+v, ожидалась область %v", match, want)
+	}
+}
+
+func TestFindTemplateScales(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale float64
+		opts  screen.MatchOptions
+	}{
+		// 1.25 и 1.5 попадают в сетку масштабов, привязанную к 1, и при границе вне сетки
+		{name: "125%", scale: 1.25, opts: screen.MatchOptions{MinScale: 0.77, MaxScale: 1.6}},
+		{name: "150%", scale: 1.5, opts: screen.MatchOptions{MinScale: 0.77, MaxScale: 1.6}},
+		// Масштаб между шагами перебора уточняется на полшага и четверть шага
+		{name: "125% между шагами", scale: 1.25, opts: screen.MatchOptions{MinScale: 1, MaxScale: 1.6, ScaleStep: 0.1}},
+		{name: "уменьшенный", scale: 0.8, opts: screen.MatchOptions{MinScale: 0.6, MaxScale: 1.2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := desktop(400, 300)
+			want := drawButton(img, 151, 97, tt.scale, light, false)
+
+			match, err := screen.FindTemplate(img, template(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(match.Scale-tt.scale) > 0.03 {
+				t.Errorf("масштаб %g, ожидался %g", match.Scale, tt.scale)
+			}
+			if abs(match.X-(want.Min.X+want.Dx()/2)) > 1 || abs(match.Y-(want.Min.Y+want.Dy()/2)) > 1 {
+				t.Errorf("центр (%d, %d), ожидался в области %v", match.X, match.Y, want)
+			}
+			if abs(match.Width-want.Dx()) > 1 || abs(match.Height-want.Dy()) > 1 {
+				t.Errorf("размер %dx%d, ожидался %dx%d", match.Width, match.Height, want.Dx(), want.Dy())
+			}
+			if match.Confidence < 0.9 {
+				t.Errorf("степень совпадения %.3f", match.Confidence)
+			}
+		})
+	}
+}
+
+func TestFindTemplateEdgeMode(t *testing.T) {
+	img := desktop(400, 300)
+	want := drawButton(img, 88, 160, 1, dark, false)
+
+	// Яркость темной кнопки не совпадает со светлым шаблоном
+	gray, err := screen.FindTemplate(img, template(), screen.MatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gray.Confidence >= 0.8 {
+		t.Errorf("совпадение по яркости %+v", gray)
+	}
+
+	// Контуры у кнопки те же
+	edge, err := screen.FindTemplate(img, template(), screen.MatchOptions{Mode: screen.MatchEdge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edge.Left != want.Min.X || edge.Top != want.Min.Y || edge.Confidence < 0.8 {
+		t.Errorf("совпадение по контурам %+v, ожидалась область %v", edge, want)
+	}
+}
+
+func TestFindTemplateTooLarge(t *testing.T) {
+	img := desktop(40, 20)
+
+	_, err := screen.FindTemplate(img, template(), screen.MatchOptions{})
+	if !errors.Is(err, screen.ErrImageNotFound) || !strings.Contains(err.Error(), "шаблон 48x28 больше области поиска 40x20") {
+		t.Errorf("ошибка %v", err)
+	}
+
+	// Масштабы, при которых шаблон не помещается, пропускаются
+	drawButton(img, 4, 2, 0.7, light, false)
+	match, err := screen.FindTemplate(img, template(), screen.MatchOptions{MinScale: 0.7, MaxScale: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match.Scale > 0.8 || match.Left > 5 || match.Top > 3 {
+		t.Errorf("совпадение %+v", match)
+	}
+
+	if _, err := screen.FindTemplate(img, image.NewRGBA(image.Rectangle{}), screen.MatchOptions{}); !errors.Is(err, screen.ErrBadCapture) {
+		t.Errorf("пустой шаблон: %v", err)
+	}
+}
+
+func TestMatchOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts screen.MatchOptions
+		err  string
+	}{
+		{name: "по умолчанию", opts: screen.MatchOptions{}},
+		{name: "только max_scale", opts: screen.MatchOptions{MaxScale: 1.5}},
+		{name: "порог", opts: screen.MatchOptions{Threshold: 1.2}, err: "threshold должен быть от 0 до 1"},
+		{name: "режим", opts: screen.MatchOptions{Mode: "color"}, err: `неизвестный match_mode "color"`},
+		{name: "диапазон", opts: screen.MatchOptions{MinScale: 0.1, MaxScale: 1}, err: "min_scale и max_scale должны быть от 0.25 до 4"},
+		{name: "порядок", opts: screen.MatchOptions{MinScale: 2, MaxScale: 1.5}, err: "min_scale больше max_scale"},
+		{name: "шаг", opts: screen.MatchOptions{ScaleStep: -0.1}, err: "scale_step не может быть отрицательным"},
+		{name: "много масштабов", opts: screen.MatchOptions{MinScale: 0.25, MaxScale: 4, ScaleStep: 0.01}, err: "слишком много масштабов"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
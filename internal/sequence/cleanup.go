@@ -0,0 +1,89 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+// CleanupTimeout ограничение времени блоков on_failure и finally: отмена задания их не прерывает,
+// поэтому остановка сервера ждет выполняемое задание не меньше этого времени
+const CleanupTimeout = 30 * time.Second
+
+// Cleanup блоки завершения последовательности, возвращающие приложение в известное состояние
+type Cleanup struct {
+	// OnFailure выполняется после ошибки или отмены основных шагов (например, Escape и закрытие диалога)
+	OnFailure []Step `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`
+	// Finally выполняется всегда: после успешных основных шагов или после on_failure
+	Finally []Step `json:"finally,omitempty" yaml:"finally,omitempty"`
+}
+
+// Empty сообщает, что блоки завершения не заданы
+func (c Cleanup) Empty() bool {
+	return len(c.OnFailure) == 0 && len(c.Finally) == 0
+}
+
+// Validate проверяет шаги блоков завершения
+func (c Cleanup) Validate() error {
+	if err := validateBlock("on_failure", c.OnFailure, true); err != nil {
+		return err
+	}
+	return validateBlock("finally", c.Finally, true)
+}
+
+// runCleanup выполняет блоки on_failure (если err не nil) и finally. Блоки выполняются
+// в отдельном контексте, который не отменяется вместе с ctx, но ограничен CleanupTimeout;
+// аварийная остановка (причина отмены input.ErrStopped) их пропускает, а fail-safe через
+// guard input.Service блокирует. Результаты блоков
+// записываются в отчет отдельно от основных шагов, hook для них не вызывается.
+// Возвращает err, а если основные шаги прошли успешно — ошибку finally.
+func (r *Runner) runCleanup(ctx context.Context, st *runState, cleanup Cleanup, err error) error {
+	if errors.Is(context.Cause(ctx), input.ErrStopped) {
+		r.logger.Warn("Блоки on_failure и finally пропущены из-за аварийной остановки")
+		return err
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+	defer cancel()
+
+	if err != nil && len(cleanup.OnFailure) > 0 {
+		// Отпускаем то, что зажал прерванный шаг, чтобы Escape не нажался вместе с Ctrl
+		if releaseErr := r.service.ReleaseAll(); releaseErr != nil {
+			r.logger.Warn("Ошибка отпускания клавиш перед on_failure", zap.Error(releaseErr))
+		}
+		var blockErr error
+		st.report.OnFailure, blockErr = r.runCleanupBlock(cleanupCtx, st, "on_failure", cleanup.OnFailure)
+		if blockErr != nil {
+			r.logger.Warn("Ошибка в блоке on_failure", zap.Error(blockErr))
+		}
+	}
+
+	if len(cleanup.Finally) > 0 {
+		var blockErr error
+		st.report.Finally, blockErr = r.runCleanupBlock(cleanupCtx, st, "finally", cleanup.Finally)
+		if blockErr != nil {
+			if err == nil {
+				return fmt.Errorf("finally: %w", blockErr)
+			}
+			r.logger.Warn("Ошибка в блоке finally", zap.Error(blockErr))
+		}
+	}
+	return err
+}
+
+// runCleanupBlock выполняет блок завершения и возвращает его результаты отдельно от основных шагов
+func (r *Runner) runCleanupBlock(ctx context.Context, st *runState, name string, steps []Step) ([]StepResult, error) {
+	mainSteps, hook := st.report.Steps, st.hook
+	st.report.Steps, st.hook = make([]StepResult, 0, len(steps)), nil
+
+	err := r.runBlock(ctx, st, steps, name, 0)
+
+	results := st.report.Steps
+	st.report.Steps, st.hook = mainSteps, hook
+	return results, err
+}
@@ -0,0 +1,261 @@
+package sequence_test
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+
+	"gopkg.in/yaml.v3"
+)
+
+// failStep шаг, который завершается ошибкой: переменная missing не задана
+const failStep = `
+- type: set
+  value: "{{ .vars.missing }}"
+  output: never
+`
+
+func parseCleanup(t *testing.T, src string) sequence.Cleanup {
+	t.Helper()
+	var cleanup sequence.Cleanup
+	if err := yaml.Unmarshal([]byte(src), &cleanup); err != nil {
+		t.Fatal(err)
+	}
+	if err := cleanup.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return cleanup
+}
+
+func TestCleanupBlocks(t *testing.T) {
+	okSteps := parseSteps(t, `
+- type: key_tap
+  key: a
+`)
+	failSteps := parseSteps(t, failStep)
+
+	cleanup := parseCleanup(t, `
+on_failure:
+  - type: key_tap
+    key: escape
+finally:
+  - type: key_tap
+    key: f12
+`)
+	failingFinally := parseCleanup(t, `
+on_failure:
+  - type: key_tap
+    key: escape
+finally:
+  - type: key_tap
+    key: f12
+  - type: set
+    value: "{{ .vars.missing }}"
+    output: never
+`)
+
+	tests := []struct {
+		name    string
+		steps   []sequence.Step
+		cleanup sequence.Cleanup
+		events  []string
+		// err начало ожидаемой ошибки (пусто — без ошибки)
+		err       string
+		onFailure []string
+		finally   []string
+	}{
+		{
+			name:    "успех",
+			steps:   okSteps,
+			cleanup: cleanup,
+			events:  []string{"key_tap(a)", "key_tap(f12)"},
+			finally: []string{"finally.1 ok"},
+		},
+		{
+			name:      "ошибка",
+			steps:     failSteps,
+			cleanup:   cleanup,
+			events:    []string{"key_tap(escape)", "key_tap(f12)"},
+			err:       "шаг 1",
+			onFailure: []string{"on_failure.1 ok"},
+			finally:   []string{"finally.1 ok"},
+		},
+		{
+			name:    "ошибка finally после успеха",
+			steps:   okSteps,
+			cleanup: failingFinally,
+			events:  []string{"key_tap(a)", "key_tap(f12)"},
+			err:     "finally: ",
+			finally: []string{"finally.1 ok", "finally.2 failed"},
+		},
+		{
+			// Ошибка основных шагов важнее ошибки finally
+			name:      "ошибка finally после ошибки",
+			steps:     failSteps,
+			cleanup:   failingFinally,
+			events:    []string{"key_tap(escape)", "key_tap(f12)"},
+			err:       "шаг 1",
+			onFailure: []string{"on_failure.1 ok"},
+			finally:   []string{"finally.1 ok", "finally.2 failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _, backend := newRunner()
+			report, err := runner.RunWithCleanup(context.Background(), tt.steps, tt.cleanup, nil, nil)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+			if report.Success != (tt.err == "") {
+				t.Errorf("success %v", report.Success)
+			}
+
+			var events []string
+			for _, e := range backend.Events() {
+				events = append(events, e.String())
+			}
+			if !slices.Equal(events, tt.events) {
+				t.Errorf("события %v, ожидались %v", events, tt.events)
+			}
+			// Результаты блоков записываются отдельно от основных шагов
+			if len(report.Steps) != len(tt.steps) {
+				t.Errorf("основные шаги %v", paths(report.Steps))
+			}
+			if got := paths(report.OnFailure); !slices.Equal(got, tt.onFailure) {
+				t.Errorf("on_failure %v, ожидался %v", got, tt.onFailure)
+			}
+			if got := paths(report.Finally); !slices.Equal(got, tt.finally) {
+				t.Errorf("finally %v, ожидался %v", got, tt.finally)
+			}
+		})
+	}
+}
+
+// ctxProbe запоминает контекст, в котором определяется цвет, и возвращает ожидаемый цвет
+type ctxProbe struct {
+	deadline time.Time
+	err      error
+}
+
+func (p *ctxProbe) ProbeColor(ctx context.Context, x, y int, region *sequence.Region, want color.RGBA) (color.RGBA, error) {
+	p.deadline, _ = ctx.Deadline()
+	p.err = ctx.Err()
+	return want, nil
+}
+
+func TestCleanupAfterCancel(t *testing.T) {
+	steps := parseSteps(t, `
+- type: key_tap
+  key: a
+`)
+	cleanup := parseCleanup(t, `
+on_failure:
+  - type: key_tap
+    key: escape
+finally:
+  - type: wait_for_color
+    x: 10
+    y: 10
+    color: "#ffffff"
+`)
+	runner, _, backend := newRunner()
+	probe := &ctxProbe{}
+	runner.SetColorProbe(probe)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	started := time.Now()
+	report, err := runner.RunWithCleanup(ctx, steps, cleanup, nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ошибка %v", err)
+	}
+
+	// Блоки выполняются в контексте, который не отменен вместе с ctx, но ограничен CleanupTimeout
+	if probe.err != nil {
+		t.Errorf("контекст finally отменен: %v", probe.err)
+	}
+	if probe.deadline.IsZero() || probe.deadline.Before(started) || probe.deadline.After(time.Now().Add(sequence.CleanupTimeout)) {
+		t.Errorf("срок контекста finally %v", probe.deadline)
+	}
+	if events := backend.Events(); len(events) != 1 || events[0].String() != "key_tap(escape)" {
+		t.Errorf("события %v", events)
+	}
+	if got := paths(report.OnFailure); !slices.Equal(got, []string{"on_failure.1 ok"}) {
+		t.Errorf("on_failure %v", got)
+	}
+	if got := paths(report.Finally); !slices.Equal(got, []string{"finally.1 ok"}) {
+		t.Errorf("finally %v", got)
+	}
+}
+
+func TestCleanupSkippedOnStop(t *testing.T) {
+	steps := parseSteps(t, `
+- type: key_tap
+  key: a
+`)
+	cleanup := parseCleanup(t, `
+on_failure:
+  - type: key_tap
+    key: escape
+finally:
+  - type: key_tap
+    key: f12
+`)
+	runner, _, backend := newRunner()
+
+	// Аварийная остановка отменяет задание с причиной input.ErrStopped
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(input.ErrStopped)
+	report, err := runner.RunWithCleanup(ctx, steps, cleanup, nil, nil)
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if events := backend.Events(); len(events) != 0 {
+		t.Errorf("блоки выполнены после аварийной остановки: %v", events)
+	}
+	if report.OnFailure != nil || report.Finally != nil {
+		t.Errorf("on_failure %v, finally %v", paths(report.OnFailure), paths(report.Finally))
+	}
+}
+
+func TestReleaseBeforeOnFailure(t *testing.T) {
+	steps := parseSteps(t, `
+- type: key_tap
+  key: a
+`+failStep)
+	cleanup := parseCleanup(t, `
+on_failure:
+  - type: key_tap
+    key: escape
+`)
+	runner, service, backend := newRunner()
+
+	// Прерванный шаг оставил зажатым Ctrl
+	if err := service.KeyToggle(context.Background(), "ctrl", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.RunWithCleanup(context.Background(), steps, cleanup, nil, nil); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+
+	var events []string
+	for _, e := range backend.Events() {
+		events = append(events, e.String())
+	}
+	// Escape нажимается после отпускания Ctrl, а не как Ctrl+Escape
+	want := []string{"key_down(ctrl)", "key_tap(a)", "key_up(ctrl)", "key_tap(escape)"}
+	if !slices.Equal(events, want) {
+		t.Errorf("события %v, ожидались %v", events, want)
+	}
+	if held := service.Held(); len(held.Keys) != 0 || len(held.Buttons) != 0 {
+		t.Errorf("зажаты клавиши и кнопки %+v", held)
+	}
+}
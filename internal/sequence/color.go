@@ -0,0 +1,85 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"time"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/screen"
+)
+
+const (
+	// defaultColorTimeout ограничение ожидания wait_for_color, если timeout_ms не указан
+	defaultColorTimeout = 10 * time.Second
+	// defaultColorInterval период опроса wait_for_color, если interval_ms не указан
+	defaultColorInterval = 200 * time.Millisecond
+)
+
+// ColorProbe определяет цвет на экране для шагов wait_for_color и условий pixel
+type ColorProbe interface {
+	// ProbeColor возвращает цвет пикселя (x, y), а если region задана — средний цвет области.
+	// want — ожидаемый цвет: его возвращает, например, проба пробного прогона, где экрана нет.
+	ProbeColor(ctx context.Context, x, y int, region *Region, want color.RGBA) (color.RGBA, error)
+}
+
+// screenProbe определяет цвет по скриншоту input.Service
+type screenProbe struct {
+	service *input.Service
+}
+
+func (p screenProbe) ProbeColor(ctx context.Context, x, y int, region *Region, want color.RGBA) (color.RGBA, error) {
+	if region != nil {
+		return screen.AverageColor(ctx, p.service, region.Rect())
+	}
+	return screen.Pixel(ctx, p.service, x, y)
+}
+
+// waitForColor опрашивает пиксель (или средний цвет области), пока цвет не совпадет с ожидаемым
+// или не истечет timeout_ms. Результат — наблюдаемый цвет и время ожидания; при ошибке
+// ожидания — последний наблюдаемый цвет.
+func (r *Runner) waitForColor(ctx context.Context, step Step) (any, error) {
+	want, err := screen.ParseColor(step.Color)
+	if err != nil {
+		return nil, err
+	}
+	timeout := durationOr(step.TimeoutMs.Value, defaultColorTimeout)
+	interval := durationOr(step.IntervalMs.Value, defaultColorInterval)
+
+	// Для области координаты не задаются (Validate допускает либо x и y, либо region)
+	var x, y int
+	if step.X != nil {
+		x, y = step.X.Value, step.Y.Value
+	}
+
+	started := r.now()
+	for {
+		got, err := r.colors.ProbeColor(ctx, x, y, step.Region, want)
+		if err != nil {
+			return nil, err
+		}
+		elapsed := r.now().Sub(started)
+		output := map[string]any{
+			"color":      screen.FormatColor(got),
+			"elapsed_ms": elapsed.Milliseconds(),
+		}
+		if screen.ColorMatches(got, want, step.Tolerance) {
+			return output, nil
+		}
+		if elapsed >= timeout {
+			return output, fmt.Errorf("цвет %s не появился за %d мс, последний цвет %s",
+				screen.FormatColor(want), timeout.Milliseconds(), screen.FormatColor(got))
+		}
+		if err := r.service.Sleep(ctx, min(interval, timeout-elapsed)); err != nil {
+			return output, err
+		}
+	}
+}
+
+func durationOr(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,331 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strings"
+
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/screen"
+)
+
+// ErrCheckNotSupported возвращается для проверок экрана, не подключенных в этой сборке
+var ErrCheckNotSupported = errors.New("проверка не поддерживается")
+
+// Condition условие для if и repeat_until. Указывается ровно один вид проверки:
+// значение (value), результат предыдущего шага (previous), пиксель, изображение, текст
+// на экране или составное условие (all, any). not инвертирует результат.
+type Condition struct {
+	// Value шаблон, значение которого сравнивается (без сравнения — проверка на непустое значение)
+	Value     string  `json:"value,omitempty" yaml:"value,omitempty"`
+	Equals    *string `json:"equals,omitempty" yaml:"equals,omitempty"`
+	NotEquals *string `json:"not_equals,omitempty" yaml:"not_equals,omitempty"`
+	Contains  string  `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Matches   string  `json:"matches,omitempty" yaml:"matches,omitempty"`
+
+	// Previous ожидаемый статус предыдущего шага: ok или failed
+	Previous StepStatus `json:"previous,omitempty" yaml:"previous,omitempty"`
+
+	// Pixel цвет пикселя экрана
+	Pixel *PixelCondition `json:"pixel,omitempty" yaml:"pixel,omitempty"`
+	// Image наличие изображения на экране
+	Image *ImageCondition `json:"image,omitempty" yaml:"image,omitempty"`
+	// Text наличие текста на экране (OCR)
+	Text *TextCondition `json:"text,omitempty" yaml:"text,omitempty"`
+
+	All []Condition `json:"all,omitempty" yaml:"all,omitempty"`
+	Any []Condition `json:"any,omitempty" yaml:"any,omitempty"`
+
+	Not bool `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// PixelCondition цвет пикселя в точке (x, y) совпадает с color с допуском tolerance
+type PixelCondition struct {
+	X Int `json:"x" yaml:"x"`
+	Y Int `json:"y" yaml:"y"`
+	// Color ожидаемый цвет #RRGGBB или шаблон
+	Color string `json:"color" yaml:"color"`
+	// Tolerance допустимое отклонение каждой компоненты цвета (0-255)
+	Tolerance int `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+}
+
+// ImageCondition изображение-шаблон найдено на экране
+type ImageCondition struct {
+	// Template имя шаблона из каталога TEMPLATES_DIR (можно с расширением .png) или шаблон текста
+	Template string `json:"template" yaml:"template"`
+	// MatchOptions порог совпадения, режим и диапазон масштабов (по умолчанию из настроек)
+	screen.MatchOptions `yaml:",inline"`
+	// Region область поиска (не указана — весь экран)
+	Region *Region `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// TextCondition текст найден на экране (в области, если указана). Регистр и пробелы не учитываются.
+type TextCondition struct {
+	Contains string  `json:"contains" yaml:"contains"`
+	Region   *Region `json:"region,omitempty" yaml:"region,omitempty"`
+	// Options языки и режим сегментации распознавания (по умолчанию из настроек)
+	ocr.Options `yaml:",inline"`
+}
+
+// Region прямоугольная область экрана. Поля могут быть шаблонами, как координаты шагов.
+type Region struct {
+	X      Int `json:"x" yaml:"x"`
+	Y      Int `json:"y" yaml:"y"`
+	Width  Int `json:"width" yaml:"width"`
+	Height Int `json:"height" yaml:"height"`
+}
+
+// Rect возвращает вычисленную область как прямоугольник экрана (nil — пустой прямоугольник, весь экран)
+func (r *Region) Rect() image.Rectangle {
+	if r == nil {
+		return image.Rectangle{}
+	}
+	return image.Rect(r.X.Value, r.Y.Value, r.X.Value+r.Width.Value, r.Y.Value+r.Height.Value)
+}
+
+// validate проверяет размер области, если он задан числами (шаблоны проверяются после вычисления)
+func (r *Region) validate() error {
+	if r == nil {
+		return nil
+	}
+	if (!r.Width.IsTemplate() && r.Width.Value <= 0) || (!r.Height.IsTemplate() && r.Height.Value <= 0) {
+		return errors.New("region: ширина и высота должны быть положительными")
+	}
+	return nil
+}
+
+// ImageChecker проверяет наличие изображения на экране
+type ImageChecker interface {
+	CheckImage(ctx context.Context, cond *ImageCondition) (bool, error)
+}
+
+// TextChecker распознает текст на экране
+type TextChecker interface {
+	CheckText(ctx context.Context, cond *TextCondition) (bool, error)
+}
+
+// kinds возвращает число указанных видов проверки
+func (c *Condition) kinds() int {
+	n := 0
+	if c.Value != "" {
+		n++
+	}
+	if c.Previous != "" {
+		n++
+	}
+	if c.Pixel != nil {
+		n++
+	}
+	if c.Image != nil {
+		n++
+	}
+	if c.Text != nil {
+		n++
+	}
+	if len(c.All) > 0 {
+		n++
+	}
+	if len(c.Any) > 0 {
+		n++
+	}
+	return n
+}
+
+// Validate проверяет структуру условия
+func (c *Condition) Validate() error {
+	if c == nil {
+		return errors.New("не указано условие")
+	}
+	switch c.kinds() {
+	case 0:
+		return errors.New("условие пустое: укажите value, previous, pixel, image, text, all или any")
+	case 1:
+	default:
+		return errors.New("в одном условии указано несколько проверок, используйте all или any")
+	}
+
+	if c.Value == "" && (c.Equals != nil || c.NotEquals != nil || c.Contains != "" || c.Matches != "") {
+		return errors.New("сравнение указано без value")
+	}
+	if c.Matches != "" {
+		if _, err := regexp.Compile(c.Matches); err != nil {
+			return fmt.Errorf("неверное регулярное выражение matches: %w", err)
+		}
+	}
+	switch c.Previous {
+	case "", StatusOK, StatusFailed:
+	default:
+		return fmt.Errorf("previous может быть %s или %s", StatusOK, StatusFailed)
+	}
+	if c.Pixel != nil && !strings.Contains(c.Pixel.Color, "{{") {
+		if _, err := screen.ParseColor(c.Pixel.Color); err != nil {
+			return fmt.Errorf("pixel: %w", err)
+		}
+	}
+	if c.Image != nil {
+		if c.Image.Template == "" {
+			return errors.New("image: не указан template")
+		}
+		if err := c.Image.MatchOptions.Validate(); err != nil {
+			return fmt.Errorf("image: %w", err)
+		}
+		if err := c.Image.Region.validate(); err != nil {
+			return fmt.Errorf("image: %w", err)
+		}
+	}
+	if c.Text != nil {
+		if c.Text.Contains == "" {
+			return errors.New("text: не указан contains")
+		}
+		if err := c.Text.Options.Validate(); err != nil {
+			return fmt.Errorf("text: %w", err)
+		}
+		if err := c.Text.Region.validate(); err != nil {
+			return fmt.Errorf("text: %w", err)
+		}
+	}
+	for i := range c.All {
+		if err := c.All[i].Validate(); err != nil {
+			return fmt.Errorf("all[%d]: %w", i, err)
+		}
+	}
+	for i := range c.Any {
+		if err := c.Any[i].Validate(); err != nil {
+			return fmt.Errorf("any[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// evaluate вычисляет условие
+func (r *Runner) evaluate(ctx context.Context, st *runState, c *Condition) (bool, error) {
+	result, err := r.evaluateKind(ctx, st, c)
+	if err != nil {
+		return false, err
+	}
+	return result != c.Not, nil
+}
+
+func (r *Runner) evaluateKind(ctx context.Context, st *runState, c *Condition) (bool, error) {
+	switch {
+	case c.Value != "":
+		return evaluateValue(st.scope, c)
+
+	case c.Previous != "":
+		return st.last == c.Previous, nil
+
+	case c.Pixel != nil:
+		x, err := st.scope.RenderInt(c.Pixel.X)
+		if err != nil {
+			return false, fmt.Errorf("pixel.x: %w", err)
+		}
+		y, err := st.scope.RenderInt(c.Pixel.Y)
+		if err != nil {
+			return false, fmt.Errorf("pixel.y: %w", err)
+		}
+		color, err := st.scope.Render(c.Pixel.Color)
+		if err != nil {
+			return false, fmt.Errorf("pixel.color: %w", err)
+		}
+		want, err := screen.ParseColor(color)
+		if err != nil {
+			return false, err
+		}
+		got, err := r.colors.ProbeColor(ctx, x, y, nil, want)
+		if err != nil {
+			return false, err
+		}
+		return screen.ColorMatches(got, want, c.Pixel.Tolerance), nil
+
+	case c.Image != nil:
+		if r.images == nil {
+			return false, fmt.Errorf("поиск изображения: %w", ErrCheckNotSupported)
+		}
+		cond := *c.Image
+		template, err := st.scope.Render(cond.Template)
+		if err != nil {
+			return false, fmt.Errorf("image.template: %w", err)
+		}
+		cond.Template = template
+		if cond.Region, err = st.scope.RenderRegion(cond.Region); err != nil {
+			return false, fmt.Errorf("image.%w", err)
+		}
+		return r.images.CheckImage(ctx, &cond)
+
+	case c.Text != nil:
+		if r.texts == nil {
+			return false, fmt.Errorf("распознавание текста: %w", ErrCheckNotSupported)
+		}
+		cond := *c.Text
+		text, err := st.scope.Render(cond.Contains)
+		if err != nil {
+			return false, fmt.Errorf("text.contains: %w", err)
+		}
+		cond.Contains = text
+		if cond.Region, err = st.scope.RenderRegion(cond.Region); err != nil {
+			return false, fmt.Errorf("text.%w", err)
+		}
+		return r.texts.CheckText(ctx, &cond)
+
+	case len(c.All) > 0:
+		for i := range c.All {
+			ok, err := r.evaluate(ctx, st, &c.All[i])
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			ok, err := r.evaluate(ctx, st, &c.Any[i])
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return false, errors.New("условие пустое")
+}
+
+// evaluateValue сравнивает значение шаблона. Без сравнения значение считается истинным,
+// если оно не пустое и не равно "false" или "0".
+func evaluateValue(scope *Scope, c *Condition) (bool, error) {
+	value, err := scope.Render(c.Value)
+	if err != nil {
+		return false, fmt.Errorf("value: %w", err)
+	}
+
+	switch {
+	case c.Equals != nil:
+		want, err := scope.Render(*c.Equals)
+		if err != nil {
+			return false, fmt.Errorf("equals: %w", err)
+		}
+		return value == want, nil
+	case c.NotEquals != nil:
+		want, err := scope.Render(*c.NotEquals)
+		if err != nil {
+			return false, fmt.Errorf("not_equals: %w", err)
+		}
+		return value != want, nil
+	case c.Contains != "":
+		want, err := scope.Render(c.Contains)
+		if err != nil {
+			return false, fmt.Errorf("contains: %w", err)
+		}
+		return strings.Contains(value, want), nil
+	case c.Matches != "":
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return false, fmt.Errorf("matches: %w", err)
+		}
+		return re.MatchString(value), nil
+	}
+
+	value = strings.TrimSpace(value)
+	return value != "" && value != "false" && value != "0", nil
+}
@@ -0,0 +1,135 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultAttempts число попыток retry по умолчанию
+	defaultAttempts = 3
+	// defaultBackoff пауза перед второй попыткой retry по умолчанию
+	defaultBackoff = 500 * time.Millisecond
+	// defaultBackoffFactor множитель паузы между попытками по умолчанию
+	defaultBackoffFactor = 2.0
+	// defaultItemVar имя переменной элемента for_each по умолчанию
+	defaultItemVar = "item"
+)
+
+// runControl выполняет управляющий шаг и возвращает его итог
+func (r *Runner) runControl(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	switch step.Type {
+	case StepIf:
+		return r.runIf(ctx, st, step, path, depth)
+	case StepForEach:
+		return r.runForEach(ctx, st, step, path, depth)
+	case StepRepeatUntil:
+		return r.runRepeatUntil(ctx, st, step, path, depth)
+	case StepRetry:
+		return r.runRetry(ctx, st, step, path, depth)
+	}
+	return nil, fmt.Errorf("неизвестный управляющий шаг %q", step.Type)
+}
+
+// runIf выполняет ветку then или else. Итог — значение условия.
+func (r *Runner) runIf(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	ok, err := r.evaluate(ctx, st, step.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("condition: %w", err)
+	}
+
+	branch, name := step.Then, "then"
+	if !ok {
+		branch, name = step.Else, "else"
+	}
+	if len(branch) == 0 {
+		return ok, nil
+	}
+	return ok, r.runBlock(ctx, st, branch, path+"."+name, depth+1)
+}
+
+// runForEach выполняет тело для каждого элемента списка. Итог — число итераций.
+func (r *Runner) runForEach(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	items, err := st.scope.List(step.Items)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+
+	as := step.As
+	if as == "" {
+		as = defaultItemVar
+	}
+	for i, item := range items {
+		st.scope.Vars[as] = item
+		st.scope.Vars[as+"_index"] = i + 1
+		if err := r.runBlock(ctx, st, step.Steps, fmt.Sprintf("%s.%d", path, i+1), depth+1); err != nil {
+			return i, fmt.Errorf("элемент %d из %d: %w", i+1, len(items), err)
+		}
+	}
+	return len(items), nil
+}
+
+// runRepeatUntil повторяет тело, пока не выполнится условие until (не больше max раз).
+// Итог — число повторов.
+func (r *Runner) runRepeatUntil(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	for i := 1; i <= step.Max; i++ {
+		if err := r.runBlock(ctx, st, step.Steps, fmt.Sprintf("%s.%d", path, i), depth+1); err != nil {
+			return i, fmt.Errorf("повтор %d: %w", i, err)
+		}
+		ok, err := r.evaluate(ctx, st, step.Until)
+		if err != nil {
+			return i, fmt.Errorf("until: %w", err)
+		}
+		if ok {
+			return i, nil
+		}
+	}
+	return step.Max, fmt.Errorf("условие until не выполнилось за %d повторов", step.Max)
+}
+
+// runRetry выполняет тело до первого успеха с растущей паузой между попытками.
+// Итог — номер успешной попытки.
+func (r *Runner) runRetry(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	attempts := step.Attempts
+	if attempts == 0 {
+		attempts = defaultAttempts
+	}
+	backoff := defaultBackoff
+	if step.BackoffMs > 0 {
+		backoff = time.Duration(step.BackoffMs) * time.Millisecond
+	}
+	factor := step.BackoffFactor
+	if factor == 0 {
+		factor = defaultBackoffFactor
+	}
+	maxBackoff := time.Duration(step.MaxBackoffMs) * time.Millisecond
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		lastErr = r.runBlock(ctx, st, step.Steps, fmt.Sprintf("%s.%d", path, attempt), depth+1)
+		if lastErr == nil {
+			return attempt, nil
+		}
+		// Отмену не повторяем
+		if ctx.Err() != nil || attempt == attempts {
+			break
+		}
+
+		r.logger.Warn("Попытка не удалась, повтор",
+			zap.String("path", path),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(lastErr))
+		if err := r.service.Sleep(ctx, backoff); err != nil {
+			return attempt, err
+		}
+		backoff = time.Duration(float64(backoff) * factor)
+		if maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	return attempts, fmt.Errorf("все попытки (%d) завершились ошибкой: %w", attempts, lastErr)
+}
@@ -0,0 +1,349 @@
+package sequence_test
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func parseSteps(t *testing.T, src string) []sequence.Step {
+	t.Helper()
+	var steps []sequence.Step
+	if err := yaml.Unmarshal([]byte(src), &steps); err != nil {
+		t.Fatal(err)
+	}
+	if err := sequence.Validate(steps); err != nil {
+		t.Fatal(err)
+	}
+	return steps
+}
+
+// newRunner создает исполнитель на fake бэкенде; длительности шагов считаются
+// по виртуальным часам бэкенда
+func newRunner() (*sequence.Runner, *input.Service, *fake.Backend) {
+	backend := fake.New(fake.Options{Platform: "linux"})
+	service := input.NewService(zap.NewNop(), backend)
+	runner := sequence.NewRunner(zap.NewNop(), service)
+	started := time.Now()
+	runner.SetClock(func() time.Time {
+		return started.Add(backend.Now())
+	})
+	return runner, service, backend
+}
+
+// timeline возвращает события бэкенда с виртуальным временем, например "100 key_tap(a)"
+func timeline(b *fake.Backend) []string {
+	var events []string
+	for _, e := range b.Events() {
+		events = append(events, fmt.Sprintf("%d %s", e.AtMs, e))
+	}
+	return events
+}
+
+// paths возвращает пути шагов отчета со статусами, например "1.2.1 ok"
+func paths(results []sequence.StepResult) []string {
+	var out []string
+	for _, result := range results {
+		out = append(out, result.Path+" "+string(result.Status))
+	}
+	return out
+}
+
+// whiteFrame кадр экрана, залитый белым
+func whiteFrame() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestIfElse(t *testing.T) {
+	steps := parseSteps(t, `
+- type: if
+  condition:
+    value: "{{ .params.mode }}"
+    equals: fast
+  then:
+    - type: key_tap
+      key: f
+  else:
+    - type: key_tap
+      key: s
+    - type: key_tap
+      key: enter
+- type: if
+  condition:
+    pixel: {x: 10, y: 10, color: "#ffffff"}
+  then:
+    - type: key_tap
+      key: w
+- type: if
+  condition:
+    previous: ok
+    not: true
+  then:
+    - type: key_tap
+      key: x
+`)
+	tests := []struct {
+		mode   string
+		frame  bool
+		events []string
+		paths  []string
+	}{
+		{
+			mode:   "fast",
+			frame:  true,
+			events: []string{"0 key_tap(f)", "0 key_tap(w)"},
+			paths:  []string{"1 ok", "1.then.1 ok", "2 ok", "2.then.1 ok", "3 ok"},
+		},
+		{
+			mode:   "slow",
+			events: []string{"0 key_tap(s)", "0 key_tap(enter)"},
+			paths:  []string{"1 ok", "1.else.1 ok", "1.else.2 ok", "2 ok", "3 ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			runner, _, backend := newRunner()
+			if tt.frame {
+				backend.AddFrame(0, whiteFrame())
+			}
+			report, err := runner.Run(context.Background(), steps, sequence.NewScope(map[string]any{"mode": tt.mode}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := timeline(backend); !slices.Equal(got, tt.events) {
+				t.Errorf("события %v, ожидались %v", got, tt.events)
+			}
+			if got := paths(report.Steps); !slices.Equal(got, tt.paths) {
+				t.Errorf("шаги %v, ожидались %v", got, tt.paths)
+			}
+			// Итог if — значение условия
+			if report.Steps[0].Output != (tt.mode == "fast") || report.Steps[len(report.Steps)-1].Output != false {
+				t.Errorf("итоги условий: %+v", report.Steps)
+			}
+		})
+	}
+}
+
+func TestForEach(t *testing.T) {
+	steps := parseSteps(t, `
+- type: for_each
+  items: "{{ .params.lots }}"
+  as: lot
+  steps:
+    - type: if
+      condition:
+        value: "{{ .vars.lot }}"
+        equals: bad
+      then:
+        - type: set
+          value: "{{ .vars.missing }}"
+          output: never
+    - type: set
+      value: "{{ .vars.lot }}-{{ .vars.lot_index }}"
+      output: last
+    - type: wait
+      duration_ms: 100
+`)
+
+	t.Run("все элементы", func(t *testing.T) {
+		runner, _, backend := newRunner()
+		report, err := runner.Run(context.Background(), steps, sequence.NewScope(map[string]any{
+			"lots": []any{"a", "b", "c"},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Steps[0].Output != 3 || report.Vars["last"] != "c-3" {
+			t.Errorf("итог %v, переменные %v", report.Steps[0].Output, report.Vars)
+		}
+		// Тело выполняется по разу для каждого элемента с путями <шаг>.<итерация>.<шаг тела>
+		want := []string{"1 ok"}
+		for i := 1; i <= 3; i++ {
+			want = append(want, fmt.Sprintf("1.%d.1 ok", i), fmt.Sprintf("1.%d.2 ok", i), fmt.Sprintf("1.%d.3 ok", i))
+		}
+		if got := paths(report.Steps); !slices.Equal(got, want) {
+			t.Errorf("шаги %v, ожидались %v", got, want)
+		}
+		if backend.Now() != 300*time.Millisecond || report.DurationMs != 300 || report.Steps[0].DurationMs != 300 {
+			t.Errorf("виртуальное время %v, длительность %d мс", backend.Now(), report.DurationMs)
+		}
+	})
+
+	t.Run("ошибка элемента", func(t *testing.T) {
+		runner, _, _ := newRunner()
+		report, err := runner.Run(context.Background(), steps, sequence.NewScope(map[string]any{
+			"lots": []any{"a", "bad", "c"},
+		}))
+		if err == nil || !strings.Contains(err.Error(), "элемент 2 из 3") {
+			t.Fatalf("ошибка %v", err)
+		}
+		// Итог — число завершенных итераций, остальные шаги тела пропущены
+		if report.Steps[0].Output != 1 || report.Vars["last"] != "a-1" {
+			t.Errorf("итог %v, переменные %v", report.Steps[0].Output, report.Vars)
+		}
+		want := []string{"1 failed", "1.1.1 ok", "1.1.2 ok", "1.1.3 ok", "1.2.1 failed", "1.2.1.then.1 failed", "1.2.2 skipped", "1.2.3 skipped"}
+		if got := paths(report.Steps); !slices.Equal(got, want) {
+			t.Errorf("шаги %v, ожидались %v", got, want)
+		}
+	})
+}
+
+func TestRepeatUntil(t *testing.T) {
+	steps := parseSteps(t, `
+- type: repeat_until
+  max: 5
+  until:
+    pixel: {x: 10, y: 10, color: "#ffffff"}
+  steps:
+    - type: wait
+      duration_ms: 100
+    - type: key_tap
+      key: f5
+`)
+	tests := []struct {
+		name string
+		// frameAt момент появления белого кадра (0 — не появляется)
+		frameAt time.Duration
+		count   int
+		err     string
+	}{
+		{name: "условие выполнилось", frameAt: 250 * time.Millisecond, count: 3},
+		{name: "max", count: 5, err: "условие until не выполнилось за 5 повторов"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _, backend := newRunner()
+			if tt.frameAt > 0 {
+				backend.AddFrame(tt.frameAt, whiteFrame())
+			}
+			report, err := runner.Run(context.Background(), steps, nil)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+
+			// Условие проверяется после каждого повтора
+			var want []string
+			for i := 1; i <= tt.count; i++ {
+				want = append(want, fmt.Sprintf("%d key_tap(f5)", i*100))
+			}
+			if got := timeline(backend); !slices.Equal(got, want) {
+				t.Errorf("события %v, ожидались %v", got, want)
+			}
+			if report.Steps[0].Output != tt.count || report.Steps[0].DurationMs != int64(tt.count*100) {
+				t.Errorf("итог %v, длительность %d мс", report.Steps[0].Output, report.Steps[0].DurationMs)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	steps := parseSteps(t, `
+- type: retry
+  attempts: 5
+  backoff_ms: 100
+  backoff_factor: 2
+  max_backoff_ms: 300
+  steps:
+    - type: key_tap
+      key: enter
+    - type: set
+      value: "{{ .vars.ready }}"
+      output: result
+`)
+	tests := []struct {
+		name string
+		// readyAt попытка, перед которой появляется переменная ready (0 — не появляется)
+		readyAt int
+		events  []string
+		output  int
+		err     string
+	}{
+		{
+			name:    "успех с четвертой попытки",
+			readyAt: 4,
+			// Паузы 100, 200 и 300 (ограничена max_backoff_ms) мс
+			events: []string{"0 key_tap(enter)", "100 key_tap(enter)", "300 key_tap(enter)", "600 key_tap(enter)"},
+			output: 4,
+		},
+		{
+			name:   "все попытки",
+			events: []string{"0 key_tap(enter)", "100 key_tap(enter)", "300 key_tap(enter)", "600 key_tap(enter)", "900 key_tap(enter)"},
+			output: 5,
+			err:    "все попытки (5) завершились ошибкой",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _, backend := newRunner()
+			// Переменная появляется к нужной попытке, как будто приложение наконец ответило
+			hook := func(ctx context.Context, info sequence.StepInfo, scope *sequence.Scope) (sequence.StepAction, error) {
+				if tt.readyAt > 0 && info.Path == fmt.Sprintf("1.%d.1", tt.readyAt) {
+					scope.Vars["ready"] = "ok"
+				}
+				return sequence.ActionRun, nil
+			}
+			report, err := runner.RunWithHook(context.Background(), steps, nil, hook)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("ошибка %v, ожидалась %q", err, tt.err)
+			}
+
+			if got := timeline(backend); !slices.Equal(got, tt.events) {
+				t.Errorf("события %v, ожидались %v", got, tt.events)
+			}
+			if report.Steps[0].Output != tt.output {
+				t.Errorf("итог %v, ожидался %d", report.Steps[0].Output, tt.output)
+			}
+			// Неудачные попытки внутри retry не влияют на итог последовательности
+			if report.Success != (tt.err == "") {
+				t.Errorf("success %v", report.Success)
+			}
+		})
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	steps := parseSteps(t, `
+- type: retry
+  attempts: 3
+  steps:
+    - type: key_tap
+      key: enter
+    - type: set
+      value: "{{ .vars.ready }}"
+      output: result
+`)
+	runner, _, backend := newRunner()
+	ctx, cancel := context.WithCancel(context.Background())
+	// Отмена во время первой попытки: повторов нет
+	hook := func(ctx context.Context, info sequence.StepInfo, scope *sequence.Scope) (sequence.StepAction, error) {
+		if info.Path == "1.1.2" {
+			cancel()
+		}
+		return sequence.ActionRun, nil
+	}
+	_, err := runner.RunWithHook(ctx, steps, nil, hook)
+	if err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if got := timeline(backend); !slices.Equal(got, []string{"0 key_tap(enter)"}) {
+		t.Errorf("события %v", got)
+	}
+}
@@ -0,0 +1,424 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"goszakup-automation/internal/input"
+
+	"go.uber.org/zap"
+)
+
+// StepStatus итог выполнения шага
+type StepStatus string
+
+const (
+	StatusOK      StepStatus = "ok"
+	StatusFailed  StepStatus = "failed"
+	StatusSkipped StepStatus = "skipped"
+)
+
+// StepResult результат выполнения шага
+type StepResult struct {
+	// Index номер шага в своем блоке, Path — полный путь для вложенных шагов
+	// (например, "3.then.1" или "4.2.1" — шаг 1 второй итерации шага 4)
+	Index  int        `json:"index"`
+	Path   string     `json:"path"`
+	Depth  int        `json:"depth,omitempty"`
+	Type   StepType   `json:"type"`
+	Name   string     `json:"name,omitempty"`
+	Status StepStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	// Output значение, которое вернул шаг (для set, read_clipboard, mouse_position, wait_for_color, read_text)
+	// или итог управляющего шага (результат условия, число итераций или попыток). При ошибке —
+	// частичный результат, например последний наблюдаемый цвет wait_for_color.
+	Output     any   `json:"output,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Report результат выполнения последовательности
+type Report struct {
+	Success bool         `json:"success"`
+	Steps   []StepResult `json:"steps"`
+	// OnFailure, Finally результаты блоков завершения (пути on_failure.N и finally.N)
+	OnFailure []StepResult `json:"on_failure,omitempty"`
+	Finally   []StepResult `json:"finally,omitempty"`
+	// Vars переменные, сохраненные шагами через output
+	Vars       map[string]any `json:"vars,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// Completed возвращает число успешно выполненных шагов верхнего уровня
+func (r *Report) Completed() int {
+	n := 0
+	for _, step := range r.Steps {
+		if step.Depth == 0 && step.Status == StatusOK {
+			n++
+		}
+	}
+	return n
+}
+
+// StepInfo шаг, который будет выполнен следующим (для Hook)
+type StepInfo struct {
+	Index int      `json:"index"`
+	Path  string   `json:"path"`
+	Depth int      `json:"depth,omitempty"`
+	Type  StepType `json:"type"`
+	Name  string   `json:"name,omitempty"`
+}
+
+// StepAction решение Hook о шаге
+type StepAction int
+
+const (
+	// ActionRun выполнить шаг
+	ActionRun StepAction = iota
+	// ActionSkip пропустить шаг (статус skipped) и перейти к следующему
+	ActionSkip
+)
+
+// Hook вызывается перед каждым шагом, включая вложенные, и может изменить scope.Vars.
+// Ошибка Hook останавливает последовательность независимо от continue_on_error.
+type Hook func(ctx context.Context, info StepInfo, scope *Scope) (StepAction, error)
+
+// runState состояние одного выполнения последовательности
+type runState struct {
+	scope  *Scope
+	report *Report
+	hook   Hook
+	// last статус последнего завершенного шага (для условия previous)
+	last StepStatus
+}
+
+// stepFunc выполняет шаг определенного типа и возвращает его результат (или nil)
+type stepFunc func(ctx context.Context, step Step) (any, error)
+
+// Runner выполняет последовательности шагов через input.Service.
+// Монопольный доступ к рабочему столу обеспечивает вызывающий (очередь executor).
+type Runner struct {
+	logger  *zap.Logger
+	service *input.Service
+	steps   map[StepType]stepFunc
+
+	// images, texts проверки экрана для условий (nil — не подключены)
+	images ImageChecker
+	texts  TextChecker
+	// locator поиск изображений для целей image:имя (nil — не подключен)
+	locator ImageLocator
+	// reader распознавание текста для read_text (nil — не подключено)
+	reader TextReader
+	// colors определение цвета для wait_for_color и условий pixel (по умолчанию по скриншоту)
+	colors ColorProbe
+
+	// now источник времени для длительности шагов (по умолчанию time.Now)
+	now func() time.Time
+}
+
+// NewRunner создает исполнитель последовательностей
+func NewRunner(logger *zap.Logger, service *input.Service) *Runner {
+	r := &Runner{
+		logger:  logger,
+		service: service,
+		colors:  screenProbe{service: service},
+		now:     time.Now,
+	}
+	r.steps = map[StepType]stepFunc{
+		StepMove:   r.move,
+		StepClick:  r.click,
+		StepText:   r.typeText,
+		StepKeyTap: r.keyTap,
+		StepHotkey: r.hotkey,
+		StepScroll: r.scroll,
+		StepWait:   r.wait,
+		StepClear:  r.clear,
+
+		StepInput:        r.input,
+		StepFillAndClick: r.fillAndClick,
+
+		StepSet:           r.set,
+		StepReadClipboard: r.readClipboard,
+		StepMousePosition: r.mousePosition,
+		StepWaitForColor:  r.waitForColor,
+		StepReadText:      r.readText,
+	}
+	return r
+}
+
+// SetImageChecker подключает поиск изображений для условий image
+func (r *Runner) SetImageChecker(checker ImageChecker) {
+	r.images = checker
+}
+
+// SetImageLocator подключает поиск изображений для целей target и button_target
+func (r *Runner) SetImageLocator(locator ImageLocator) {
+	r.locator = locator
+}
+
+// SetTextChecker подключает распознавание текста для условий text
+func (r *Runner) SetTextChecker(checker TextChecker) {
+	r.texts = checker
+}
+
+// SetTextReader подключает распознавание текста для шагов read_text и условий text
+func (r *Runner) SetTextReader(reader TextReader) {
+	r.reader = reader
+	r.texts = readerChecker{reader: reader}
+}
+
+// SetColorProbe подменяет определение цвета для шагов wait_for_color и условий pixel
+func (r *Runner) SetColorProbe(probe ColorProbe) {
+	r.colors = probe
+}
+
+// SetClock подменяет источник времени для длительности шагов,
+// например виртуальными часами fake бэкенда при пробном прогоне
+func (r *Runner) SetClock(now func() time.Time) {
+	r.now = now
+}
+
+// Run выполняет шаги по порядку. Перед каждым шагом вычисляются его шаблоны в scope,
+// результат шага с output сохраняется в scope.Vars. На первой ошибке выполнение
+// останавливается, если у шага не указан continue_on_error; оставшиеся шаги помечаются
+// как пропущенные. Отмена ctx останавливает последовательность независимо от continue_on_error.
+// Возвращаемая ошибка — первая ошибка, остановившая последовательность (*input.StepError).
+func (r *Runner) Run(ctx context.Context, steps []Step, scope *Scope) (*Report, error) {
+	return r.RunWithHook(ctx, steps, scope, nil)
+}
+
+// RunWithHook выполняет шаги как Run, вызывая hook перед каждым шагом (например, для отладчика)
+func (r *Runner) RunWithHook(ctx context.Context, steps []Step, scope *Scope, hook Hook) (*Report, error) {
+	return r.RunWithCleanup(ctx, steps, Cleanup{}, scope, hook)
+}
+
+// RunWithCleanup выполняет шаги как RunWithHook, а затем блоки завершения: on_failure — после
+// ошибки или отмены, finally — всегда. Блоки выполняются и после отмены ctx; hook для них
+// не вызывается. Success отчета учитывает все блоки.
+func (r *Runner) RunWithCleanup(ctx context.Context, steps []Step, cleanup Cleanup, scope *Scope, hook Hook) (*Report, error) {
+	if scope == nil {
+		scope = NewScope(nil)
+	}
+
+	started := r.now()
+	st := &runState{
+		scope:  scope,
+		report: &Report{Success: true, Steps: make([]StepResult, 0, len(steps))},
+		hook:   hook,
+	}
+
+	err := r.runBlock(ctx, st, steps, "", 0)
+	if !cleanup.Empty() {
+		err = r.runCleanup(ctx, st, cleanup, err)
+	}
+
+	// Неудачные попытки внутри retry не влияют на итог: учитываются только шаги верхнего уровня
+	for _, results := range [][]StepResult{st.report.Steps, st.report.OnFailure, st.report.Finally} {
+		for _, result := range results {
+			if result.Depth == 0 && result.Status == StatusFailed {
+				st.report.Success = false
+			}
+		}
+	}
+	if len(scope.Vars) > 0 {
+		st.report.Vars = scope.Vars
+	}
+	st.report.DurationMs = r.now().Sub(started).Milliseconds()
+	return st.report, err
+}
+
+// runBlock выполняет блок шагов. Вложенные шаги управляющих шагов выполняются
+// этим же методом с путем prefix и глубиной depth.
+func (r *Runner) runBlock(ctx context.Context, st *runState, steps []Step, prefix string, depth int) error {
+	var blockErr error
+	for i, step := range steps {
+		path := strconv.Itoa(i + 1)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		result := StepResult{Index: i + 1, Path: path, Depth: depth, Type: step.Type, Name: step.Name}
+
+		if blockErr != nil {
+			result.Status = StatusSkipped
+			st.report.Steps = append(st.report.Steps, result)
+			continue
+		}
+
+		// Результат управляющего шага добавляется до вложенных, а заполняется после них
+		slot := len(st.report.Steps)
+		st.report.Steps = append(st.report.Steps, result)
+
+		if st.hook != nil {
+			action, err := st.hook(ctx, StepInfo{Index: i + 1, Path: path, Depth: depth, Type: step.Type, Name: step.Name}, st.scope)
+			if err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+				st.report.Steps[slot] = result
+				st.last = StatusFailed
+				blockErr = &input.StepError{Step: i + 1, Total: len(steps), Name: step.label(), Err: err}
+				continue
+			}
+			if action == ActionSkip {
+				result.Status = StatusSkipped
+				st.report.Steps[slot] = result
+				continue
+			}
+		}
+
+		stepStarted := r.now()
+		output, err := r.runStep(ctx, st, step, path, depth)
+		result.DurationMs = r.now().Sub(stepStarted).Milliseconds()
+
+		if err == nil {
+			result.Status = StatusOK
+			result.Output = output
+			st.report.Steps[slot] = result
+			st.last = StatusOK
+			continue
+		}
+
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		result.Output = output
+		st.report.Steps[slot] = result
+		st.last = StatusFailed
+
+		stepErr := &input.StepError{Step: i + 1, Total: len(steps), Name: step.label(), Err: err}
+		if step.ContinueOnError && ctx.Err() == nil {
+			r.logger.Warn("Шаг завершился ошибкой, продолжаем", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		blockErr = stepErr
+	}
+	return blockErr
+}
+
+func (r *Runner) runStep(ctx context.Context, st *runState, step Step, path string, depth int) (any, error) {
+	step, err := RenderStep(step, st.scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := step.Validate(); err != nil {
+		return nil, err
+	}
+
+	if step.Type.IsControl() {
+		return r.runControl(ctx, st, step, path, depth)
+	}
+	if step, err = r.resolveTargets(ctx, step); err != nil {
+		return nil, err
+	}
+
+	fn, ok := r.steps[step.Type]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный тип шага %q", step.Type)
+	}
+
+	output, err := fn(ctx, step)
+	if err != nil {
+		// Частичный результат (например, последний цвет wait_for_color) попадает в отчет
+		return output, err
+	}
+	if step.Output != "" {
+		st.scope.Vars[step.Output] = output
+	}
+	return output, nil
+}
+
+func (r *Runner) move(ctx context.Context, step Step) (any, error) {
+	return nil, r.service.MoveMouse(ctx, step.X.Value, step.Y.Value)
+}
+
+func (r *Runner) click(ctx context.Context, step Step) (any, error) {
+	if step.X != nil {
+		return nil, r.service.ClickAt(ctx, step.X.Value, step.Y.Value, step.Button)
+	}
+	return nil, r.service.Click(ctx, step.Button)
+}
+
+func (r *Runner) typeText(ctx context.Context, step Step) (any, error) {
+	if step.X != nil {
+		return nil, r.service.TypeTextAt(ctx, step.X.Value, step.Y.Value, step.Text, step.DelayMs)
+	}
+	return nil, r.service.TypeText(ctx, step.Text, step.DelayMs)
+}
+
+func (r *Runner) keyTap(ctx context.Context, step Step) (any, error) {
+	return nil, r.service.KeyTap(ctx, step.Key, step.Modifiers...)
+}
+
+func (r *Runner) hotkey(ctx context.Context, step Step) (any, error) {
+	last := len(step.Keys) - 1
+	return nil, r.service.KeyTap(ctx, step.Keys[last], step.Keys[:last]...)
+}
+
+func (r *Runner) scroll(ctx context.Context, step Step) (any, error) {
+	return nil, r.service.Scroll(ctx, step.DX.Value, step.DY.Value)
+}
+
+func (r *Runner) wait(ctx context.Context, step Step) (any, error) {
+	return nil, r.service.Sleep(ctx, time.Duration(step.DurationMs.Value)*time.Millisecond)
+}
+
+func (r *Runner) clear(ctx context.Context, step Step) (any, error) {
+	if step.X != nil {
+		if err := r.service.ClickAt(ctx, step.X.Value, step.Y.Value, "left"); err != nil {
+			return nil, err
+		}
+	}
+	return nil, r.service.ClearInput(ctx)
+}
+
+func (r *Runner) input(ctx context.Context, step Step) (any, error) {
+	return nil, r.service.InputAtCoordinates(ctx, step.X.Value, step.Y.Value, step.Text, inputOptions(step, false))
+}
+
+func (r *Runner) fillAndClick(ctx context.Context, step Step) (any, error) {
+	button := step.Button
+	if button == "" {
+		button = "left"
+	}
+	return nil, r.service.FillInputAndClickButton(ctx,
+		step.X.Value, step.Y.Value,
+		step.Text,
+		step.ButtonX.Value, step.ButtonY.Value,
+		button,
+		inputOptions(step, true))
+}
+
+// inputOptions параметры input и fill_and_click с теми же значениями по умолчанию,
+// что у /input и /fill-and-click (очистка поля по умолчанию — clearByDefault)
+func inputOptions(step Step, clearByDefault bool) *input.InputOptions {
+	options := &input.InputOptions{
+		ClearBeforeInput: clearByDefault,
+		ClickDelay:       step.ClickDelayMs,
+		TypeDelay:        step.DelayMs,
+	}
+	if step.ClearBeforeInput != nil {
+		options.ClearBeforeInput = *step.ClearBeforeInput
+	}
+	if options.ClickDelay == 0 {
+		options.ClickDelay = 100
+	}
+	if options.TypeDelay == 0 {
+		options.TypeDelay = 30
+	}
+	return options
+}
+
+func (r *Runner) set(ctx context.Context, step Step) (any, error) {
+	return step.Value, nil
+}
+
+func (r *Runner) readClipboard(ctx context.Context, step Step) (any, error) {
+	return r.service.ReadClipboard(ctx)
+}
+
+func (r *Runner) mousePosition(ctx context.Context, step Step) (any, error) {
+	x, y, err := r.service.MousePosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"x": x, "y": y}, nil
+}
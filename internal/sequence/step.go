@@ -0,0 +1,395 @@
+// Package sequence выполняет упорядоченные последовательности шагов (перемещение,
+// клик, ввод, нажатия клавиш и т.д.) за одно обращение к рабочему столу.
+package sequence
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"goszakup-automation/internal/ocr"
+	"goszakup-automation/internal/screen"
+)
+
+// StepType тип шага последовательности
+type StepType string
+
+const (
+	StepMove   StepType = "move"
+	StepClick  StepType = "click"
+	StepText   StepType = "type"
+	StepKeyTap StepType = "key_tap"
+	StepHotkey StepType = "hotkey"
+	StepScroll StepType = "scroll"
+	StepWait   StepType = "wait"
+	StepClear  StepType = "clear"
+
+	// Составные шаги, повторяющие /input и /fill-and-click (со всеми их задержками)
+	StepInput        StepType = "input"
+	StepFillAndClick StepType = "fill_and_click"
+
+	// Шаги, возвращающие значение для output
+	StepSet           StepType = "set"
+	StepReadClipboard StepType = "read_clipboard"
+	StepMousePosition StepType = "mouse_position"
+	StepWaitForColor  StepType = "wait_for_color"
+	StepReadText      StepType = "read_text"
+
+	// Управляющие шаги
+	StepIf          StepType = "if"
+	StepForEach     StepType = "for_each"
+	StepRepeatUntil StepType = "repeat_until"
+	StepRetry       StepType = "retry"
+)
+
+// Known сообщает, поддерживается ли тип шага
+func (t StepType) Known() bool {
+	switch t {
+	case StepMove, StepClick, StepText, StepKeyTap, StepHotkey, StepScroll, StepWait, StepClear,
+		StepInput, StepFillAndClick:
+		return true
+	}
+	return t.HasOutput() || t.IsControl()
+}
+
+// IsControl сообщает, что шаг управляет выполнением вложенных шагов
+func (t StepType) IsControl() bool {
+	switch t {
+	case StepIf, StepForEach, StepRepeatUntil, StepRetry:
+		return true
+	}
+	return false
+}
+
+// HasOutput сообщает, что шаг возвращает значение, которое можно сохранить в переменную
+func (t StepType) HasOutput() bool {
+	switch t {
+	case StepSet, StepReadClipboard, StepMousePosition, StepWaitForColor, StepReadText:
+		return true
+	}
+	return false
+}
+
+// Step шаг последовательности. Какие поля обязательны, зависит от типа шага.
+// Текстовые поля и координаты могут быть шаблонами ({{ .params.имя }}, {{ .vars.имя }}),
+// они вычисляются непосредственно перед выполнением шага.
+type Step struct {
+	Type StepType `json:"type" yaml:"type"`
+	// Name необязательное имя шага для отчета
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// X, Y координаты для move, а также для click и type (если не указаны — текущая позиция)
+	X *Int `json:"x,omitempty" yaml:"x,omitempty"`
+	Y *Int `json:"y,omitempty" yaml:"y,omitempty"`
+	// Target цель вместо x и y: "image:имя" — центр шаблона, найденного на экране
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	// Button кнопка мыши для click: left, right, center (по умолчанию left)
+	Button string `json:"button,omitempty" yaml:"button,omitempty"`
+
+	// Text текст для type, input и fill_and_click
+	Text string `json:"text,omitempty" yaml:"text,omitempty"`
+	// DelayMs задержка между символами для type, input и fill_and_click
+	DelayMs int `json:"delay_ms,omitempty" yaml:"delay_ms,omitempty"`
+
+	// ButtonX, ButtonY координаты кнопки для fill_and_click
+	ButtonX *Int `json:"button_x,omitempty" yaml:"button_x,omitempty"`
+	ButtonY *Int `json:"button_y,omitempty" yaml:"button_y,omitempty"`
+	// ButtonTarget цель кнопки fill_and_click вместо button_x и button_y
+	ButtonTarget string `json:"button_target,omitempty" yaml:"button_target,omitempty"`
+	// MatchOptions параметры поиска шаблонов target и button_target: threshold, match_mode,
+	// min_scale, max_scale, scale_step (по умолчанию из настроек)
+	screen.MatchOptions `yaml:",inline"`
+	// ClearBeforeInput очистить поле перед вводом (по умолчанию false для input и true для fill_and_click)
+	ClearBeforeInput *bool `json:"clear_before_input,omitempty" yaml:"clear_before_input,omitempty"`
+	// ClickDelayMs задержка после клика для input и fill_and_click (по умолчанию 100 мс)
+	ClickDelayMs int `json:"click_delay_ms,omitempty" yaml:"click_delay_ms,omitempty"`
+
+	// Key клавиша для key_tap, Modifiers — удерживаемые при этом модификаторы
+	Key       string   `json:"key,omitempty" yaml:"key,omitempty"`
+	Modifiers []string `json:"modifiers,omitempty" yaml:"modifiers,omitempty"`
+	// Keys сочетание клавиш для hotkey: последняя — основная, остальные — модификаторы
+	Keys []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+
+	// DX, DY величина прокрутки для scroll
+	DX Int `json:"dx,omitzero" yaml:"dx,omitempty"`
+	DY Int `json:"dy,omitzero" yaml:"dy,omitempty"`
+
+	// DurationMs длительность паузы для wait
+	DurationMs Int `json:"duration_ms,omitzero" yaml:"duration_ms,omitempty"`
+
+	// Color ожидаемый цвет #RRGGBB для wait_for_color, Tolerance — допустимое отклонение компонент (0-255)
+	Color     string `json:"color,omitempty" yaml:"color,omitempty"`
+	Tolerance int    `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	// Region область экрана: для wait_for_color сравнивается ее средний цвет вместо пикселя (x, y),
+	// для target и button_target — область поиска изображения, для read_text — область распознавания
+	Region *Region `json:"region,omitempty" yaml:"region,omitempty"`
+	// Options языки (languages) и режим сегментации (psm) распознавания для read_text
+	// (по умолчанию из настроек)
+	ocr.Options `yaml:",inline"`
+	// TimeoutMs ограничение ожидания wait_for_color (по умолчанию 10 с), IntervalMs — период опроса (по умолчанию 200 мс)
+	TimeoutMs  Int `json:"timeout_ms,omitzero" yaml:"timeout_ms,omitempty"`
+	IntervalMs Int `json:"interval_ms,omitzero" yaml:"interval_ms,omitempty"`
+
+	// Value значение для set (обычно шаблон)
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Output имя переменной, в которую сохраняется результат шага ({{ .vars.имя }})
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Condition условие для if
+	Condition *Condition `json:"condition,omitempty" yaml:"condition,omitempty"`
+	// Then, Else ветки if
+	Then []Step `json:"then,omitempty" yaml:"then,omitempty"`
+	Else []Step `json:"else,omitempty" yaml:"else,omitempty"`
+
+	// Steps тело for_each, repeat_until и retry
+	Steps []Step `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// Items список для for_each: массив или ссылка на список ("{{ .params.lots }}")
+	Items any `json:"items,omitempty" yaml:"items,omitempty"`
+	// As имя переменной текущего элемента for_each (по умолчанию item), номер — <as>_index
+	As string `json:"as,omitempty" yaml:"as,omitempty"`
+	// Until условие окончания repeat_until, проверяется после каждого повтора
+	Until *Condition `json:"until,omitempty" yaml:"until,omitempty"`
+	// Max максимальное число повторов repeat_until
+	Max int `json:"max,omitempty" yaml:"max,omitempty"`
+
+	// Attempts число попыток retry (по умолчанию 3)
+	Attempts int `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+	// BackoffMs пауза перед второй попыткой (по умолчанию 500 мс)
+	BackoffMs int `json:"backoff_ms,omitempty" yaml:"backoff_ms,omitempty"`
+	// BackoffFactor множитель паузы для следующих попыток (по умолчанию 2)
+	BackoffFactor float64 `json:"backoff_factor,omitempty" yaml:"backoff_factor,omitempty"`
+	// MaxBackoffMs ограничение паузы между попытками (0 — без ограничения)
+	MaxBackoffMs int `json:"max_backoff_ms,omitempty" yaml:"max_backoff_ms,omitempty"`
+
+	// ContinueOnError продолжить последовательность, даже если шаг завершился ошибкой
+	ContinueOnError bool `json:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"`
+}
+
+// label возвращает имя шага для отчета и ошибок
+func (s Step) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return string(s.Type)
+}
+
+// Validate проверяет, что у шага заполнены поля, необходимые для его типа
+func (s Step) Validate() error {
+	if (s.X == nil) != (s.Y == nil) {
+		return errors.New("координаты x и y указываются вместе")
+	}
+	if err := s.validateTargets(); err != nil {
+		return err
+	}
+
+	if s.Options != (ocr.Options{}) && s.Type != StepReadText {
+		return errors.New("languages и psm указываются только для read_text")
+	}
+
+	switch s.Type {
+	case StepMove:
+		if s.X == nil && s.Target == "" {
+			return errors.New("для move необходимо указать x и y или target")
+		}
+	case StepClick, StepClear:
+	case StepInput, StepFillAndClick:
+		if (s.X == nil && s.Target == "") || s.Text == "" {
+			return fmt.Errorf("для %s необходимо указать x, y (или target) и text", s.Type)
+		}
+		if s.Type == StepFillAndClick && (s.ButtonX == nil || s.ButtonY == nil) && s.ButtonTarget == "" {
+			return errors.New("для fill_and_click необходимо указать button_x и button_y или button_target")
+		}
+		if s.DelayMs < 0 || s.ClickDelayMs < 0 {
+			return errors.New("задержки не могут быть отрицательными")
+		}
+	case StepText:
+		if s.Text == "" {
+			return errors.New("для type необходимо указать text")
+		}
+		if s.DelayMs < 0 {
+			return errors.New("delay_ms не может быть отрицательным")
+		}
+	case StepKeyTap:
+		if s.Key == "" {
+			return errors.New("для key_tap необходимо указать key")
+		}
+	case StepHotkey:
+		if len(s.Keys) == 0 {
+			return errors.New("для hotkey необходимо указать keys")
+		}
+	case StepScroll:
+		if s.DX.IsZero() && s.DY.IsZero() {
+			return errors.New("для scroll необходимо указать dx или dy")
+		}
+	case StepWait:
+		if !s.DurationMs.IsTemplate() && s.DurationMs.Value <= 0 {
+			return errors.New("для wait необходимо указать положительный duration_ms")
+		}
+	case StepSet:
+		if s.Output == "" {
+			return errors.New("для set необходимо указать output")
+		}
+	case StepReadClipboard, StepMousePosition:
+	case StepReadText:
+		if s.X != nil {
+			return errors.New("для read_text область указывается в region, а не x и y")
+		}
+		if err := s.Region.validate(); err != nil {
+			return err
+		}
+		if err := s.Options.Validate(); err != nil {
+			return err
+		}
+	case StepWaitForColor:
+		if s.X == nil && s.Region == nil {
+			return errors.New("для wait_for_color необходимо указать x и y или region")
+		}
+		if s.X != nil && s.Region != nil {
+			return errors.New("для wait_for_color указываются либо x и y, либо region")
+		}
+		if err := s.Region.validate(); err != nil {
+			return err
+		}
+		if s.Color == "" {
+			return errors.New("для wait_for_color необходимо указать color")
+		}
+		if !strings.Contains(s.Color, "{{") {
+			if _, err := screen.ParseColor(s.Color); err != nil {
+				return err
+			}
+		}
+		if s.Tolerance < 0 || s.Tolerance > 255 {
+			return errors.New("tolerance должен быть от 0 до 255")
+		}
+		if s.TimeoutMs.Value < 0 || s.IntervalMs.Value < 0 {
+			return errors.New("timeout_ms и interval_ms не могут быть отрицательными")
+		}
+	case StepIf:
+		if err := s.Condition.Validate(); err != nil {
+			return fmt.Errorf("condition: %w", err)
+		}
+		if len(s.Then) == 0 && len(s.Else) == 0 {
+			return errors.New("для if необходимо указать then или else")
+		}
+		if err := validateBlock("then", s.Then, true); err != nil {
+			return err
+		}
+		if err := validateBlock("else", s.Else, true); err != nil {
+			return err
+		}
+	case StepForEach:
+		if s.Items == nil {
+			return errors.New("для for_each необходимо указать items")
+		}
+		if s.As != "" && !outputPattern.MatchString(s.As) {
+			return fmt.Errorf("недопустимое имя переменной %q", s.As)
+		}
+		if err := validateBlock("steps", s.Steps, false); err != nil {
+			return err
+		}
+	case StepRepeatUntil:
+		if err := s.Until.Validate(); err != nil {
+			return fmt.Errorf("until: %w", err)
+		}
+		if s.Max <= 0 {
+			return errors.New("для repeat_until необходимо указать положительный max")
+		}
+		if err := validateBlock("steps", s.Steps, false); err != nil {
+			return err
+		}
+	case StepRetry:
+		if s.Attempts < 0 || s.BackoffMs < 0 || s.BackoffFactor < 0 || s.MaxBackoffMs < 0 {
+			return errors.New("параметры retry не могут быть отрицательными")
+		}
+		if err := validateBlock("steps", s.Steps, false); err != nil {
+			return err
+		}
+	case "":
+		return errors.New("не указан тип шага")
+	default:
+		return fmt.Errorf("неизвестный тип шага %q", s.Type)
+	}
+
+	if s.Output != "" {
+		if !s.Type.HasOutput() {
+			return fmt.Errorf("шаг %s не возвращает значение для output", s.Type)
+		}
+		if !outputPattern.MatchString(s.Output) {
+			return fmt.Errorf("недопустимое имя переменной %q", s.Output)
+		}
+	}
+	return nil
+}
+
+// validateTargets проверяет цели target и button_target: они заменяют координаты
+// и допустимы только у шагов, работающих с точкой экрана
+func (s Step) validateTargets() error {
+	if s.Target == "" && s.ButtonTarget == "" {
+		if s.MatchOptions != (screen.MatchOptions{}) {
+			return errors.New("threshold, match_mode и масштабы указываются вместе с target или button_target")
+		}
+		return nil
+	}
+
+	switch s.Type {
+	case StepMove, StepClick, StepText, StepClear, StepInput, StepFillAndClick:
+	default:
+		return fmt.Errorf("target не поддерживается для шага %s", s.Type)
+	}
+	if s.Target != "" && s.X != nil {
+		return errors.New("target указывается вместо x и y, а не вместе с ними")
+	}
+	if s.ButtonTarget != "" {
+		if s.Type != StepFillAndClick {
+			return errors.New("button_target указывается только для fill_and_click")
+		}
+		if s.ButtonX != nil || s.ButtonY != nil {
+			return errors.New("button_target указывается вместо button_x и button_y, а не вместе с ними")
+		}
+	}
+	for _, t := range []struct{ field, value string }{{"target", s.Target}, {"button_target", s.ButtonTarget}} {
+		if t.value == "" || strings.Contains(t.value, "{{") {
+			continue
+		}
+		if _, err := ParseTarget(t.value); err != nil {
+			return fmt.Errorf("%s: %w", t.field, err)
+		}
+	}
+	if err := s.MatchOptions.Validate(); err != nil {
+		return err
+	}
+	return s.Region.validate()
+}
+
+// outputPattern допустимые имена переменных (должны работать в шаблонах как .vars.имя)
+var outputPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate проверяет все шаги последовательности, включая вложенные
+func Validate(steps []Step) error {
+	if len(steps) == 0 {
+		return errors.New("последовательность не содержит шагов")
+	}
+	for i, step := range steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("шаг %d (%s): %w", i+1, step.label(), err)
+		}
+	}
+	return nil
+}
+
+// validateBlock проверяет вложенные шаги управляющего шага
+func validateBlock(field string, steps []Step, optional bool) error {
+	if len(steps) == 0 {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("не указаны вложенные шаги %s", field)
+	}
+	for i, step := range steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("%s[%d] (%s): %w", field, i+1, step.label(), err)
+		}
+	}
+	return nil
+}
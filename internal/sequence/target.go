@@ -0,0 +1,62 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	"goszakup-automation/internal/screen"
+)
+
+// TargetImage префикс цели-изображения: "image:имя_шаблона"
+const TargetImage = "image:"
+
+// ImageLocator находит изображение-шаблон на экране для целей image:имя
+type ImageLocator interface {
+	// LocateImage возвращает центр шаблона name, найденного в области region (nil — весь экран),
+	// с параметрами поиска opts (незаданные — по умолчанию)
+	LocateImage(ctx context.Context, name string, region *Region, opts screen.MatchOptions) (image.Point, error)
+}
+
+// ParseTarget возвращает имя шаблона из цели "image:имя"
+func ParseTarget(target string) (string, error) {
+	name, ok := strings.CutPrefix(target, TargetImage)
+	if !ok {
+		return "", fmt.Errorf("неизвестная цель %q, ожидается %sимя", target, TargetImage)
+	}
+	if name == "" {
+		return "", fmt.Errorf("в цели %q не указано имя шаблона", target)
+	}
+	return name, nil
+}
+
+// resolveTargets заменяет target и button_target координатами найденных на экране изображений
+func (r *Runner) resolveTargets(ctx context.Context, step Step) (Step, error) {
+	if step.Target != "" {
+		p, err := r.locateTarget(ctx, step.Target, step)
+		if err != nil {
+			return step, fmt.Errorf("target: %w", err)
+		}
+		step.X, step.Y, step.Target = IntOf(p.X), IntOf(p.Y), ""
+	}
+	if step.ButtonTarget != "" {
+		p, err := r.locateTarget(ctx, step.ButtonTarget, step)
+		if err != nil {
+			return step, fmt.Errorf("button_target: %w", err)
+		}
+		step.ButtonX, step.ButtonY, step.ButtonTarget = IntOf(p.X), IntOf(p.Y), ""
+	}
+	return step, nil
+}
+
+func (r *Runner) locateTarget(ctx context.Context, target string, step Step) (image.Point, error) {
+	name, err := ParseTarget(target)
+	if err != nil {
+		return image.Point{}, err
+	}
+	if r.locator == nil {
+		return image.Point{}, fmt.Errorf("поиск изображения: %w", ErrCheckNotSupported)
+	}
+	return r.locator.LocateImage(ctx, name, step.Region, step.MatchOptions)
+}
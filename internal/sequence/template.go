@@ -0,0 +1,238 @@
+package sequence
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrUndefined возвращается при обращении шаблона к неопределенной переменной или параметру
+var ErrUndefined = errors.New("не определена переменная")
+
+// Scope данные, доступные шаблонам шагов: {{ .params.имя }} и {{ .vars.имя }}
+type Scope struct {
+	Params map[string]any
+	Vars   map[string]any
+	// Now источник текущего времени для функции now (по умолчанию time.Now)
+	Now func() time.Time
+}
+
+// NewScope создает область видимости с параметрами и пустым набором переменных
+func NewScope(params map[string]any) *Scope {
+	if params == nil {
+		params = map[string]any{}
+	}
+	return &Scope{
+		Params: params,
+		Vars:   map[string]any{},
+		Now:    time.Now,
+	}
+}
+
+// missingKeyPattern выделяет выражение из ошибки text/template об отсутствующем ключе
+var missingKeyPattern = regexp.MustCompile(`at <(.+?)>: map has no entry for key "(.*?)"`)
+
+// Render выполняет шаблон. Строки без {{ возвращаются без изменений.
+func (sc *Scope) Render(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Funcs(sc.funcs()).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("ошибка разбора шаблона %q: %w", text, err)
+	}
+
+	data := map[string]any{"params": sc.Params, "vars": sc.Vars}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		if m := missingKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+			return "", fmt.Errorf("%w: {{ %s }}", ErrUndefined, m[1])
+		}
+		return "", fmt.Errorf("ошибка подстановки шаблона %q: %w", text, err)
+	}
+
+	out := sb.String()
+	// Обращение к nil значению text/template выводит "<no value>"
+	if strings.Contains(out, "<no value>") {
+		return "", fmt.Errorf("%w: шаблон %q вернул пустое значение", ErrUndefined, text)
+	}
+	return out, nil
+}
+
+// RenderInt вычисляет целочисленное поле
+func (sc *Scope) RenderInt(value Int) (int, error) {
+	if !value.IsTemplate() {
+		return value.Value, nil
+	}
+	out, err := sc.Render(value.Expr)
+	if err != nil {
+		return 0, err
+	}
+	out = strings.TrimSpace(out)
+	if n, err := strconv.Atoi(out); err == nil {
+		return n, nil
+	}
+	// Координаты могут прийти дробными (например, центр найденной области)
+	f, err := strconv.ParseFloat(out, 64)
+	if err != nil {
+		return 0, fmt.Errorf("шаблон %q вернул %q, ожидается число", value.Expr, out)
+	}
+	return int(f), nil
+}
+
+// List возвращает список для for_each: литеральный массив или значение переменной
+// по ссылке вида "{{ .params.lots }}" (без вызова функций)
+func (sc *Scope) List(items any) ([]any, error) {
+	switch v := items.(type) {
+	case []any:
+		return v, nil
+	case string:
+		value, err := sc.Lookup(v)
+		if err != nil {
+			return nil, err
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: ожидается список, получено %T", v, value)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("ожидается список или ссылка на переменную, получено %T", items)
+}
+
+// Lookup возвращает значение по ссылке вида "{{ .vars.pos.x }}", ".vars.pos.x" или "params.lots"
+func (sc *Scope) Lookup(ref string) (any, error) {
+	expr := strings.TrimSpace(ref)
+	expr = strings.TrimPrefix(expr, "{{")
+	expr = strings.TrimSuffix(expr, "}}")
+	expr = strings.TrimPrefix(strings.TrimSpace(expr), ".")
+
+	var current any = map[string]any{"params": sc.Params, "vars": sc.Vars}
+	for _, key := range strings.Split(expr, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("ссылка %q: %q не является объектом", ref, key)
+		}
+		value, ok := m[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: {{ .%s }}", ErrUndefined, expr)
+		}
+		current = value
+	}
+	return current, nil
+}
+
+// RenderRegion вычисляет шаблоны в полях области. Исходная область не изменяется.
+func (sc *Scope) RenderRegion(region *Region) (*Region, error) {
+	if region == nil {
+		return nil, nil
+	}
+	out := *region
+	for _, f := range []struct {
+		name  string
+		value *Int
+	}{{"x", &out.X}, {"y", &out.Y}, {"width", &out.Width}, {"height", &out.Height}} {
+		n, err := sc.RenderInt(*f.value)
+		if err != nil {
+			return nil, fmt.Errorf("поле region.%s: %w", f.name, err)
+		}
+		*f.value = Int{Value: n}
+	}
+	return &out, nil
+}
+
+// RenderStep вычисляет шаблоны в текстовых полях, координатах, областях и длительностях шага
+func RenderStep(step Step, scope *Scope) (Step, error) {
+	var err error
+	render := func(field, value string) string {
+		if err != nil {
+			return value
+		}
+		out, renderErr := scope.Render(value)
+		if renderErr != nil {
+			err = fmt.Errorf("поле %s: %w", field, renderErr)
+			return value
+		}
+		return out
+	}
+	renderInt := func(field string, value *Int) *Int {
+		if err != nil || value == nil {
+			return value
+		}
+		n, renderErr := scope.RenderInt(*value)
+		if renderErr != nil {
+			err = fmt.Errorf("поле %s: %w", field, renderErr)
+			return value
+		}
+		return IntOf(n)
+	}
+	renderList := func(field string, values []string) []string {
+		if values == nil {
+			return nil
+		}
+		out := make([]string, len(values))
+		for i, value := range values {
+			out[i] = render(fmt.Sprintf("%s[%d]", field, i), value)
+		}
+		return out
+	}
+
+	step.Name = render("name", step.Name)
+	step.X = renderInt("x", step.X)
+	step.Y = renderInt("y", step.Y)
+	step.ButtonX = renderInt("button_x", step.ButtonX)
+	step.ButtonY = renderInt("button_y", step.ButtonY)
+	step.Target = render("target", step.Target)
+	step.ButtonTarget = render("button_target", step.ButtonTarget)
+	step.Button = render("button", step.Button)
+	step.Text = render("text", step.Text)
+	step.Key = render("key", step.Key)
+	step.Modifiers = renderList("modifiers", step.Modifiers)
+	step.Keys = renderList("keys", step.Keys)
+	step.Value = render("value", step.Value)
+	step.Color = render("color", step.Color)
+	step.DX = *renderInt("dx", &step.DX)
+	step.DY = *renderInt("dy", &step.DY)
+	step.DurationMs = *renderInt("duration_ms", &step.DurationMs)
+	step.TimeoutMs = *renderInt("timeout_ms", &step.TimeoutMs)
+	step.IntervalMs = *renderInt("interval_ms", &step.IntervalMs)
+	if err == nil {
+		step.Region, err = scope.RenderRegion(step.Region)
+	}
+	return step, err
+}
+
+// funcs функции, доступные в шаблонах
+func (sc *Scope) funcs() template.FuncMap {
+	now := sc.Now
+	if now == nil {
+		now = time.Now
+	}
+	return template.FuncMap{
+		// now текущее время: {{ now | date "02.01.2006" }}
+		"now": now,
+		// date форматирует время по образцу Go
+		"date": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		// addDays сдвигает время на n дней: {{ now | addDays 3 | date "02.01.2006" }}
+		"addDays": func(n int, t time.Time) time.Time {
+			return t.AddDate(0, 0, n)
+		},
+		// default подставляет значение, если переменная пустая: {{ .vars.x | default "0" }}
+		"default": func(def, value any) any {
+			if value == nil || value == "" {
+				return def
+			}
+			return value
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+}
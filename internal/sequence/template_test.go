@@ -0,0 +1,120 @@
+package sequence_test
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"goszakup-automation/internal/backend/fake"
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/sequence"
+
+	"go.uber.org/zap"
+)
+
+func TestRenderStepNumericFields(t *testing.T) {
+	steps := parseSteps(t, `
+- type: wait_for_color
+  color: "#000000"
+  region: {x: "{{ .params.x }}", y: "{{ .params.y }}", width: "{{ .params.w }}", height: 5}
+  timeout_ms: "{{ .params.timeout }}"
+  interval_ms: "{{ .params.interval }}"
+- type: scroll
+  dx: "{{ .params.dx }}"
+  dy: "{{ .params.dy }}"
+- type: wait
+  duration_ms: "{{ .params.pause }}"
+`)
+	scope := sequence.NewScope(map[string]any{
+		"x": 10, "y": "20", "w": 30, "timeout": 500, "interval": 50, "dx": -1, "dy": 3, "pause": 250,
+	})
+
+	color, err := sequence.RenderStep(steps[0], scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := color.Region.Rect(), image.Rect(10, 20, 40, 25); got != want {
+		t.Errorf("region %v, ожидалось %v", got, want)
+	}
+	if color.TimeoutMs.Value != 500 || color.IntervalMs.Value != 50 {
+		t.Errorf("timeout_ms %v, interval_ms %v", color.TimeoutMs, color.IntervalMs)
+	}
+	if !steps[0].Region.X.IsTemplate() {
+		t.Error("RenderStep изменил область исходного шага")
+	}
+
+	scroll, err := sequence.RenderStep(steps[1], scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scroll.DX.Value != -1 || scroll.DY.Value != 3 {
+		t.Errorf("dx %v, dy %v", scroll.DX, scroll.DY)
+	}
+
+	wait, err := sequence.RenderStep(steps[2], scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait.DurationMs.Value != 250 {
+		t.Errorf("duration_ms %v", wait.DurationMs)
+	}
+
+	if _, err := sequence.RenderStep(steps[0], sequence.NewScope(nil)); err == nil {
+		t.Error("неопределенный параметр области не привел к ошибке")
+	}
+}
+
+// recordingChecker запоминает условия, с которыми вызваны проверки экрана
+type recordingChecker struct {
+	images []sequence.ImageCondition
+	texts  []sequence.TextCondition
+}
+
+func (c *recordingChecker) CheckImage(ctx context.Context, cond *sequence.ImageCondition) (bool, error) {
+	c.images = append(c.images, *cond)
+	return true, nil
+}
+
+func (c *recordingChecker) CheckText(ctx context.Context, cond *sequence.TextCondition) (bool, error) {
+	c.texts = append(c.texts, *cond)
+	return true, nil
+}
+
+func TestConditionTemplates(t *testing.T) {
+	steps := parseSteps(t, `
+- type: if
+  condition:
+    all:
+      - image:
+          template: "{{ .params.button }}"
+          region: {x: 0, y: "{{ .params.top }}", width: 100, height: "{{ .params.h }}"}
+      - text:
+          contains: Подтвердите
+          region: {x: "{{ .params.top }}", y: 0, width: 10, height: 10}
+      - pixel: {x: 1, y: 1, color: "{{ .params.color }}"}
+  then:
+    - type: set
+      value: ok
+      output: result
+`)
+	runner := sequence.NewRunner(zap.NewNop(), input.NewService(zap.NewNop(), fake.New(fake.Options{})))
+	checker := &recordingChecker{}
+	runner.SetImageChecker(checker)
+	runner.SetTextChecker(checker)
+
+	scope := sequence.NewScope(map[string]any{"button": "submit", "top": 40, "h": 60, "color": "#000000"})
+	report, err := runner.Run(context.Background(), steps, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Steps[0].Output != true {
+		t.Fatalf("условие не выполнено: %+v", report.Steps[0])
+	}
+	if len(checker.images) != 1 || checker.images[0].Template != "submit" ||
+		checker.images[0].Region.Rect() != image.Rect(0, 40, 100, 100) {
+		t.Errorf("условие image: %+v", checker.images)
+	}
+	if len(checker.texts) != 1 || checker.texts[0].Region.Rect() != image.Rect(40, 0, 50, 10) {
+		t.Errorf("условие text: %+v", checker.texts)
+	}
+}
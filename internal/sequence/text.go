@@ -0,0 +1,58 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"goszakup-automation/internal/ocr"
+)
+
+// TextReader распознает текст в области экрана
+type TextReader interface {
+	// ReadText распознает текст в области region (пустая — весь экран) с параметрами opts
+	// (незаданные — по умолчанию). Координаты слов — в координатах экрана.
+	ReadText(ctx context.Context, region image.Rectangle, opts ocr.Options) (*ocr.Result, error)
+}
+
+// readerChecker проверяет условия text распознаванием области через TextReader
+type readerChecker struct {
+	reader TextReader
+}
+
+func (c readerChecker) CheckText(ctx context.Context, cond *TextCondition) (bool, error) {
+	result, err := c.reader.ReadText(ctx, cond.Region.Rect(), cond.Options)
+	if err != nil {
+		return false, err
+	}
+	return ocr.ContainsText(result.Text, cond.Contains), nil
+}
+
+// readText распознает текст в области region (или на всем экране). Результат — текст,
+// средняя уверенность и слова с их областями.
+func (r *Runner) readText(ctx context.Context, step Step) (any, error) {
+	if r.reader == nil {
+		return nil, fmt.Errorf("распознавание текста: %w", ErrCheckNotSupported)
+	}
+	result, err := r.reader.ReadText(ctx, step.Region.Rect(), step.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]any, 0, len(result.Words))
+	for _, w := range result.Words {
+		words = append(words, map[string]any{
+			"text":       w.Text,
+			"confidence": w.Confidence,
+			"x":          w.X,
+			"y":          w.Y,
+			"width":      w.Width,
+			"height":     w.Height,
+		})
+	}
+	return map[string]any{
+		"text":       result.Text,
+		"confidence": result.Confidence,
+		"words":      words,
+	}, nil
+}
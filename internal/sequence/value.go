@@ -0,0 +1,99 @@
+package sequence
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Int целочисленное поле шага (например, координата): число или шаблон,
+// который вычисляется непосредственно перед выполнением шага.
+type Int struct {
+	Value int
+	// Expr шаблон, например "{{ .vars.pos.x }}" (пусто — значение задано числом)
+	Expr string
+}
+
+// IntOf возвращает указатель на Int с заданным значением
+func IntOf(v int) *Int {
+	return &Int{Value: v}
+}
+
+// IsZero сообщает, что значение не задано (ни числом, ни шаблоном)
+func (i Int) IsZero() bool {
+	return i == Int{}
+}
+
+// IsTemplate сообщает, что значение задано шаблоном и еще не вычислено
+func (i Int) IsTemplate() bool {
+	return i.Expr != ""
+}
+
+// String возвращает значение или шаблон
+func (i Int) String() string {
+	if i.Expr != "" {
+		return i.Expr
+	}
+	return strconv.Itoa(i.Value)
+}
+
+// MarshalJSON сериализует число или шаблон
+func (i Int) MarshalJSON() ([]byte, error) {
+	if i.Expr != "" {
+		return json.Marshal(i.Expr)
+	}
+	return json.Marshal(i.Value)
+}
+
+// UnmarshalJSON принимает число или строку (число либо шаблон)
+func (i *Int) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*i = Int{Value: n}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("ожидается число или шаблон, получено %s", data)
+	}
+	return i.parse(s)
+}
+
+// MarshalYAML сериализует число или шаблон
+func (i Int) MarshalYAML() (any, error) {
+	if i.Expr != "" {
+		return i.Expr, nil
+	}
+	return i.Value, nil
+}
+
+// UnmarshalYAML принимает число или строку (число либо шаблон)
+func (i *Int) UnmarshalYAML(node *yaml.Node) error {
+	var n int
+	if err := node.Decode(&n); err == nil {
+		*i = Int{Value: n}
+		return nil
+	}
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return fmt.Errorf("строка %d: ожидается число или шаблон", node.Line)
+	}
+	return i.parse(s)
+}
+
+func (i *Int) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "{{") {
+		*i = Int{Expr: s}
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("ожидается число или шаблон, получено %q", s)
+	}
+	*i = Int{Value: n}
+	return nil
+}
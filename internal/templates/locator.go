@@ -0,0 +1,68 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"image"
+
+	"goszakup-automation/internal/input"
+	"goszakup-automation/internal/screen"
+	"goszakup-automation/internal/sequence"
+)
+
+// Locator находит шаблоны хранилища на экране. Реализует sequence.ImageChecker
+// (условия image) и sequence.ImageLocator (цели image:имя).
+type Locator struct {
+	store   *Store
+	service *input.Service
+	// defaults параметры поиска, не указанные в запросе или шаге
+	defaults screen.MatchOptions
+}
+
+// NewLocator создает поиск шаблонов store на экране service с параметрами по умолчанию defaults
+func NewLocator(store *Store, service *input.Service, defaults screen.MatchOptions) *Locator {
+	return &Locator{store: store, service: service, defaults: defaults}
+}
+
+// Store возвращает хранилище шаблонов
+func (l *Locator) Store() *Store {
+	return l.store
+}
+
+// Defaults возвращает параметры поиска по умолчанию
+func (l *Locator) Defaults() screen.MatchOptions {
+	return l.defaults
+}
+
+// Find ищет шаблон name в области region (пустая — весь экран). Незаданные параметры opts
+// берутся по умолчанию. Если совпадение ниже порога, оно возвращается вместе с screen.ErrImageNotFound.
+func (l *Locator) Find(ctx context.Context, name string, region image.Rectangle, opts screen.MatchOptions) (screen.Match, error) {
+	tmpl, err := l.store.Load(name)
+	if err != nil {
+		return screen.Match{}, err
+	}
+	return l.FindImage(ctx, tmpl, region, opts)
+}
+
+// FindImage ищет на экране переданное изображение, как Find
+func (l *Locator) FindImage(ctx context.Context, tmpl image.Image, region image.Rectangle, opts screen.MatchOptions) (screen.Match, error) {
+	return screen.Locate(ctx, l.service, tmpl, region, opts.WithDefaults(l.defaults))
+}
+
+// LocateImage возвращает центр найденного шаблона (sequence.ImageLocator)
+func (l *Locator) LocateImage(ctx context.Context, name string, region *sequence.Region, opts screen.MatchOptions) (image.Point, error) {
+	match, err := l.Find(ctx, name, region.Rect(), opts)
+	if err != nil {
+		return image.Point{}, err
+	}
+	return image.Pt(match.X, match.Y), nil
+}
+
+// CheckImage сообщает, найден ли шаблон условия на экране (sequence.ImageChecker)
+func (l *Locator) CheckImage(ctx context.Context, cond *sequence.ImageCondition) (bool, error) {
+	_, err := l.Find(ctx, cond.Template, cond.Region.Rect(), cond.MatchOptions)
+	if errors.Is(err, screen.ErrImageNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,253 @@
+// Package templates хранит изображения-шаблоны (кнопки, значки, бейджи) и находит их
+// на экране, чтобы шаги могли указывать цель "image:имя" вместо координат.
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // декодирование загружаемых JPEG
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound возвращается, если шаблона с указанным именем нет
+var ErrNotFound = errors.New("шаблон не найден")
+
+// ErrBadTemplate возвращается при недопустимом имени или содержимом шаблона
+var ErrBadTemplate = errors.New("недопустимый шаблон")
+
+// namePattern допустимые имена шаблонов (имя файла без расширения .png)
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// ext расширение файлов шаблонов: загруженные изображения сохраняются в PNG
+const ext = ".png"
+
+// Info описание шаблона для списка
+type Info struct {
+	Name      string    `json:"name"`
+	File      string    `json:"file"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store каталог шаблонов. Декодированные изображения кешируются до изменения файла.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	modTime time.Time
+	img     image.Image
+}
+
+// NewStore создает хранилище шаблонов в каталоге path
+func NewStore(path string) *Store {
+	return &Store{path: path, cache: make(map[string]cached)}
+}
+
+// Path возвращает путь к каталогу шаблонов
+func (s *Store) Path() string {
+	return s.path
+}
+
+// List возвращает все шаблоны каталога
+func (s *Store) List() ([]Info, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []Info{}, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения каталога шаблонов: %w", err)
+	}
+
+	infos := []Info{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if !namePattern.MatchString(name) {
+			continue
+		}
+		info, err := s.Info(name)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// Info возвращает описание шаблона
+func (s *Store) Info(name string) (Info, error) {
+	img, modTime, err := s.load(name)
+	if err != nil {
+		return Info{}, err
+	}
+	name = trimExt(name)
+	return Info{
+		Name:      name,
+		File:      name + ext,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		UpdatedAt: modTime,
+	}, nil
+}
+
+// Load возвращает изображение шаблона. Имя можно указывать с расширением .png.
+func (s *Store) Load(name string) (image.Image, error) {
+	img, _, err := s.load(name)
+	return img, err
+}
+
+// Read возвращает PNG файл шаблона
+func (s *Store) Read(name string) ([]byte, error) {
+	path, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, trimExt(name))
+		}
+		return nil, fmt.Errorf("ошибка чтения шаблона: %w", err)
+	}
+	return data, nil
+}
+
+// Save сохраняет изображение PNG или JPEG как шаблон name (существующий заменяется)
+func (s *Store) Save(name string, data []byte) (Info, error) {
+	path, err := s.file(name)
+	if err != nil {
+		return Info{}, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, fmt.Errorf("%w: ошибка декодирования изображения (ожидается PNG или JPEG): %v", ErrBadTemplate, err)
+	}
+	if img.Bounds().Empty() {
+		return Info{}, fmt.Errorf("%w: изображение пустое", ErrBadTemplate)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return Info{}, fmt.Errorf("ошибка кодирования шаблона: %w", err)
+	}
+	if err := os.MkdirAll(s.path, 0o755); err != nil {
+		return Info{}, fmt.Errorf("ошибка создания каталога шаблонов: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return Info{}, err
+	}
+	return s.Info(name)
+}
+
+// Delete удаляет шаблон
+func (s *Store) Delete(name string) error {
+	path, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotFound, trimExt(name))
+		}
+		return fmt.Errorf("ошибка удаления шаблона: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.cache, path)
+	s.mu.Unlock()
+	return nil
+}
+
+// load читает шаблон с диска или из кеша, если файл не изменился
+func (s *Store) load(name string) (image.Image, time.Time, error) {
+	path, err := s.file(name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, time.Time{}, fmt.Errorf("%w: %s", ErrNotFound, trimExt(name))
+		}
+		return nil, time.Time{}, fmt.Errorf("ошибка чтения шаблона: %w", err)
+	}
+
+	s.mu.Lock()
+	entry, ok := s.cache[path]
+	s.mu.Unlock()
+	if ok && entry.modTime.Equal(stat.ModTime()) {
+		return entry.img, entry.modTime, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("ошибка чтения шаблона: %w", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("ошибка декодирования шаблона %s: %w", filepath.Base(path), err)
+	}
+
+	s.mu.Lock()
+	s.cache[path] = cached{modTime: stat.ModTime(), img: img}
+	s.mu.Unlock()
+	return img, stat.ModTime(), nil
+}
+
+// file возвращает путь к файлу шаблона по имени
+func (s *Store) file(name string) (string, error) {
+	name = trimExt(name)
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("%w: имя %q", ErrBadTemplate, name)
+	}
+	return filepath.Join(s.path, name+ext), nil
+}
+
+func trimExt(name string) string {
+	return strings.TrimSuffix(name, ext)
+}
+
+// writeFileAtomic записывает файл через временный файл и переименование,
+// чтобы параллельный поиск не прочитал шаблон наполовину записанным
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}